@@ -103,8 +103,8 @@ func buildKubeContextCompletionFunc(kubeconfigFlag string) func(*cobra.Command,
 	}
 }
 
-func buildKubeNSCompletionFunc(ctx context.Context, kubeconfigFlag string,
-	contextFlag string,
+func buildKubeNSCompletionFunc(ctx context.Context, kubeconfigFlag, contextFlag,
+	insecureSkipTLSVerifyFlag, caFileFlag string,
 ) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
 	return func(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
 		logger, _, err := buildLogger(cmd.Flags())
@@ -114,8 +114,9 @@ func buildKubeNSCompletionFunc(ctx context.Context, kubeconfigFlag string,
 
 		srcKubeconfig, _ := cmd.Flags().GetString(kubeconfigFlag)
 		srcContext, _ := cmd.Flags().GetString(contextFlag)
+		tlsOptions := buildTLSOptions(cmd.Flags(), insecureSkipTLSVerifyFlag, caFileFlag)
 
-		contexts, err := k8s.GetNamespaces(ctx, srcKubeconfig, srcContext, logger)
+		contexts, err := k8s.GetNamespaces(ctx, srcKubeconfig, srcContext, tlsOptions, logger)
 		if err != nil {
 			logger.Debug("failed to get namespaces", "error", err)
 
@@ -126,6 +127,17 @@ func buildKubeNSCompletionFunc(ctx context.Context, kubeconfigFlag string,
 	}
 }
 
+func strategyProfileCompletionFunc(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	configPath, _ := cmd.Flags().GetString(FlagConfig)
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	return cfg.strategyProfileNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
 func buildStaticSliceCompletionFunc(values []string) func(*cobra.Command,
 	[]string, string) ([]string, cobra.ShellCompDirective) {
 	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
@@ -195,15 +207,17 @@ func buildPVCCompletionFunc(ctx context.Context,
 
 		kubeconfig, _ := cmd.Flags().GetString(FlagSourceKubeconfig)
 		useContext, _ := cmd.Flags().GetString(FlagSourceContext)
-		namespace, _ := cmd.Flags().GetString(FlagSourceNamespace)
+		namespace := getNamespace(cmd.Flags(), FlagSourceNamespace)
+		tlsOptions := buildTLSOptions(cmd.Flags(), FlagSourceInsecureSkipTLSVerify, FlagSourceCAFile)
 
 		if isDestPVC {
 			kubeconfig, _ = cmd.Flags().GetString(FlagDestKubeconfig)
 			useContext, _ = cmd.Flags().GetString(FlagDestContext)
-			namespace, _ = cmd.Flags().GetString(FlagDestNamespace)
+			namespace = getNamespace(cmd.Flags(), FlagDestNamespace)
+			tlsOptions = buildTLSOptions(cmd.Flags(), FlagDestInsecureSkipTLSVerify, FlagDestCAFile)
 		}
 
-		pvcs, err := k8s.GetPVCs(ctx, kubeconfig, useContext, namespace, logger)
+		pvcs, err := k8s.GetPVCs(ctx, kubeconfig, useContext, namespace, tlsOptions, logger)
 		if err != nil {
 			logger.Debug("failed to get PVCs", "error", err)
 
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/utkuozdemir/pv-migrate/strategy"
+)
+
+// config is the configuration file of pv-migrate, e.g.:
+//
+//	strategyProfiles:
+//	  fast: [mnt2, svc]
+//	  safe: [svc]
+//	  cross-cluster: [lbsvc, local]
+type config struct {
+	// StrategyProfiles are the named orderings of strategies to be picked by --strategy-profile.
+	StrategyProfiles map[string][]string `yaml:"strategyProfiles"`
+}
+
+// loadConfig loads the configuration file at the given path. If the path is empty, the file is looked up
+// in the user configuration directory, i.e., $XDG_CONFIG_HOME/pv-migrate/config.yaml on Linux,
+// and an empty configuration is returned if it does not exist.
+func loadConfig(path string) (*config, error) {
+	explicit := path != ""
+
+	if !explicit {
+		configDir, err := os.UserConfigDir()
+		if err != nil { // without a home directory, there is no default config file
+			return &config{}, nil //nolint:nilerr
+		}
+
+		path = filepath.Join(configDir, "pv-migrate", "config.yaml")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return &config{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg config
+	if err = yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// strategyProfile returns the strategies of the profile with the given name,
+// validating that the profile exists and all of its strategies are known.
+func (c *config) strategyProfile(name string) ([]string, error) {
+	strategies, ok := c.StrategyProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("strategy profile %q is not defined in the config file, defined profiles: [%s]",
+			name, strings.Join(c.strategyProfileNames(), ", "))
+	}
+
+	if len(strategies) == 0 {
+		return nil, fmt.Errorf("strategy profile %q has no strategies", name)
+	}
+
+	for _, s := range strategies {
+		if !slices.Contains(strategy.AllStrategies, s) {
+			return nil, fmt.Errorf("strategy profile %q has the unknown strategy %q, must be one of: %s",
+				name, s, strings.Join(strategy.AllStrategies, ", "))
+		}
+	}
+
+	return strategies, nil
+}
+
+// strategyProfileNames returns the names of the strategy profiles, sorted.
+func (c *config) strategyProfileNames() []string {
+	names := make([]string, 0, len(c.StrategyProfiles))
+	for name := range c.StrategyProfiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+const (
+	CommandList = "list"
+
+	FlagKubeconfig            = "kubeconfig"
+	FlagContext               = "context"
+	FlagAllNamespaces         = "all-namespaces"
+	FlagInsecureSkipTLSVerify = "insecure-skip-tls-verify"
+	FlagCAFile                = "ca-file"
+	FlagOutput                = "output"
+
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+var outputFormats = []string{outputTable, outputJSON}
+
+func buildListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   CommandList,
+		Short: "List the PVCs with their size, access modes, storage class and the pods mounting them",
+		Args:  cobra.NoArgs,
+		RunE:  runList,
+	}
+
+	flags := cmd.Flags()
+
+	flags.StringP(FlagKubeconfig, "k", "", "path of the kubeconfig file")
+	flags.StringP(FlagContext, "c", "", "context in the kubeconfig file")
+	flags.StringP(FlagNamespace, "n", "", "namespace to list the PVCs in. Defaults to the namespace of the context")
+	flags.BoolP(FlagAllNamespaces, "A", false, "list the PVCs in all namespaces")
+	flags.Bool(FlagInsecureSkipTLSVerify, false, "skip the verification of the API server certificate")
+	flags.String(FlagCAFile, "", "path of a CA bundle to verify the API server certificate with, "+
+		"overriding the one in the kubeconfig")
+	flags.StringP(FlagOutput, "o", outputTable, "output format, must be one of: "+strings.Join(outputFormats, ", "))
+
+	cmd.MarkFlagsMutuallyExclusive(FlagNamespace, FlagAllNamespaces)
+
+	//nolint:errcheck
+	cmd.RegisterFlagCompletionFunc(FlagContext, buildKubeContextCompletionFunc(FlagKubeconfig))
+	//nolint:errcheck
+	cmd.RegisterFlagCompletionFunc(FlagOutput, buildStaticSliceCompletionFunc(outputFormats))
+
+	return cmd
+}
+
+func runList(cmd *cobra.Command, _ []string) error {
+	flags := cmd.Flags()
+
+	logger, _, err := buildLogger(flags)
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, _ := flags.GetString(FlagKubeconfig)
+	kubeContext, _ := flags.GetString(FlagContext)
+	namespace, _ := flags.GetString(FlagNamespace)
+	allNamespaces, _ := flags.GetBool(FlagAllNamespaces)
+	output, _ := flags.GetString(FlagOutput)
+	tlsOptions := buildTLSOptions(flags, FlagInsecureSkipTLSVerify, FlagCAFile)
+
+	if output != outputTable && output != outputJSON {
+		return fmt.Errorf("--%s must be one of: %s", FlagOutput, strings.Join(outputFormats, ", "))
+	}
+
+	client, err := k8s.GetClusterClient(kubeconfig, kubeContext, tlsOptions, logger)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster client: %w", err)
+	}
+
+	if namespace == "" && !allNamespaces {
+		namespace = client.NsInContext
+	}
+
+	summaries, err := pvc.List(cmd.Context(), client.KubeClient, namespace)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err = encoder.Encode(summaries); err != nil {
+			return fmt.Errorf("failed to write PVCs: %w", err)
+		}
+
+		return nil
+	}
+
+	return writePVCTable(os.Stdout, summaries)
+}
+
+// writePVCTable writes the summaries of the PVCs as a table, in the style of kubectl.
+func writePVCTable(writer io.Writer, summaries []pvc.Summary) error {
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 3, ' ', 0) //nolint:mnd
+
+	fmt.Fprintln(tabWriter, "NAMESPACE\tNAME\tSTATUS\tSIZE\tACCESS MODES\tSTORAGECLASS\tMOUNTED BY")
+
+	for _, summary := range summaries {
+		fmt.Fprintf(tabWriter, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", summary.Namespace, summary.Name, summary.Phase,
+			summary.Size, strings.Join(summary.AccessModes, ","), orNone(summary.StorageClass),
+			orNone(strings.Join(summary.MountedBy, ",")))
+	}
+
+	if err := tabWriter.Flush(); err != nil {
+		return fmt.Errorf("failed to write PVCs: %w", err)
+	}
+
+	return nil
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+
+	return value
+}
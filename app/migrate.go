@@ -1,10 +1,17 @@
 package app
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,12 +19,17 @@ import (
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
+	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
 	"github.com/utkuozdemir/pv-migrate/migrator"
+	"github.com/utkuozdemir/pv-migrate/rsync"
 	"github.com/utkuozdemir/pv-migrate/rsync/progress"
 	"github.com/utkuozdemir/pv-migrate/ssh"
 	"github.com/utkuozdemir/pv-migrate/strategy"
+	"github.com/utkuozdemir/pv-migrate/tracing"
+	"github.com/utkuozdemir/pv-migrate/util"
 )
 
 const (
@@ -29,38 +41,160 @@ const (
 	logFormatText = "text"
 	logFormatJSON = "json"
 
-	FlagSource           = "source"
-	FlagSourceKubeconfig = "source-kubeconfig"
-	FlagSourceContext    = "source-context"
-	FlagSourceNamespace  = "source-namespace"
-	FlagSourcePath       = "source-path"
-
-	FlagDest             = "dest"
-	FlagDestKubeconfig   = "dest-kubeconfig"
-	FlagDestContext      = "dest-context"
-	FlagDestNamespace    = "dest-namespace"
-	FlagDestPath         = "dest-path"
-	FlagDestHostOverride = "dest-host-override"
-	FlagLBSvcTimeout     = "lbsvc-timeout"
+	conflictOverwrite    = "overwrite"
+	conflictKeepNewer    = "keep-newer"
+	conflictSkipExisting = "skip-existing"
+
+	FlagSource                 = "source"
+	FlagSourceKubeconfig       = "source-kubeconfig"
+	FlagSourceKubeconfigSecret = "source-kubeconfig-secret"
+	FlagSourceContext          = "source-context"
+	FlagSourceWorkload         = "source-workload"
+	FlagSourcePV               = "source-pv"
+	FlagSourceNamespace        = "source-namespace"
+	FlagNamespace              = "namespace"
+	FlagSourcePath             = "source-path"
+	FlagIncludeLostFound       = "include-lost-found"
+
+	FlagSourceInsecureSkipTLSVerify = "source-insecure-skip-tls-verify"
+	FlagSourceCAFile                = "source-ca-file"
+
+	FlagDest                 = "dest"
+	FlagDestKubeconfig       = "dest-kubeconfig"
+	FlagDestKubeconfigSecret = "dest-kubeconfig-secret"
+	FlagDestContext          = "dest-context"
+	FlagDestNamespace        = "dest-namespace"
+	FlagDestPath             = "dest-path"
+	FlagDestHostOverride     = "dest-host-override"
+	FlagNoDestMkdir          = "no-dest-mkdir"
+	FlagFailIfDestNotEmpty   = "fail-if-dest-not-empty"
+	FlagDestSSHHost          = "dest-ssh-host"
+	FlagDestSSHPort          = "dest-ssh-port"
+	FlagSSHServiceName       = "ssh-service-name"
+	FlagSSHClusterIP         = "ssh-cluster-ip"
+	FlagRsyncdPort           = "rsyncd-port"
+	FlagLBSvcTimeout         = "lbsvc-timeout"
+
+	FlagDestInsecureSkipTLSVerify = "dest-insecure-skip-tls-verify"
+	FlagDestCAFile                = "dest-ca-file"
+
+	FlagIntermediateBucket = "intermediate-bucket"
+	FlagIntermediateSecret = "intermediate-secret"
+	FlagIntermediatePhase  = "intermediate-phase"
+	FlagIntermediateImage  = "intermediate-image"
 
 	FlagDestDeleteExtraneousFiles = "dest-delete-extraneous-files"
+	FlagMaxDelete                 = "max-delete"
 	FlagIgnoreMounted             = "ignore-mounted"
+	FlagScaleDownDest             = "scale-down-dest"
+	FlagDestReclaimPolicy         = "dest-reclaim-policy"
 	FlagNoChown                   = "no-chown"
+	FlagNoOwner                   = "no-owner"
+	FlagNoGroup                   = "no-group"
+	FlagAddCapability             = "add-capability"
+	FlagDropCapability            = "drop-capability"
 	FlagSkipCleanup               = "skip-cleanup"
+	FlagKeepResources             = "keep-resources"
+	FlagServerSideApply           = "server-side-apply"
 	FlagNoProgressBar             = "no-progress-bar"
+	FlagETAInterval               = "eta-interval"
 	FlagSourceMountReadOnly       = "source-mount-read-only"
+	FlagSourcePrepareCommand      = "source-prepare-command"
 	FlagStrategies                = "strategies"
+	FlagStrategyProfile           = "strategy-profile"
+	FlagConfig                    = "config"
 	FlagSSHKeyAlgorithm           = "ssh-key-algorithm"
+	FlagRsyncUser                 = "rsync-user"
+	FlagStrictHostKeyChecking     = "strict-host-key-checking"
 	FlagCompress                  = "compress"
+	FlagSSHCompression            = "ssh-compression"
+	FlagAutoCompress              = "auto-compress"
+	FlagSnapshotClass             = "snapshot-class"
+	FlagItemize                   = "itemize"
+	FlagRsyncVerbose              = "rsync-verbose"
+	FlagParallel                  = "parallel"
+	FlagMaxConcurrentPods         = "max-concurrent-pods"
+	FlagTopologySpread            = "topology-spread"
+	FlagWatch                     = "watch"
+	FlagSyncInterval              = "sync-interval"
+	FlagSSHConnectRetries         = "ssh-connect-retries"
+	FlagBlockSize                 = "block-size"
+	FlagHardLinks                 = "hard-links"
+	FlagOmitDirTimes              = "omit-dir-times"
+	FlagDirsOnly                  = "dirs-only"
+	FlagNumericIDs                = "numeric-ids"
+	FlagDelayUpdates              = "delay-updates"
+	FlagEvictionRetries           = "eviction-retries"
+	FlagWholeFile                 = "whole-file"
+	FlagNoWholeFile               = "no-whole-file"
+	FlagChmod                     = "chmod"
+	FlagIconv                     = "iconv"
+	FlagFilesFrom                 = "files-from"
+	FlagSince                     = "since"
+	FlagCompareDest               = "compare-dest"
+	FlagBackup                    = "backup"
+	FlagBackupDir                 = "backup-dir"
+	FlagIOTimeout                 = "io-timeout"
+	FlagConnectTimeout            = "connect-timeout"
+	FlagUpdate                    = "update"
+	FlagConflict                  = "conflict"
+	FlagProtocol                  = "protocol"
+	FlagSockOpts                  = "sockopts"
+	FlagBwLimitSchedule           = "bwlimit-schedule"
+	FlagWebhookURL                = "webhook-url"
+	FlagOTLPEndpoint              = "otlp-endpoint"
+	FlagValidateOnly              = "validate-only"
+	FlagPrintCommand              = "print-command"
+	FlagResultFile                = "result-file"
+	FlagChecksumManifest          = "checksum-manifest"
+	FlagFromSnapshot              = "from-snapshot"
+	FlagSnapshotAfter             = "snapshot-after"
+	FlagSnapshotAfterClass        = "snapshot-after-class"
+	FlagPreserveSnapshotBase      = "preserve-snapshot-base"
+	FlagSeccompProfile            = "seccomp-profile"
+	FlagAppArmorProfile           = "apparmor-profile"
+	FlagRuntimeClass              = "runtime-class"
+	FlagAllowSidecars             = "allow-sidecars"
+	FlagPodDNSPolicy              = "pod-dns-policy"
+	FlagPodDNSNameserver          = "pod-dns-nameserver"
+	FlagPreserve                  = "preserve"
+	FlagClientImage               = "client-image"
+	FlagServerImage               = "server-image"
+	FlagExtraVolume               = "extra-volume"
+	FlagFilterFile                = "filter-file"
+	FlagRespectTopology           = "respect-topology"
+	FlagCheckTopology             = "check-topology"
+	FlagStrictFS                  = "strict-fs"
+
+	FlagHelmTimeout           = "helm-timeout"
+	FlagHelmValues            = "helm-values"
+	FlagHelmSet               = "helm-set"
+	FlagHelmSetString         = "helm-set-string"
+	FlagHelmSetFile           = "helm-set-file"
+	FlagCoordinationNamespace = "coordination-namespace"
+
+	FlagExpandEnv = "expand-env"
+	FlagYes       = "yes"
+
+	// EnvRsyncImage and EnvSshdImage are the environment variables to override the default images with,
+	// e.g., with the mirrored ones in air-gapped environments.
+	EnvRsyncImage = "PV_MIGRATE_RSYNC_IMAGE"
+	EnvSshdImage  = "PV_MIGRATE_SSHD_IMAGE"
+
+	// maxUploadedFileSize is the maximum size of a local file to be passed to rsync, e.g., the --files-from list,
+	// for all of them to fit into the Helm release and a ConfigMap.
+	maxUploadedFileSize = 256 * 1024
+)
 
-	FlagHelmTimeout   = "helm-timeout"
-	FlagHelmValues    = "helm-values"
-	FlagHelmSet       = "helm-set"
-	FlagHelmSetString = "helm-set-string"
-	FlagHelmSetFile   = "helm-set-file"
+var conflictPolicies = []string{conflictOverwrite, conflictKeepNewer, conflictSkipExisting}
 
-	lbSvcTimeoutDefault = 2 * time.Minute
-)
+// capabilityRegex matches the names of the Linux capabilities, without the "CAP_" prefix.
+var capabilityRegex = regexp.MustCompile(`^[A-Z][A-Z_]*$`)
+
+// preserveSELinux is the value of --preserve for preserving the SELinux contexts of the files.
+const preserveSELinux = "selinux"
+
+var preservableAttributes = []string{preserveSELinux}
 
 var completionFuncNoFileComplete = func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
 	return nil, cobra.ShellCompDirectiveNoFileComp
@@ -120,6 +254,7 @@ func BuildMigrateCmd(ctx context.Context, version, commit, date string, legacy b
 		legacyMigrateCommand := BuildMigrateCmd(ctx, version, commit, date, true)
 
 		cmd.AddCommand(legacyMigrateCommand)
+		cmd.AddCommand(buildListCmd())
 	}
 
 	cmd.AddCommand(buildCompletionCmd())
@@ -135,17 +270,29 @@ func setMigrateCmdCompletion(ctx context.Context, cmd *cobra.Command, levels, fo
 	cmd.RegisterFlagCompletionFunc(FlagSourceContext,
 		buildKubeContextCompletionFunc(FlagSourceKubeconfig))
 	cmd.RegisterFlagCompletionFunc(FlagSourceNamespace,
-		buildKubeNSCompletionFunc(ctx, FlagSourceKubeconfig, FlagSourceContext))
+		buildKubeNSCompletionFunc(ctx, FlagSourceKubeconfig, FlagSourceContext,
+			FlagSourceInsecureSkipTLSVerify, FlagSourceCAFile))
 	cmd.RegisterFlagCompletionFunc(FlagSourcePath, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagNamespace,
+		buildKubeNSCompletionFunc(ctx, FlagSourceKubeconfig, FlagSourceContext,
+			FlagSourceInsecureSkipTLSVerify, FlagSourceCAFile))
 
 	cmd.RegisterFlagCompletionFunc(FlagDestContext,
 		buildKubeContextCompletionFunc(FlagDestKubeconfig))
 	cmd.RegisterFlagCompletionFunc(FlagDestNamespace,
-		buildKubeNSCompletionFunc(ctx, FlagDestKubeconfig, FlagDestContext))
+		buildKubeNSCompletionFunc(ctx, FlagDestKubeconfig, FlagDestContext,
+			FlagDestInsecureSkipTLSVerify, FlagDestCAFile))
 	cmd.RegisterFlagCompletionFunc(FlagDestPath, completionFuncNoFileComplete)
 
 	cmd.RegisterFlagCompletionFunc(FlagStrategies, buildSliceCompletionFunc(strategy.AllStrategies))
+	cmd.RegisterFlagCompletionFunc(FlagStrategyProfile, strategyProfileCompletionFunc)
 	cmd.RegisterFlagCompletionFunc(FlagSSHKeyAlgorithm, buildStaticSliceCompletionFunc(ssh.KeyAlgorithms))
+	cmd.RegisterFlagCompletionFunc(FlagConflict, buildStaticSliceCompletionFunc(conflictPolicies))
+	cmd.RegisterFlagCompletionFunc(FlagPodDNSPolicy, buildStaticSliceCompletionFunc(migration.PodDNSPolicies))
+	cmd.RegisterFlagCompletionFunc(FlagDestReclaimPolicy, buildStaticSliceCompletionFunc(migration.ReclaimPolicies))
+	cmd.RegisterFlagCompletionFunc(FlagPreserve, buildStaticSliceCompletionFunc(preservableAttributes))
+	cmd.RegisterFlagCompletionFunc(FlagKeepResources, buildSliceCompletionFunc(strategy.KeepableResourceKinds))
+	cmd.RegisterFlagCompletionFunc(FlagIntermediatePhase, buildStaticSliceCompletionFunc(strategy.ObjStorePhases))
 
 	cmd.RegisterFlagCompletionFunc(FlagHelmSet, completionFuncNoFileComplete)
 	cmd.RegisterFlagCompletionFunc(FlagHelmSetString, completionFuncNoFileComplete)
@@ -153,6 +300,8 @@ func setMigrateCmdCompletion(ctx context.Context, cmd *cobra.Command, levels, fo
 
 	if !legacy {
 		cmd.RegisterFlagCompletionFunc(FlagSource, buildPVCCompletionFunc(ctx, false))
+		cmd.RegisterFlagCompletionFunc(FlagSourceWorkload, completionFuncNoFileComplete)
+		cmd.RegisterFlagCompletionFunc(FlagSourcePV, completionFuncNoFileComplete)
 		cmd.RegisterFlagCompletionFunc(FlagDest, buildPVCCompletionFunc(ctx, true))
 	}
 }
@@ -169,51 +318,371 @@ func setMigrateCmdFlags(cmd *cobra.Command, logLevels, logFormats []string, lega
 		"log format, must be one of: "+strings.Join(logFormats, ", "))
 
 	flags.StringP(FlagSourceKubeconfig, "k", "", "path of the kubeconfig file of the source PVC")
+	flags.String(FlagSourceKubeconfigSecret, "", "the key of a secret holding the kubeconfig of the source PVC "+
+		"in the form of [NAMESPACE/]NAME/KEY, read from the cluster pv-migrate runs in, e.g., when run as a Job. "+
+		"The namespace defaults to the one of the current context, i.e., of the pod when run in a pod")
+	cmd.MarkFlagsMutuallyExclusive(FlagSourceKubeconfig, FlagSourceKubeconfigSecret)
 	flags.StringP(FlagSourceContext, "c", "", "context in the kubeconfig file of the source PVC")
 	flags.StringP(FlagSourceNamespace, "n", "", "namespace of the source PVC")
+	flags.String(FlagNamespace, "", fmt.Sprintf("namespace of both the source and the destination PVCs, "+
+		"overridden by --%s and --%s", FlagSourceNamespace, FlagDestNamespace))
 
 	if !legacy {
 		flags.String(FlagSource, "", "source PVC name")
-
-		cmd.MarkFlagRequired(FlagSource) //nolint:errcheck
+		flags.String(FlagSourceWorkload, "", "the workload to migrate the PVCs of instead of a single PVC, "+
+			"in the form of <kind>/<name>, where kind is deployment or statefulset. Each PVC is migrated "+
+			fmt.Sprintf("to the PVC with the same name on the destination, so --%s cannot be used with it, ", FlagDest)+
+			"and the destination needs to be in another namespace or cluster")
+		flags.String(FlagSourcePV, "", "the PersistentVolume to migrate from instead of a PVC, "+
+			"e.g., to rescue the data of a PV whose PVC was deleted. A temporary PVC bound to it is created "+
+			"in the source namespace and deleted afterwards. "+
+			"The PV must be Released or Available and have the Retain reclaim policy")
+
+		cmd.MarkFlagsOneRequired(FlagSource, FlagSourceWorkload, FlagSourcePV)
+		cmd.MarkFlagsMutuallyExclusive(FlagSource, FlagSourceWorkload, FlagSourcePV)
 	}
 
-	flags.StringP(FlagSourcePath, "p", "/", "the filesystem path to migrate in the source PVC")
+	flags.StringP(FlagSourcePath, "p", "/", "the directory to migrate in the source PVC. Its contents are copied "+
+		"into --"+FlagDestPath+", e.g., with /old/prefix and /new/prefix, /old/prefix/a is copied to /new/prefix/a. "+
+		"It must be a directory: to migrate a single file, set it to the directory of the file "+
+		"and list the file with --"+FlagFilesFrom)
+	flags.Bool(FlagIncludeLostFound, false, "also migrate the lost+found directory at the root of the source PVC, "+
+		"created by mkfs on ext filesystems. By default, it is excluded from the transfer and from the deletions "+
+		"of --"+FlagDestDeleteExtraneousFiles+". It is only excluded when --"+FlagSourcePath+" is the root. "+
+		"Required by the "+strategy.SnapshotStrategy+" strategy, which restores the whole volume")
+	flags.Bool(FlagSourceInsecureSkipTLSVerify, false, "do not verify the certificate of the API server "+
+		"of the source PVC. This makes the connection insecure")
+	flags.String(FlagSourceCAFile, "", "path of a CA bundle to verify the certificate of the API server "+
+		"of the source PVC, overriding the one in the kubeconfig")
 
 	flags.StringP(FlagDestKubeconfig, "K", "", "path of the kubeconfig file of the destination PVC")
+	flags.String(FlagDestKubeconfigSecret, "", fmt.Sprintf("the key of a secret holding the kubeconfig "+
+		"of the destination PVC, like --%s", FlagSourceKubeconfigSecret))
+	cmd.MarkFlagsMutuallyExclusive(FlagDestKubeconfig, FlagDestKubeconfigSecret)
 	flags.StringP(FlagDestContext, "C", "", "context in the kubeconfig file of the destination PVC")
 	flags.StringP(FlagDestNamespace, "N", "", "namespace of the destination PVC")
 
 	if !legacy {
 		flags.String(FlagDest, "", "destination PVC name")
 
-		cmd.MarkFlagRequired(FlagDest) //nolint:errcheck
+		cmd.MarkFlagsOneRequired(FlagDest, FlagSourceWorkload)
+		cmd.MarkFlagsMutuallyExclusive(FlagDest, FlagSourceWorkload)
 	}
 
-	flags.StringP(FlagDestPath, "P", "/", "the filesystem path to migrate in the destination PVC")
+	flags.StringP(FlagDestPath, "P", "/", "the directory to copy the contents of --"+FlagSourcePath+" into "+
+		"in the destination PVC. "+
+		"It is created with its parent directories if it does not exist, unless --"+FlagNoDestMkdir+" is set")
+	flags.Bool(FlagNoDestMkdir, false, "do not create the destination path before the migration, "+
+		"to fail if it does not exist")
+	flags.Bool(FlagFailIfDestNotEmpty, false, "fail without transferring anything if the destination path "+
+		"has any entries other than lost+found, listing the entries found, to avoid merging into existing data by accident. "+
+		"The check is done by each strategy tried, so the data left by a failed strategy also fails the next ones")
+	flags.Bool(FlagDestInsecureSkipTLSVerify, false, "do not verify the certificate of the API server "+
+		"of the destination PVC. This makes the connection insecure")
+	flags.String(FlagDestCAFile, "", "path of a CA bundle to verify the certificate of the API server "+
+		"of the destination PVC, overriding the one in the kubeconfig")
 
 	flags.BoolP(FlagDestDeleteExtraneousFiles, "d", false,
 		"delete extraneous files on the destination by using rsync's '--delete' flag")
+	flags.Int(FlagMaxDelete, 0, fmt.Sprintf("with --%s, do not delete more than the given number of files "+
+		"('--max-delete' flag of rsync), as a safety net against a wrong source path wiping the destination. "+
+		"When the limit is hit, the rest of the deletions are skipped, a warning is logged and the migration fails. "+
+		"0 skips all deletions. By default, there is no limit", FlagDestDeleteExtraneousFiles))
 	flags.BoolP(FlagIgnoreMounted, "i", false,
 		"do not fail if the source or destination PVC is mounted")
+	flags.Bool(FlagScaleDownDest, false, "scale the deployments and the statefulsets using the destination PVC "+
+		"down to zero during the migration, and back up after it, "+
+		"e.g., for a ReadWriteOnce PVC mounted on another node")
+	flags.String(FlagDestReclaimPolicy, "", "the reclaim policy to set on the PV of the destination PVC after "+
+		"the migration, one of: "+strings.Join(migration.ReclaimPolicies, ", ")+", e.g., Retain for the migrated data "+
+		"to survive the deletion of the PVC. It only applies when the PV is provisioned during the migration, "+
+		"i.e., when the destination PVC is not bound yet. Requires the permission to get and patch persistent volumes")
 	flags.BoolP(FlagNoChown, "o", false, "omit chown on rsync")
+	flags.Bool(FlagNoOwner, false, "do not preserve the owners of the files on rsync")
+	flags.Bool(FlagNoGroup, false, "do not preserve the groups of the files on rsync")
+	flags.StringSlice(FlagAddCapability, nil, "Linux capabilities to add to the rsync pods (can specify multiple)")
+	flags.StringSlice(FlagDropCapability, nil, "Linux capabilities to drop from the rsync pods, "+
+		"or ALL to only add the ones of --"+FlagAddCapability+" (can specify multiple)")
 	flags.BoolP(FlagSkipCleanup, "x", false, "skip cleanup of the migration")
+	flags.StringSlice(FlagKeepResources, nil, fmt.Sprintf("the kinds of the resources to keep on cleanup, "+
+		"while the rest is cleaned up, e.g., secret,service to debug SSH issues. Can be any of: %s",
+		strings.Join(strategy.KeepableResourceKinds, ", ")))
+	flags.Bool(FlagServerSideApply, false, fmt.Sprintf("create the resources of the migration using server-side apply "+
+		"with the field manager %q instead of creating them on the client side, "+
+		"e.g., to coexist with Argo CD or Flux managing the namespace", k8s.FieldManager))
 	flags.BoolP(FlagNoProgressBar, "b", false, "do not display a progress bar")
-	flags.BoolP(FlagSourceMountReadOnly, "R", true, "mount the source PVC in ReadOnly mode")
+	flags.Duration(FlagETAInterval, 0, "the interval to log the estimated time remaining at when the progress bar "+
+		"is not displayed. The estimate is projected from the average throughput of the last minute, "+
+		"so it is more stable than the one of rsync. It is also included in the progress events of --"+FlagWebhookURL+
+		". 0 disables the logging")
+	flags.BoolP(FlagSourceMountReadOnly, "R", migration.DefaultSourceMountReadOnly,
+		"mount the source PVC in ReadOnly mode")
+	flags.String(FlagSourcePrepareCommand, "", "a command to run with 'sh -c' in a running pod mounting "+
+		"the source PVC before the transfer, e.g., to flush or checkpoint a database. It is run in the container "+
+		"named by the kubectl.kubernetes.io/default-container annotation of the pod, or in its first container. "+
+		"The migration fails if no such pod is running or the command fails. In watch mode, it is run before each sync")
+	flags.Bool(FlagRespectTopology, false, "schedule the migration pods only on the nodes matching "+
+		"the node affinity of the persistent volumes, e.g., in the zone of zonal volumes. "+
+		"Requires the permission to get persistent volumes")
+	flags.Bool(FlagCheckTopology, false, "check before the migration that the nodes of the clusters can mount "+
+		"the PVCs, failing with an explanation instead of the migration pods hanging until the timeout")
+	flags.Bool(FlagStrictFS, false, "fail instead of warning if the filesystem of the destination PVC does not "+
+		"support the features of the source filesystem, e.g., reflinks or project quotas")
+	flags.String(FlagSeccompProfile, "", "the seccomp profile of the migration pods: RuntimeDefault, Unconfined "+
+		"or Localhost/<profile>, e.g., to run in namespaces enforcing the restricted Pod Security Standard")
+	flags.String(FlagAppArmorProfile, "", "the AppArmor profile of the migration pods: RuntimeDefault, Unconfined "+
+		"or Localhost/<profile>. Requires Kubernetes 1.30 or later")
+	flags.String(FlagClientImage, "", "the image of the rsync client, i.e., the job running rsync, in the form of "+
+		"<repository>:<tag>, e.g., to use a mirrored image. "+
+		fmt.Sprintf("By default, the image in the %s environment variable or in the Helm chart is used", EnvRsyncImage))
+	flags.String(FlagServerImage, "", "the image of the sshd server which rsync connects to, in the form of "+
+		"<repository>:<tag>. "+
+		fmt.Sprintf("By default, the image in the %s environment variable or in the Helm chart is used", EnvSshdImage))
+	flags.String(FlagRuntimeClass, "", "the RuntimeClass to run the migration pods with, e.g., for gVisor or "+
+		"Kata Containers. It must exist in the clusters of both the source and the destination")
+	flags.String(FlagPodDNSPolicy, "", "the DNS policy of the migration pods, one of: "+
+		strings.Join(migration.PodDNSPolicies, ", ")+". Defaults to ClusterFirst, e.g., None can be used "+
+		"to only resolve the names with the nameservers given with --"+FlagPodDNSNameserver)
+	flags.StringSlice(FlagPodDNSNameserver, nil, fmt.Sprintf("the IP address of a nameserver to add to "+
+		"the DNS config of the migration pods, e.g., to resolve the SSH host with a specific resolver "+
+		"(can specify up to %d)", migration.MaxPodDNSNameservers))
+	flags.StringSlice(FlagPreserve, nil, "additional attributes of the files to preserve, one of: "+
+		strings.Join(preservableAttributes, ", ")+". For selinux, the SELinux contexts are transferred "+
+		"('-X' flag of rsync, limited to the security.selinux attributes), and the migration pods run "+
+		"with the spc_t SELinux type to be allowed to relabel the files, unless set in the Helm values. "+
+		"See the usage docs for the requirements (can specify multiple)")
+	flags.Bool(FlagAllowSidecars, false, "keep the Istio sidecars injected into the migration pods "+
+		"in the namespaces with the sidecar injection enabled, only excluding the ports of the migration "+
+		"from their traffic redirection. By default, the injection is disabled for the migration pods, "+
+		"as the sidecars break the SSH connections and keep the rsync pods from completing")
+	flags.StringArray(FlagExtraVolume, nil, "an existing ConfigMap or Secret in the destination namespace to mount "+
+		"read-only into the rsync pod, in the form of <configmap|secret>:<name>:<mount path>, "+
+		"e.g., configmap:rsync-filters:/etc/rsync-filters (can specify multiple). "+
+		fmt.Sprintf("Has no effect for the %s strategy", strategy.LocalStrategy))
 	flags.StringSliceP(FlagStrategies, "s", strategy.DefaultStrategies,
 		"the comma-separated list of strategies to be used in the given order")
-	flags.StringP(FlagSSHKeyAlgorithm, "a", ssh.Ed25519KeyAlgorithm,
+	flags.String(FlagStrategyProfile, "", "the name of a profile in the strategyProfiles section of the config file "+
+		"to use the strategies of, in the given order, instead of listing them with --"+FlagStrategies)
+	cmd.MarkFlagsMutuallyExclusive(FlagStrategies, FlagStrategyProfile)
+	flags.String(FlagConfig, "", "path of the config file. "+
+		"Defaults to pv-migrate/config.yaml in the user config directory, e.g., ~/.config/pv-migrate/config.yaml")
+	flags.StringP(FlagSSHKeyAlgorithm, "a", migration.DefaultKeyAlgorithm,
 		"ssh key algorithm to be used. Valid values are "+strings.Join(ssh.KeyAlgorithms, ","))
+	flags.String(FlagRsyncUser, "root", "the user to connect to the SSH server of the sshd pod as, "+
+		"for the hardened sshd images which run as a non-root user or disallow the root login. "+
+		"The public key is mounted to the path of the root user, which can be changed with "+
+		"--"+FlagHelmSet+" sshd.publicKeyMountPath=/home/<user>/.ssh/authorized_keys")
+	flags.Bool(FlagStrictHostKeyChecking, false, "verify the host key of the SSH server of the sshd pod "+
+		"instead of accepting any, by provisioning the sshd pod with a generated host key and pinning it "+
+		"in the known_hosts file of rsync. Not supported by the local strategy")
 	flags.StringP(FlagDestHostOverride, "H", "",
 		"the override for the rsync host destination when it is run over SSH, "+
 			"in cases when you need to target a different destination IP on rsync for some reason. "+
 			"By default, it is determined by used strategy and differs across strategies. "+
 			"Has no effect for mnt2 and local strategies")
-	flags.Duration(FlagLBSvcTimeout, lbSvcTimeoutDefault, fmt.Sprintf("timeout for the load balancer service to "+
-		"receive an external IP. Only used by the %s strategy", strategy.LbSvcStrategy))
-	flags.Bool(FlagCompress, true, "compress data during migration ('-z' flag of rsync)")
-
-	flags.DurationP(FlagHelmTimeout, "t", 1*time.Minute, "install/uninstall timeout for helm releases")
+	flags.String(FlagDestSSHHost, "", fmt.Sprintf("the externally reachable host, e.g., of an ingress or a bastion, "+
+		"the rsync job on the destination connects to the SSH server on the source with, instead of the address of "+
+		"a load balancer service, for clusters whose networks are isolated from each other. It is expected "+
+		"to route to the service created in the source namespace, named with --"+FlagSSHServiceName+", "+
+		"or pv-migrate-<attempt id>-src-sshd by default. "+
+		"Only used by the %s strategy", strategy.LbSvcStrategy))
+	flags.Int(FlagDestSSHPort, 0, "the port of the endpoint given with --"+FlagDestSSHHost+", defaults to 22")
+	cmd.MarkFlagsMutuallyExclusive(FlagDestSSHHost, FlagDestHostOverride)
+	flags.String(FlagSSHServiceName, "", fmt.Sprintf("the name of the service of the SSH server created by the %s "+
+		"and %s strategies, e.g., to allow the migration traffic with NetworkPolicies authored in advance, "+
+		"or to route the endpoint of --%s to it. By default, it is generated for each attempt",
+		strategy.SvcStrategy, strategy.LbSvcStrategy, FlagDestSSHHost))
+	flags.String(FlagSSHClusterIP, "", fmt.Sprintf("the fixed cluster IP of the service of the SSH server created "+
+		"by the %s strategy. It must be in the service CIDR of the source cluster. "+
+		"By default, it is allocated by the cluster", strategy.SvcStrategy))
+	flags.Int(FlagRsyncdPort, migration.DefaultRsyncdPort, fmt.Sprintf("the port of the rsync daemon "+
+		"run by the %s strategy, and of its service", strategy.RsyncdStrategy))
+	flags.Int(FlagSSHConnectRetries, 0, "number of times to retry establishing the SSH connection "+
+		"before starting rsync, separate from the retries of the data transfer. "+
+		"Useful when the service takes a while to become reachable. Has no effect for the mnt2 strategy")
+	flags.Duration(FlagLBSvcTimeout, migration.DefaultLBSvcTimeout, fmt.Sprintf("timeout for the load balancer "+
+		"service to receive an external IP. Only used by the %s strategy", strategy.LbSvcStrategy))
+	flags.String(FlagIntermediateBucket, "", fmt.Sprintf("the bucket of an object store, optionally with a path "+
+		"prefix, e.g., my-bucket/migrations, to stage the data in for the %s strategy, which uploads the source "+
+		"to it and downloads the destination from it, for the clusters which cannot reach each other reliably. "+
+		"The data is kept under <source namespace>/<source PVC> in it, and the strategy is only applicable "+
+		"between different clusters when it is set. Requires --%s", strategy.ObjStoreStrategy,
+		FlagIntermediateSecret))
+	flags.String(FlagIntermediateSecret, "", fmt.Sprintf("the name of an existing Secret holding the credentials "+
+		"of the object store of --%s, in both the source and the destination namespaces. It must have "+
+		"an rclone config under the %q key, defining a remote named %q to access the object store with",
+		FlagIntermediateBucket, strategy.IntermediateCredentialsKey, strategy.IntermediateRemote))
+	flags.String(FlagIntermediatePhase, strategy.ObjStorePhaseAll, fmt.Sprintf("the phases of the %s strategy "+
+		"to run, one of: %s, e.g., to only upload the source while the destination cluster is not reachable, "+
+		"and download it later. Each phase resumes from the files already transferred when it is run again, "+
+		"while a partially transferred file is transferred again from its start",
+		strategy.ObjStoreStrategy, strings.Join(strategy.ObjStorePhases, ", ")))
+	flags.String(FlagIntermediateImage, strategy.DefaultIntermediateImage, fmt.Sprintf("the image running "+
+		"rclone in the jobs of the %s strategy, in the form of <repository>:<tag>", strategy.ObjStoreStrategy))
+	flags.Bool(FlagCompress, migration.DefaultCompress, "compress data during migration ('-z' flag of rsync)")
+	flags.Bool(FlagAutoCompress, false, fmt.Sprintf("measure the throughput of the link before the transfer, "+
+		"and compress the data only if it is below %d Mbit/s, i.e., where compressing is faster than transferring "+
+		"the data as is. The throughput is measured over SSH, so for the mnt2 and rsyncd strategies, "+
+		"the data is compressed as with --%s", rsync.AutoCompressThreshold*8/1_000_000, FlagCompress))
+	cmd.MarkFlagsMutuallyExclusive(FlagCompress, FlagAutoCompress)
+	flags.Bool(FlagSSHCompression, false, "compress the SSH connection rsync runs over ('-C' flag of ssh), "+
+		"including the protocol messages of rsync, e.g., for links with very high latency. "+
+		"Combined with --"+FlagCompress+", the data is compressed twice, which is usually counterproductive, "+
+		"so consider disabling it with --"+FlagCompress+"=false. Has no effect for the mnt2 and rsyncd strategies")
+	flags.Bool(FlagItemize, false, "log the changes rsync makes on each file at debug level "+
+		"('--itemize-changes' flag of rsync). This can be verbose for large file trees")
+	flags.Int(FlagRsyncVerbose, migration.DefaultRsyncVerbose, fmt.Sprintf("the verbosity level of rsync "+
+		"from 1 to %d, i.e., the number of '-v' flags passed to it. Above 1, the output of rsync other than "+
+		"the progress is logged at info level",
+		migration.MaxRsyncVerbosity))
+	flags.Int(FlagParallel, migration.DefaultParallel, "number of rsync streams to split the top-level entries "+
+		"of the source path across, each running in its own pod. "+
+		"The pods are spread across the nodes where the volumes allow it. "+
+		"The progress bar is not displayed when it is greater than 1. "+
+		fmt.Sprintf("Cannot be combined with --%s. Only supported by the %s strategies, and not for block volumes",
+			FlagDestDeleteExtraneousFiles, strings.Join(strategy.ParallelStrategies, ", ")))
+	flags.Int(FlagMaxConcurrentPods, 0, fmt.Sprintf("the maximum number of the pods of the migration to run at once, "+
+		"including the pod of the SSH server, e.g., to avoid overwhelming the scheduler or exceeding the quotas "+
+		"with a large --%s. The rsync pods over the limit wait for the others to complete. ", FlagParallel)+
+		"At least one rsync pod is always run. 0 means no limit")
+	flags.String(FlagTopologySpread, "", fmt.Sprintf("the node label to spread the rsync pods of --%s evenly across "+
+		"the values of, e.g., topology.kubernetes.io/zone to maximize the aggregate bandwidth in multi-zone clusters. "+
+		"The spreading is preferred, not required. It is ignored when the volumes require the pods to run "+
+		"on a single node. By default, the pods are only preferred to run on different nodes", FlagParallel))
+	flags.Bool(FlagWatch, false, "keep syncing the data from the source to the destination repeatedly until interrupted, "+
+		"to keep the destination up-to-date while the source is still in use. "+
+		"A final sync after stopping the workload using the source will then be fast")
+	flags.Duration(FlagSyncInterval, migration.DefaultSyncInterval, fmt.Sprintf("the interval between "+
+		"the syncs when --%s is enabled", FlagWatch))
+	flags.Int(FlagBlockSize, 0, "the block size in bytes for the delta-transfer algorithm of rsync "+
+		"('--block-size' flag of rsync). Larger blocks can speed up the transfer of big files, but make "+
+		"the detection of small changes in them less precise. By default, rsync chooses it based on the file size")
+	flags.Bool(FlagHardLinks, false, "preserve the hard links instead of copying the linked files separately "+
+		"('-H' flag of rsync). rsync needs to keep track of all the files with multiple links in memory, "+
+		"which can increase its memory usage considerably on large file trees")
+	flags.Bool(FlagOmitDirTimes, false, "do not preserve the modification times of the directories "+
+		"('--omit-dir-times' flag of rsync). By default, they are preserved like those of the files, "+
+		"and set to the ones on the source after their entries are transferred, also when entries are deleted "+
+		"with --"+FlagDestDeleteExtraneousFiles+". When omitted, the directories on the destination get "+
+		"the time of the migration if their entries are created or deleted by it")
+	flags.Bool(FlagDirsOnly, false, "only migrate the directory structure, i.e., the directories with their "+
+		"owners, permissions and times, without the files, e.g., to stage the layout on the destination before "+
+		"the full migration. The files, the symlinks and the special files are excluded with the "+
+		"\"--filter='+ */' --filter='- *'\" rules of rsync, after the rules of --"+FlagFilterFile+", "+
+		"so they are not deleted by --"+FlagDestDeleteExtraneousFiles+" either")
+	flags.Bool(FlagNumericIDs, false, "preserve the numeric user and group IDs instead of mapping them by name "+
+		"('--numeric-ids' flag of rsync). Use it when the users and groups differ between the images "+
+		"on the source and the destination, e.g., across clusters")
+	flags.Bool(FlagDelayUpdates, false, "put the updated files into place all together at the end of the transfer "+
+		"('--delay-updates' flag of rsync), to shorten the window in which the destination is inconsistent "+
+		"when it is read during the migration. The updated files are kept in temporary files until then, "+
+		"so the destination needs free space for all of them in addition to the files they replace")
+	flags.Int(FlagEvictionRetries, 0, "the number of times to resume the transfer in a new rsync pod "+
+		"when the rsync pod is disrupted, e.g., evicted or its node drained, for long migrations. "+
+		"The partially transferred files are kept on the destination ('--partial-dir' flag of rsync) "+
+		"for the new pod to resume them. The other failures of rsync are not retried by it. "+
+		"Requires Kubernetes 1.26 or later, and not supported by the "+strategy.LocalStrategy+" strategy")
+	flags.Bool(FlagWholeFile, false, "send the changed files whole instead of only their changed parts "+
+		"('--whole-file' flag of rsync). Saves CPU on fast networks, where the delta-transfer algorithm of rsync "+
+		"is slower than sending the data. By default, rsync only sends whole files when both sides are local, "+
+		fmt.Sprintf("e.g., with the %s strategy", strategy.Mnt2Strategy))
+	flags.Bool(FlagNoWholeFile, false, "always use the delta-transfer algorithm of rsync to send only the changed "+
+		"parts of the files ('--no-whole-file' flag of rsync). Saves bandwidth on slow networks")
+	cmd.MarkFlagsMutuallyExclusive(FlagWholeFile, FlagNoWholeFile)
+	flags.String(FlagFilesFrom, "", "path of a local file listing the paths to migrate, one per line, "+
+		"relative to the source path ('--files-from' flag of rsync). Only the listed files are migrated, "+
+		fmt.Sprintf("the listed directories are not recursed into. Cannot be combined with --%s or --%s",
+			FlagParallel, FlagDestDeleteExtraneousFiles))
+	flags.String(FlagSince, "", "only migrate the files modified after the given time, in the RFC3339 format, "+
+		"e.g., 2024-05-01T12:00:00Z, for incremental migrations. The files are listed with 'find -newer' "+
+		"on the source side and passed to rsync with '--files-from', so the directories are only created "+
+		"as the parents of the listed files and the deleted files are not detected. "+
+		fmt.Sprintf("Cannot be combined with --%s, --%s or --%s, and not supported by the %s strategy",
+			FlagFilesFrom, FlagParallel, FlagDestDeleteExtraneousFiles, strategy.RsyncdStrategy))
+	flags.String(FlagCompareDest, "", "path of a reference directory in the destination PVC, e.g., the destination "+
+		"of a previous migration, to skip the files identical to those in it ('--compare-dest' flag of rsync), "+
+		"for layered or incremental migrations. The migration fails if it does not exist")
+	flags.Bool(FlagBackup, false, "keep the files overwritten or deleted on the destination instead of losing them "+
+		"('--backup' flag of rsync), renamed with a '~' suffix in place, or moved to --"+FlagBackupDir+
+		" if it is set. Without --"+FlagBackupDir+", the times of the directories are not preserved")
+	flags.String(FlagBackupDir, "", "path of a directory in the destination PVC to move the files overwritten "+
+		"or deleted on the destination to ('--backup-dir' flag of rsync), keeping their paths relative to "+
+		"the destination path. It implies --"+FlagBackup+" and is created if it does not exist. If it is inside "+
+		"the destination path, it is protected from the deletions of --"+FlagDestDeleteExtraneousFiles)
+	flags.String(FlagFilterFile, "", "path of a local rsync filter file, with the include, exclude and other rules "+
+		"in the merge-file syntax of rsync, to be applied to the migration ('--filter=. FILE' flag of rsync). "+
+		fmt.Sprintf("Not supported by the %s strategy", strategy.LocalStrategy))
+	flags.String(FlagChmod, "", "the permissions to apply to the migrated files on the destination "+
+		"('--chmod' flag of rsync), as a comma-separated list of chmod modes, optionally prefixed with D or F "+
+		"to only apply to directories or files, e.g., 'Dg+s,ug+w,Fo-w'. The permissions of the source "+
+		"are preserved and these are applied on top of them. By default, the source permissions are kept as is")
+	flags.String(FlagIconv, "", "convert the charset of the file names between the source and the destination "+
+		"('--iconv' flag of rsync), in the form of LOCAL,REMOTE, e.g., 'UTF-8,ISO-8859-1', where LOCAL is "+
+		"the charset of the side running rsync. The rsync in both the rsync and the sshd images must be built "+
+		"with iconv support. By default, the file names are transferred as is")
+	flags.Int(FlagIOTimeout, 0, "the number of seconds without any data transferred after which rsync aborts "+
+		"('--timeout' flag of rsync), so that a stalled transfer is retried instead of hanging forever. "+
+		"0 means no timeout")
+	flags.Int(FlagConnectTimeout, rsync.DefaultConnectTimeoutSeconds, "the number of seconds to wait "+
+		"for the connection of rsync to the remote side to be established, i.e., the 'ConnectTimeout' option "+
+		fmt.Sprintf("of ssh, or the '--contimeout' flag of rsync with the %s strategy, ", strategy.RsyncdStrategy)+
+		"separately from --"+FlagIOTimeout+". 0 means no timeout. By default, the rsync daemon has no timeout")
+	flags.Bool(FlagUpdate, false, "skip the files which are newer on the destination than on the source "+
+		"('-u' flag of rsync), e.g., for a top-up sync to a destination that is already partially in use")
+	flags.String(FlagConflict, conflictOverwrite, "what to do with the files which exist on both the source and "+
+		"the destination, must be one of: "+strings.Join(conflictPolicies, ", ")+". "+
+		fmt.Sprintf("%s replaces them, %s keeps the ones newer on the destination (same as --%s) and ",
+			conflictOverwrite, conflictKeepNewer, FlagUpdate)+
+		fmt.Sprintf("%s keeps all of them ('--ignore-existing' flag of rsync)", conflictSkipExisting))
+	flags.Int(FlagProtocol, 0, "the version of the rsync protocol to use ('--protocol' flag of rsync), "+
+		"when the rsync versions in the images of the source and the destination fail to negotiate it, "+
+		"e.g., 29 for rsync 2.6.x, 30 for 3.0.x and 31 for 3.1.x and later. By default, it is negotiated")
+	flags.String(FlagSockOpts, "", "the TCP socket options to tune the connections of rsync with, passed as is "+
+		"('--sockopts' flag of rsync), e.g., 'SO_SNDBUF=4194304,SO_RCVBUF=4194304' for links with a high "+
+		"bandwidth-delay product. Only supported by the "+strategy.RsyncdStrategy+" strategy, "+
+		"the transfers over SSH use the socket options of ssh instead")
+	flags.String(FlagBwLimitSchedule, "", "the daily time windows in UTC with different bandwidth limits of rsync, "+
+		"as a comma-separated list of HH:MM-HH:MM=LIMIT, where LIMIT is in the format of the '--bwlimit' flag "+
+		"of rsync, e.g., '09:00-17:00=10M' to limit it during the business hours. There is no limit outside "+
+		"the windows. rsync is restarted with the new limit at the boundaries of the windows, resuming "+
+		"the partially transferred files. Cannot be combined with --"+FlagSince+" or --"+FlagParallel+
+		", nor with --"+FlagFilesFrom+" for the "+strategy.LocalStrategy+" strategy")
+	flags.String(FlagWebhookURL, "", "the URL to POST the events of the migration to as JSON, "+
+		"i.e., started, strategy-selected, progress, completed and failed. "+
+		"Failures to deliver the events are logged but do not fail the migration")
+	flags.String(FlagResultFile, "", "the path of a file to write the result of the migration to as JSON "+
+		"on success or failure, i.e., the status, the error, the attempted strategies with their errors, "+
+		"the exit code of rsync and the transfer stats so far. In watch mode, it is rewritten after each sync. "+
+		"With --"+FlagSourceWorkload+", the result of each PVC is written to its own file, "+
+		"with the name of the PVC inserted before the extension, e.g., result-data.json")
+	flags.String(FlagChecksumManifest, "", "after the migration, compute the SHA-256 checksums of the files "+
+		"in the source and the destination paths in a pod mounting each PVC read-only, write those of the source "+
+		"to the file at the given path in the format of sha256sum, and fail if any file is missing or differs "+
+		"on the destination. The extraneous files on the destination are only logged. "+
+		"Not supported for the PVCs with the Block volume mode")
+	flags.String(FlagOTLPEndpoint, "", "the OTLP/HTTP endpoint to export the OpenTelemetry traces of the migration "+
+		"phases to, e.g., http://localhost:4318. Tracing is disabled if not set")
+	flags.Bool(FlagFromSnapshot, false, "take a CSI volume snapshot of the source PVC and migrate from a temporary "+
+		"PVC restored from it, to copy a consistent point-in-time state of the source without stopping "+
+		"the workload using it. The source PVC is then allowed to be mounted. The temporary PVC and the snapshot "+
+		fmt.Sprintf("are deleted afterwards, unless --%s is set", FlagSkipCleanup))
+	flags.String(FlagSnapshotClass, "", fmt.Sprintf("the VolumeSnapshotClass to use for the %s strategy "+
+		"and --%s. By default, the class matching the CSI driver of the source PVC's storage class is used",
+		strategy.SnapshotStrategy, FlagFromSnapshot))
+	flags.Bool(FlagSnapshotAfter, false, "take a CSI volume snapshot of the destination PVC after a successful "+
+		"migration, as a checkpoint to restore from. The snapshot is kept and its name is logged. "+
+		"It is skipped if the storage class of the destination PVC does not support volume snapshots. "+
+		"In watch mode, a snapshot is taken after each sync")
+	flags.Bool(FlagPreserveSnapshotBase, false, "take a base CSI volume snapshot of the destination PVC "+
+		"after the first full copy, for the storage systems with snapshot chains, e.g., managed by backup tools, "+
+		"to build their incremental snapshots upon. It is named after the destination PVC with the "+
+		fmt.Sprintf("'-pv-migrate-base' suffix, and is kept as is by the later migrations and syncs of --%s",
+			FlagWatch))
+	flags.String(FlagSnapshotAfterClass, "",
+		fmt.Sprintf("the VolumeSnapshotClass to use for --%s and --%s. ", FlagSnapshotAfter, FlagPreserveSnapshotBase)+
+			"By default, the class matching the CSI driver of the destination PVC's storage class is used")
+
+	flags.DurationP(FlagHelmTimeout, "t", migration.DefaultHelmTimeout, "install/uninstall timeout for helm releases")
 	flags.StringSliceP(FlagHelmValues, "f", nil,
 		"set additional Helm values by a YAML file or a URL (can specify multiple)")
 	flags.StringSlice(FlagHelmSet, nil, "set additional Helm values on the command line (can specify "+
@@ -222,6 +691,20 @@ func setMigrateCmdFlags(cmd *cobra.Command, logLevels, logFormats []string, lega
 		"(can specify multiple or separate values with commas: key1=val1,key2=val2)")
 	flags.StringSlice(FlagHelmSetFile, nil, "set additional Helm values from respective files specified "+
 		"via the command line (can specify multiple or separate values with commas: key1=path1,key2=path2)")
+	flags.String(FlagCoordinationNamespace, "", "namespace to store the Helm releases of the migration in, "+
+		"in both the source and the destination clusters, to keep them apart from the data namespaces. "+
+		"The pods, and the services and secrets they use, are still created in the namespaces of the PVCs. "+
+		"By default, the releases are stored in the namespaces of the PVCs")
+
+	flags.Bool(FlagValidateOnly, false, "only validate the migration, i.e., the flags, the kubeconfigs, "+
+		"the reachability of the clusters and the PVCs, and exit without creating any resources or transferring data")
+	flags.Bool(FlagPrintCommand, false, "log the full rsync command of each attempt before it is run, "+
+		"e.g., for auditing or for reproducing the transfer manually. It does not contain the SSH keys")
+	flags.BoolP(FlagYes, "y", false, fmt.Sprintf("do not ask for confirmation before destructive operations "+
+		"such as --%s. Required when the standard input is not a terminal", FlagDestDeleteExtraneousFiles))
+	flags.Bool(FlagExpandEnv, false, "expand the environment variable references in the form of ${VAR} "+
+		"in the string flag values. It is an error to reference an undefined variable, "+
+		"unless a default is provided as ${VAR:-default}. Use $$ for a literal $")
 }
 
 //nolint:funlen
@@ -230,6 +713,12 @@ func runMigration(cmd *cobra.Command, args []string) error {
 
 	ctx := cmd.Context()
 
+	if expandEnv, _ := flags.GetBool(FlagExpandEnv); expandEnv {
+		if err := expandEnvInFlags(flags); err != nil {
+			return fmt.Errorf("failed to expand environment variables in flags: %w", err)
+		}
+	}
+
 	logger, canDisplayProgressBar, err := buildLogger(flags)
 	if err != nil {
 		return fmt.Errorf("failed to build logger: %w", err)
@@ -239,7 +728,7 @@ func runMigration(cmd *cobra.Command, args []string) error {
 		ctx = context.WithValue(ctx, progress.CanDisplayProgressBarContextKey{}, struct{}{})
 	}
 
-	var src, dest string
+	var src, dest, workload, sourcePV string
 
 	//nolint:mnd
 	if len(args) == 2 {
@@ -251,50 +740,381 @@ func runMigration(cmd *cobra.Command, args []string) error {
 	} else {
 		src, _ = flags.GetString(FlagSource)
 		dest, _ = flags.GetString(FlagDest)
+		workload, _ = flags.GetString(FlagSourceWorkload)
+		sourcePV, _ = flags.GetString(FlagSourcePV)
 	}
 
 	ignoreMounted, _ := flags.GetBool(FlagIgnoreMounted)
+	scaleDownDest, _ := flags.GetBool(FlagScaleDownDest)
+	destReclaimPolicy, _ := flags.GetString(FlagDestReclaimPolicy)
 	srcMountReadOnly, _ := flags.GetBool(FlagSourceMountReadOnly)
+	sourcePrepareCommand, _ := flags.GetString(FlagSourcePrepareCommand)
 	noChown, _ := flags.GetBool(FlagNoChown)
+	noOwner, _ := flags.GetBool(FlagNoOwner)
+	noGroup, _ := flags.GetBool(FlagNoGroup)
 	skipCleanup, _ := flags.GetBool(FlagSkipCleanup)
+	keepResources, _ := flags.GetStringSlice(FlagKeepResources)
+	addCapabilitiesList, _ := flags.GetStringSlice(FlagAddCapability)
+	dropCapabilitiesList, _ := flags.GetStringSlice(FlagDropCapability)
+	serverSideApply, _ := flags.GetBool(FlagServerSideApply)
 	noProgressBar, _ := flags.GetBool(FlagNoProgressBar)
+	etaInterval, _ := flags.GetDuration(FlagETAInterval)
 	sshKeyAlg, _ := flags.GetString(FlagSSHKeyAlgorithm)
+	rsyncUser, _ := flags.GetString(FlagRsyncUser)
+	strictHostKeyChecking, _ := flags.GetBool(FlagStrictHostKeyChecking)
 	helmTimeout, _ := flags.GetDuration(FlagHelmTimeout)
+	coordinationNamespace, _ := flags.GetString(FlagCoordinationNamespace)
 	helmValues, _ := flags.GetStringSlice(FlagHelmValues)
 	helmSet, _ := flags.GetStringSlice(FlagHelmSet)
 	helmSetString, _ := flags.GetStringSlice(FlagHelmSetString)
 	helmSetFile, _ := flags.GetStringSlice(FlagHelmSetFile)
 	strs, _ := flags.GetStringSlice(FlagStrategies)
+	strategyProfile, _ := flags.GetString(FlagStrategyProfile)
+	configPath, _ := flags.GetString(FlagConfig)
 	destHostOverride, _ := flags.GetString(FlagDestHostOverride)
+	noDestMkdir, _ := flags.GetBool(FlagNoDestMkdir)
+	includeLostFound, _ := flags.GetBool(FlagIncludeLostFound)
+	failIfDestNotEmpty, _ := flags.GetBool(FlagFailIfDestNotEmpty)
+	destSSHHost, _ := flags.GetString(FlagDestSSHHost)
+	destSSHPort, _ := flags.GetInt(FlagDestSSHPort)
+	sshServiceName, _ := flags.GetString(FlagSSHServiceName)
+	sshClusterIP, _ := flags.GetString(FlagSSHClusterIP)
+	rsyncdPort, _ := flags.GetInt(FlagRsyncdPort)
 	lbSvcTimeout, _ := flags.GetDuration(FlagLBSvcTimeout)
+	intermediateBucket, _ := flags.GetString(FlagIntermediateBucket)
+	intermediateSecret, _ := flags.GetString(FlagIntermediateSecret)
+	intermediatePhase, _ := flags.GetString(FlagIntermediatePhase)
+	intermediateImage, _ := flags.GetString(FlagIntermediateImage)
 	compress, _ := flags.GetBool(FlagCompress)
+	sshCompression, _ := flags.GetBool(FlagSSHCompression)
+	autoCompress, _ := flags.GetBool(FlagAutoCompress)
+	snapshotClass, _ := flags.GetString(FlagSnapshotClass)
+	itemize, _ := flags.GetBool(FlagItemize)
+	rsyncVerbose, _ := flags.GetInt(FlagRsyncVerbose)
+	parallel, _ := flags.GetInt(FlagParallel)
+	maxConcurrentPods, _ := flags.GetInt(FlagMaxConcurrentPods)
+	topologySpread, _ := flags.GetString(FlagTopologySpread)
+	watch, _ := flags.GetBool(FlagWatch)
+	syncInterval, _ := flags.GetDuration(FlagSyncInterval)
+	sshConnectRetries, _ := flags.GetInt(FlagSSHConnectRetries)
+	blockSize, _ := flags.GetInt(FlagBlockSize)
+	hardLinks, _ := flags.GetBool(FlagHardLinks)
+	omitDirTimes, _ := flags.GetBool(FlagOmitDirTimes)
+	dirsOnly, _ := flags.GetBool(FlagDirsOnly)
+	numericIDs, _ := flags.GetBool(FlagNumericIDs)
+	delayUpdates, _ := flags.GetBool(FlagDelayUpdates)
+	evictionRetries, _ := flags.GetInt(FlagEvictionRetries)
+	wholeFile := parseWholeFileFlags(flags)
+	filesFromPath, _ := flags.GetString(FlagFilesFrom)
+	sinceStr, _ := flags.GetString(FlagSince)
+	compareDest, _ := flags.GetString(FlagCompareDest)
+	backup, _ := flags.GetBool(FlagBackup)
+	backupDir, _ := flags.GetString(FlagBackupDir)
+	filterFilePath, _ := flags.GetString(FlagFilterFile)
+	chmod, _ := flags.GetString(FlagChmod)
+	iconv, _ := flags.GetString(FlagIconv)
+	ioTimeout, _ := flags.GetInt(FlagIOTimeout)
+	update, _ := flags.GetBool(FlagUpdate)
+	conflict, _ := flags.GetString(FlagConflict)
+	protocol, _ := flags.GetInt(FlagProtocol)
+	sockOpts, _ := flags.GetString(FlagSockOpts)
+	bwLimitScheduleStr, _ := flags.GetString(FlagBwLimitSchedule)
+	webhookURL, _ := flags.GetString(FlagWebhookURL)
+	otlpEndpoint, _ := flags.GetString(FlagOTLPEndpoint)
+	validateOnly, _ := flags.GetBool(FlagValidateOnly)
+	printCommand, _ := flags.GetBool(FlagPrintCommand)
+	resultFile, _ := flags.GetString(FlagResultFile)
+	checksumManifest, _ := flags.GetString(FlagChecksumManifest)
+	fromSnapshot, _ := flags.GetBool(FlagFromSnapshot)
+	snapshotAfter, _ := flags.GetBool(FlagSnapshotAfter)
+	snapshotAfterClass, _ := flags.GetString(FlagSnapshotAfterClass)
+	preserveSnapshotBase, _ := flags.GetBool(FlagPreserveSnapshotBase)
+	respectTopology, _ := flags.GetBool(FlagRespectTopology)
+	checkTopology, _ := flags.GetBool(FlagCheckTopology)
+	strictFS, _ := flags.GetBool(FlagStrictFS)
+	runtimeClass, _ := flags.GetString(FlagRuntimeClass)
+	allowSidecars, _ := flags.GetBool(FlagAllowSidecars)
+	podDNSPolicy, _ := flags.GetString(FlagPodDNSPolicy)
+	podDNSNameservers, _ := flags.GetStringSlice(FlagPodDNSNameserver)
+	preserve, _ := flags.GetStringSlice(FlagPreserve)
 
 	deleteExtraneousFiles, _ := flags.GetBool(FlagDestDeleteExtraneousFiles)
+
+	var maxDelete *int
+
+	if flags.Changed(FlagMaxDelete) {
+		value, _ := flags.GetInt(FlagMaxDelete)
+		maxDelete = &value
+	}
+
+	for _, kind := range keepResources {
+		if !slices.Contains(strategy.KeepableResourceKinds, kind) {
+			return fmt.Errorf("--%s must be a list of: %s", FlagKeepResources,
+				strings.Join(strategy.KeepableResourceKinds, ", "))
+		}
+	}
+
+	addCapabilities, err := parseCapabilities(FlagAddCapability, addCapabilitiesList)
+	if err != nil {
+		return err
+	}
+
+	dropCapabilities, err := parseCapabilities(FlagDropCapability, dropCapabilitiesList)
+	if err != nil {
+		return err
+	}
+
+	if strategyProfile != "" {
+		cfg, configErr := loadConfig(configPath)
+		if configErr != nil {
+			return configErr
+		}
+
+		if strs, err = cfg.strategyProfile(strategyProfile); err != nil {
+			return err
+		}
+	}
+
+	for _, attribute := range preserve {
+		if !slices.Contains(preservableAttributes, attribute) {
+			return fmt.Errorf("--%s must be one of: %s", FlagPreserve, strings.Join(preservableAttributes, ", "))
+		}
+	}
+
+	update, ignoreExisting, err := applyConflictPolicy(conflict, update)
+	if err != nil {
+		return err
+	}
+
+	var connectTimeout *int
+
+	if flags.Changed(FlagConnectTimeout) {
+		value, _ := flags.GetInt(FlagConnectTimeout)
+		connectTimeout = &value
+	}
+
+	if parallel > 1 && !slices.ContainsFunc(strs, func(name string) bool {
+		return slices.Contains(strategy.ParallelStrategies, name)
+	}) {
+		return fmt.Errorf("--%s is only supported by the %s strategies, none of which is in --%s",
+			FlagParallel, strings.Join(strategy.ParallelStrategies, ", "), FlagStrategies)
+	}
+
+	// the transfers over SSH do not use the sockets opened by rsync, on which the options are set
+	if sockOpts != "" && !slices.Contains(strs, strategy.RsyncdStrategy) {
+		return fmt.Errorf("--%s is only supported by the %s strategy, which is not in --%s",
+			FlagSockOpts, strategy.RsyncdStrategy, FlagStrategies)
+	}
+
+	var bwLimitSchedule []rsync.BwLimitWindow
+
+	if bwLimitScheduleStr != "" {
+		// the local strategy passes the files list through the standard input, which the schedule cannot replay
+		if filesFromPath != "" && slices.Contains(strs, strategy.LocalStrategy) {
+			return fmt.Errorf("--%s cannot be used together with --%s for the %s strategy",
+				FlagBwLimitSchedule, FlagFilesFrom, strategy.LocalStrategy)
+		}
+
+		if bwLimitSchedule, err = rsync.ParseBwLimitSchedule(bwLimitScheduleStr); err != nil {
+			return fmt.Errorf("invalid --%s: %w", FlagBwLimitSchedule, err)
+		}
+	}
+
+	clientImage, err := getImage(flags, FlagClientImage, EnvRsyncImage)
+	if err != nil {
+		return err
+	}
+
+	serverImage, err := getImage(flags, FlagServerImage, EnvSshdImage)
+	if err != nil {
+		return err
+	}
+
+	if otlpEndpoint != "" {
+		if parsed, err := url.Parse(otlpEndpoint); err != nil || parsed.Host == "" ||
+			(parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return fmt.Errorf("--%s must be an http or https URL", FlagOTLPEndpoint)
+		}
+	}
+
+	var filesFrom string
+
+	if filesFromPath != "" {
+		if filesFrom, err = readUploadedFile(FlagFilesFrom, filesFromPath); err != nil {
+			return err
+		}
+	}
+
+	var since time.Time
+
+	if sinceStr != "" {
+		if since, err = time.Parse(time.RFC3339, sinceStr); err != nil {
+			return fmt.Errorf("--%s must be a time in the RFC3339 format: %w", FlagSince, err)
+		}
+	}
+
+	var filterFile string
+
+	if filterFilePath != "" {
+		if filterFile, err = readUploadedFile(FlagFilterFile, filterFilePath); err != nil {
+			return err
+		}
+	}
+
+	seccompProfile, err := parseSecurityProfileFlag(flags, FlagSeccompProfile)
+	if err != nil {
+		return err
+	}
+
+	appArmorProfile, err := parseSecurityProfileFlag(flags, FlagAppArmorProfile)
+	if err != nil {
+		return err
+	}
+
+	extraVolumeValues, _ := flags.GetStringArray(FlagExtraVolume)
+
+	extraVolumes, err := parseExtraVolumes(extraVolumeValues)
+	if err != nil {
+		return err
+	}
+
 	request := migration.Request{
 		Source:                buildSrcPVCInfo(flags, src),
 		Dest:                  buildDestPVCInfo(flags, dest),
 		DeleteExtraneousFiles: deleteExtraneousFiles,
 		IgnoreMounted:         ignoreMounted,
+		ScaleDownDest:         scaleDownDest,
+		DestReclaimPolicy:     destReclaimPolicy,
 		SourceMountReadOnly:   srcMountReadOnly,
+		SourcePrepareCommand:  sourcePrepareCommand,
 		NoChown:               noChown,
+		NoOwner:               noOwner,
+		NoGroup:               noGroup,
 		SkipCleanup:           skipCleanup,
+		KeepResources:         keepResources,
+		AddCapabilities:       addCapabilities,
+		DropCapabilities:      dropCapabilities,
+		ServerSideApply:       serverSideApply,
 		NoProgressBar:         noProgressBar,
+		ETAInterval:           etaInterval,
 		KeyAlgorithm:          sshKeyAlg,
+		RsyncUser:             rsyncUser,
+		StrictHostKeyChecking: strictHostKeyChecking,
 		HelmTimeout:           helmTimeout,
+		CoordinationNamespace: coordinationNamespace,
 		HelmValuesFiles:       helmValues,
 		HelmValues:            helmSet,
 		HelmStringValues:      helmSetString,
 		HelmFileValues:        helmSetFile,
 		Strategies:            strs,
 		DestHostOverride:      destHostOverride,
+		NoDestMkdir:           noDestMkdir,
+		IncludeLostFound:      includeLostFound,
+		FailIfDestNotEmpty:    failIfDestNotEmpty,
+		DestSSHHost:           destSSHHost,
+		DestSSHPort:           destSSHPort,
+		SSHServiceName:        sshServiceName,
+		SSHClusterIP:          sshClusterIP,
+		RsyncdPort:            rsyncdPort,
 		LBSvcTimeout:          lbSvcTimeout,
-		Compress:              compress,
+		IntermediateBucket:    intermediateBucket,
+		IntermediateSecret:    intermediateSecret,
+		IntermediatePhase:     intermediatePhase,
+		IntermediateImage:     intermediateImage,
+		Compress:              compress && !autoCompress,
+		SSHCompression:        sshCompression,
+		AutoCompress:          autoCompress,
+		SnapshotClass:         snapshotClass,
+		Itemize:               itemize,
+		RsyncVerbose:          rsyncVerbose,
+		Parallel:              parallel,
+		MaxConcurrentPods:     maxConcurrentPods,
+		TopologySpread:        topologySpread,
+		Watch:                 watch,
+		SyncInterval:          syncInterval,
+		SSHConnectRetries:     sshConnectRetries,
+		BlockSize:             blockSize,
+		HardLinks:             hardLinks,
+		OmitDirTimes:          omitDirTimes,
+		DirsOnly:              dirsOnly,
+		NumericIDs:            numericIDs,
+		FilesFrom:             filesFrom,
+		Since:                 since,
+		CompareDest:           compareDest,
+		Backup:                backup,
+		BackupDir:             backupDir,
+		FilterFile:            filterFile,
+		Chmod:                 chmod,
+		Iconv:                 iconv,
+		IOTimeout:             ioTimeout,
+		ConnectTimeout:        connectTimeout,
+		Update:                update,
+		IgnoreExisting:        ignoreExisting,
+		DelayUpdates:          delayUpdates,
+		WholeFile:             wholeFile,
+		MaxDelete:             maxDelete,
+		EvictionRetries:       evictionRetries,
+		Protocol:              protocol,
+		SockOpts:              sockOpts,
+		BwLimitSchedule:       bwLimitSchedule,
+		WebhookURL:            webhookURL,
+		ResultFile:            resultFile,
+		ChecksumManifest:      checksumManifest,
+		FromSnapshot:          fromSnapshot,
+		SourcePV:              sourcePV,
+		SnapshotAfter:         snapshotAfter,
+		SnapshotAfterClass:    snapshotAfterClass,
+		PreserveSnapshotBase:  preserveSnapshotBase,
+		SeccompProfile:        seccompProfile,
+		AppArmorProfile:       appArmorProfile,
+		RuntimeClass:          runtimeClass,
+		AllowSidecars:         allowSidecars,
+		PodDNSPolicy:          podDNSPolicy,
+		PodDNSNameservers:     podDNSNameservers,
+		PreserveSELinux:       slices.Contains(preserve, preserveSELinux),
+		ClientImage:           clientImage,
+		ServerImage:           serverImage,
+		ExtraVolumes:          extraVolumes,
+		RespectTopology:       respectTopology,
+		CheckTopology:         checkTopology,
+		StrictFS:              strictFS,
+		ValidateOnly:          validateOnly,
+		PrintCommand:          printCommand,
+	}
+
+	if err = request.Validate(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if err = loadKubeconfigSecrets(ctx, flags, &request, logger); err != nil {
+		return err
 	}
 
 	logger.Info("🚀 Starting migration")
 
-	if deleteExtraneousFiles {
+	if deleteExtraneousFiles && !validateOnly {
 		logger.Info("❕ Extraneous files will be deleted from the destination")
+
+		if yes, _ := flags.GetBool(FlagYes); !yes {
+			if err = confirmDeletion(cmd, &request); err != nil {
+				return err
+			}
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, otlpEndpoint)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	defer func() {
+		if shutdownErr := shutdownTracing(context.WithoutCancel(ctx)); shutdownErr != nil {
+			logger.Warn("🔶 Failed to export the traces", "error", shutdownErr)
+		}
+	}()
+
+	if workload != "" {
+		return runWorkloadMigration(ctx, &request, workload, logger)
 	}
 
 	if err := migrator.New().Run(ctx, &request, logger); err != nil {
@@ -304,6 +1124,356 @@ func runMigration(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runWorkloadMigration migrates each PVC of the given source workload to the PVC with the same name on the destination.
+func runWorkloadMigration(ctx context.Context, request *migration.Request, workload string, logger *slog.Logger) error {
+	source := request.Source
+
+	client, err := getPVCClusterClient(source, logger)
+	if err != nil {
+		return fmt.Errorf("failed to get source cluster client: %w", err)
+	}
+
+	namespace := source.Namespace
+	if namespace == "" {
+		namespace = client.NsInContext
+	}
+
+	pvcNames, err := k8s.GetWorkloadPVCNames(ctx, client.KubeClient, namespace, workload)
+	if err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if len(pvcNames) == 0 {
+		return fmt.Errorf("workload %s/%s does not use any PVCs", namespace, workload)
+	}
+
+	dest := request.Dest
+
+	destClient, err := getPVCClusterClient(dest, logger)
+	if err != nil {
+		return fmt.Errorf("failed to get destination cluster client: %w", err)
+	}
+
+	destNamespace := dest.Namespace
+	if destNamespace == "" {
+		destNamespace = destClient.NsInContext
+	}
+
+	// each PVC is migrated to the PVC with the same name, which would be itself
+	if destClient.RestConfig.Host == client.RestConfig.Host && destNamespace == namespace {
+		return fmt.Errorf("the PVCs of workload %s/%s would be migrated onto themselves, as each PVC is migrated "+
+			"to the PVC with the same name: set --%s or --%s to migrate them to another namespace or cluster",
+			namespace, workload, FlagDestNamespace, FlagDestContext)
+	}
+
+	logger.Info("🔍 Found the PVCs of the workload", "workload", namespace+"/"+workload,
+		"pvcs", strings.Join(pvcNames, ","))
+
+	var errs []error
+
+	for _, name := range pvcNames {
+		pvcSource := *request.Source
+		pvcSource.Namespace = namespace
+		pvcSource.Name = name
+
+		pvcDest := *request.Dest
+		pvcDest.Name = name
+
+		pvcRequest := *request
+		pvcRequest.Source = &pvcSource
+		pvcRequest.Dest = &pvcDest
+
+		if request.ResultFile != "" {
+			pvcRequest.ResultFile = workloadResultFile(request.ResultFile, name)
+		}
+
+		if err = migrator.New().Run(ctx, &pvcRequest, logger); err != nil {
+			errs = append(errs, fmt.Errorf("migration of PVC %s failed: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// workloadResultFile returns the path of the result file of the migration of a PVC of a workload,
+// i.e., the path with the name of the PVC inserted before its extension, for each PVC to have its own result.
+func workloadResultFile(resultFile, pvcName string) string {
+	ext := path.Ext(resultFile)
+
+	return strings.TrimSuffix(resultFile, ext) + "-" + pvcName + ext
+}
+
+// confirmDeletion asks the user to confirm that the extraneous files on the destination will be deleted.
+// It returns an error if the user does not confirm, or if the confirmation cannot be asked as the input is not a TTY.
+func confirmDeletion(cmd *cobra.Command, request *migration.Request) error {
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		return fmt.Errorf("--%s requires confirmation, but the input is not a terminal: "+
+			"pass --%s to proceed without confirmation", FlagDestDeleteExtraneousFiles, FlagYes)
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "⚠️ The files which do not exist on the source %s\n"+
+		"   will be DELETED from the destination %s\n"+
+		"Do you want to continue? [y/N]: ", describePVC(request.Source), describePVC(request.Dest))
+
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return errors.New("migration aborted by the user")
+	}
+}
+
+// describePVC returns a human-readable identifier of the PVC, including its context and path.
+func describePVC(info *migration.PVCInfo) string {
+	namespace := info.Namespace
+	if namespace == "" {
+		namespace = "<namespace in context>"
+	}
+
+	name := info.Name
+	if name == "" {
+		name = "<same name as the source PVC>"
+	}
+
+	kubeContext := info.Context
+	if kubeContext == "" {
+		kubeContext = "<current context>"
+	}
+
+	return fmt.Sprintf("%s/%s (path: %s, context: %s)", namespace, name, info.Path, kubeContext)
+}
+
+// applyConflictPolicy returns whether the files newer on the destination and the files existing on the destination
+// are to be skipped, according to the given --conflict policy and --update flag.
+//
+//nolint:nonamedreturns
+func applyConflictPolicy(conflict string, update bool) (skipNewer, skipExisting bool, err error) {
+	switch conflict {
+	case conflictOverwrite:
+		return update, false, nil
+	case conflictKeepNewer:
+		return true, false, nil
+	case conflictSkipExisting:
+		if update {
+			return false, false, fmt.Errorf("--%s cannot be used together with --%s %s",
+				FlagUpdate, FlagConflict, conflict)
+		}
+
+		return false, true, nil
+	default:
+		return false, false, fmt.Errorf("--%s must be one of: %s", FlagConflict, strings.Join(conflictPolicies, ", "))
+	}
+}
+
+// parseWholeFileFlags returns whether the files are to be sent whole, or nil to let rsync decide.
+func parseWholeFileFlags(flags *flag.FlagSet) *bool {
+	var wholeFile *bool
+
+	if enabled, _ := flags.GetBool(FlagWholeFile); enabled {
+		wholeFile = &enabled
+	}
+
+	if disabled, _ := flags.GetBool(FlagNoWholeFile); disabled {
+		enabled := false
+		wholeFile = &enabled
+	}
+
+	return wholeFile
+}
+
+// parseExtraVolumes parses the values of the --extra-volume flag in the form of <configmap|secret>:<name>:<mount path>.
+func parseExtraVolumes(values []string) ([]migration.ExtraVolume, error) {
+	volumes := make([]migration.ExtraVolume, 0, len(values))
+
+	for _, value := range values {
+		parts := strings.SplitN(value, ":", 3) //nolint:mnd
+
+		if len(parts) != 3 || parts[1] == "" || !strings.HasPrefix(parts[2], "/") { //nolint:mnd
+			return nil, fmt.Errorf("invalid --%s %q: must be in the form of <configmap|secret>:<name>:<mount path> "+
+				"with an absolute mount path", FlagExtraVolume, value)
+		}
+
+		kind := strings.ToLower(parts[0])
+		if kind != migration.ExtraVolumeKindConfigMap && kind != migration.ExtraVolumeKindSecret {
+			return nil, fmt.Errorf("invalid --%s %q: kind must be %s or %s", FlagExtraVolume, value,
+				migration.ExtraVolumeKindConfigMap, migration.ExtraVolumeKindSecret)
+		}
+
+		volumes = append(volumes, migration.ExtraVolume{Kind: kind, Name: parts[1], MountPath: parts[2]})
+	}
+
+	return volumes, nil
+}
+
+// readUploadedFile reads the local file given by the flag with the given name, to be uploaded into the rsync pod.
+func readUploadedFile(flagName, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read --%s file: %w", flagName, err)
+	}
+
+	if len(data) > maxUploadedFileSize {
+		return "", fmt.Errorf("--%s file is too large: %d bytes, at most %d bytes are supported",
+			flagName, len(data), maxUploadedFileSize)
+	}
+
+	if strings.TrimSpace(string(data)) == "" {
+		return "", fmt.Errorf("--%s file is empty", flagName)
+	}
+
+	return string(data), nil
+}
+
+// loadKubeconfigSecrets reads the kubeconfigs given with the secret flags from the cluster pv-migrate runs in,
+// and sets them as the kubeconfigs of the PVCs. They are kept in memory, not to leave the credentials on the disk.
+func loadKubeconfigSecrets(ctx context.Context, flags *flag.FlagSet, request *migration.Request,
+	logger *slog.Logger,
+) error {
+	var client *k8s.ClusterClient
+
+	kubeconfigs := map[string][]byte{}
+
+	for _, side := range []struct {
+		flagName string
+		info     *migration.PVCInfo
+	}{
+		{FlagSourceKubeconfigSecret, request.Source},
+		{FlagDestKubeconfigSecret, request.Dest},
+	} {
+		ref, _ := flags.GetString(side.flagName)
+		if ref == "" {
+			continue
+		}
+
+		if kubeconfig, ok := kubeconfigs[ref]; ok {
+			side.info.Kubeconfig = kubeconfig
+
+			continue
+		}
+
+		if client == nil {
+			var err error
+			if client, err = k8s.GetClusterClient("", "", k8s.TLSOptions{}, logger); err != nil {
+				return fmt.Errorf("failed to get the client of the cluster to read --%s from: %w",
+					side.flagName, err)
+			}
+		}
+
+		kubeconfig, err := readKubeconfigSecret(ctx, client, side.flagName, ref)
+		if err != nil {
+			return err
+		}
+
+		kubeconfigs[ref] = kubeconfig
+		side.info.Kubeconfig = kubeconfig
+	}
+
+	return nil
+}
+
+// readKubeconfigSecret reads the kubeconfig from the key of the secret referenced in the form
+// of [NAMESPACE/]NAME/KEY.
+func readKubeconfigSecret(ctx context.Context, client *k8s.ClusterClient, flagName, ref string) ([]byte, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) == 2 { //nolint:mnd
+		parts = append([]string{client.NsInContext}, parts...)
+	}
+
+	if len(parts) != 3 || slices.Contains(parts, "") { //nolint:mnd
+		return nil, fmt.Errorf("--%s must be in the form of [NAMESPACE/]NAME/KEY", flagName)
+	}
+
+	kubeconfig, err := k8s.GetSecretKey(ctx, client.KubeClient, parts[0], parts[1], parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --%s: %w", flagName, err)
+	}
+
+	if _, err = clientcmd.Load(kubeconfig); err != nil {
+		return nil, fmt.Errorf("--%s does not hold a valid kubeconfig: %w", flagName, err)
+	}
+
+	return kubeconfig, nil
+}
+
+// getPVCClusterClient returns the client of the cluster of the PVC, from the content of its kubeconfig
+// if it was read from a secret, or from its kubeconfig file otherwise.
+func getPVCClusterClient(info *migration.PVCInfo, logger *slog.Logger) (*k8s.ClusterClient, error) {
+	tlsOptions := k8s.TLSOptions{
+		InsecureSkipTLSVerify: info.InsecureSkipTLSVerify,
+		CAFile:                info.CAFile,
+	}
+
+	if info.Kubeconfig != nil {
+		return k8s.GetClusterClientFromKubeconfig(info.Kubeconfig, info.Context, tlsOptions, logger) //nolint:wrapcheck
+	}
+
+	return k8s.GetClusterClient(info.KubeconfigPath, info.Context, tlsOptions, logger) //nolint:wrapcheck
+}
+
+// parseSecurityProfileFlag parses the seccomp or AppArmor profile flag with the given name. It returns nil if not set.
+func parseSecurityProfileFlag(flags *flag.FlagSet, name string) (*k8s.SecurityProfile, error) {
+	value, _ := flags.GetString(name)
+	if value == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	profile, err := k8s.ParseSecurityProfile(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", name, err)
+	}
+
+	return &profile, nil
+}
+
+// expandEnvInFlags expands the environment variable references in the values of the string flags set by the user.
+func expandEnvInFlags(flags *flag.FlagSet) error {
+	var errs []error
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Value.Type() {
+		case "string":
+			expanded, err := util.ExpandEnv(f.Value.String(), os.LookupEnv)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("--%s: %w", f.Name, err))
+
+				return
+			}
+
+			if err = f.Value.Set(expanded); err != nil {
+				errs = append(errs, fmt.Errorf("--%s: %w", f.Name, err))
+			}
+		case "stringSlice", "stringArray":
+			sliceValue, ok := f.Value.(flag.SliceValue)
+			if !ok {
+				return
+			}
+
+			values := sliceValue.GetSlice()
+			for i, value := range values {
+				expanded, err := util.ExpandEnv(value, os.LookupEnv)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("--%s: %w", f.Name, err))
+
+					return
+				}
+
+				values[i] = expanded
+			}
+
+			if err := sliceValue.Replace(values); err != nil {
+				errs = append(errs, fmt.Errorf("--%s: %w", f.Name, err))
+			}
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
 //nolint:nonamedreturns
 func buildLogger(flags *flag.FlagSet) (logger *slog.Logger, canDisplayProgressBar bool, err error) {
 	loglvl, _ := flags.GetString(FlagLogLevel)
@@ -347,29 +1517,94 @@ func buildLogger(flags *flag.FlagSet) (logger *slog.Logger, canDisplayProgressBa
 func buildSrcPVCInfo(flags *flag.FlagSet, name string) *migration.PVCInfo {
 	srcKubeconfigPath, _ := flags.GetString(FlagSourceKubeconfig)
 	srcContext, _ := flags.GetString(FlagSourceContext)
-	srcNS, _ := flags.GetString(FlagSourceNamespace)
+	srcNS := getNamespace(flags, FlagSourceNamespace)
 	srcPath, _ := flags.GetString(FlagSourcePath)
+	tlsOptions := buildTLSOptions(flags, FlagSourceInsecureSkipTLSVerify, FlagSourceCAFile)
 
 	return &migration.PVCInfo{
-		KubeconfigPath: srcKubeconfigPath,
-		Context:        srcContext,
-		Namespace:      srcNS,
-		Name:           name,
-		Path:           srcPath,
+		KubeconfigPath:        srcKubeconfigPath,
+		Context:               srcContext,
+		Namespace:             srcNS,
+		Name:                  name,
+		Path:                  srcPath,
+		InsecureSkipTLSVerify: tlsOptions.InsecureSkipTLSVerify,
+		CAFile:                tlsOptions.CAFile,
 	}
 }
 
 func buildDestPVCInfo(flags *flag.FlagSet, name string) *migration.PVCInfo {
 	destKubeconfigPath, _ := flags.GetString(FlagDestKubeconfig)
 	destContext, _ := flags.GetString(FlagDestContext)
-	destNS, _ := flags.GetString(FlagDestNamespace)
+	destNS := getNamespace(flags, FlagDestNamespace)
 	destPath, _ := flags.GetString(FlagDestPath)
+	tlsOptions := buildTLSOptions(flags, FlagDestInsecureSkipTLSVerify, FlagDestCAFile)
 
 	return &migration.PVCInfo{
-		KubeconfigPath: destKubeconfigPath,
-		Context:        destContext,
-		Namespace:      destNS,
-		Name:           name,
-		Path:           destPath,
+		KubeconfigPath:        destKubeconfigPath,
+		Context:               destContext,
+		Namespace:             destNS,
+		Name:                  name,
+		Path:                  destPath,
+		InsecureSkipTLSVerify: tlsOptions.InsecureSkipTLSVerify,
+		CAFile:                tlsOptions.CAFile,
+	}
+}
+
+// getImage returns the image given by the flag, falling back to the one in the environment variable, if set.
+func getImage(flags *flag.FlagSet, imageFlag, envVar string) (string, error) {
+	image, _ := flags.GetString(imageFlag)
+	source := "--" + imageFlag
+
+	if envImage, ok := os.LookupEnv(envVar); ok && !flags.Changed(imageFlag) {
+		image = envImage
+		source = envVar
+	}
+
+	if image == "" {
+		return "", nil
+	}
+
+	if _, _, err := util.ParseImage(image); err != nil {
+		return "", fmt.Errorf("invalid %s: %w", source, err)
+	}
+
+	return image, nil
+}
+
+// parseCapabilities normalizes the Linux capabilities given to the flag to the form used by Kubernetes,
+// i.e., in upper case and without the "CAP_" prefix.
+func parseCapabilities(flagName string, capabilities []string) ([]string, error) {
+	parsed := make([]string, 0, len(capabilities))
+
+	for _, capability := range capabilities {
+		name := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(capability)), "CAP_")
+		if !capabilityRegex.MatchString(name) || name == "ALL" && flagName != FlagDropCapability {
+			return nil, fmt.Errorf("invalid --%s: %q is not a capability name", flagName, capability)
+		}
+
+		parsed = append(parsed, name)
+	}
+
+	return parsed, nil
+}
+
+// getNamespace returns the namespace of a side given by its own flag, falling back to the shared --namespace flag.
+func getNamespace(flags *flag.FlagSet, namespaceFlag string) string {
+	if !flags.Changed(namespaceFlag) {
+		namespaceFlag = FlagNamespace
+	}
+
+	namespace, _ := flags.GetString(namespaceFlag)
+
+	return namespace
+}
+
+func buildTLSOptions(flags *flag.FlagSet, insecureSkipTLSVerifyFlag, caFileFlag string) k8s.TLSOptions {
+	insecureSkipTLSVerify, _ := flags.GetBool(insecureSkipTLSVerifyFlag)
+	caFile, _ := flags.GetString(caFileFlag)
+
+	return k8s.TLSOptions{
+		InsecureSkipTLSVerify: insecureSkipTLSVerify,
+		CAFile:                caFile,
 	}
 }
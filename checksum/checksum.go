@@ -0,0 +1,139 @@
+package checksum
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// fileMode allows the manifest to be read by the other containers, e.g., a controller sharing the volume.
+const fileMode = 0o644
+
+// lineRegex matches the entries in the format of sha256sum, e.g., "<64 hex digits>  ./dir/file.txt",
+// whose paths can contain newlines as they are separated by NUL.
+var lineRegex = regexp.MustCompile(`(?s)^(?P<sum>[0-9a-f]{64}) [ *](?P<path>.+)$`)
+
+// pathEscaper escapes the newlines and the backslashes in the paths written to the manifest as sha256sum does.
+var pathEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// Manifest maps the paths of the files, relative to the migrated directory, to their SHA-256 checksums in hex.
+type Manifest map[string]string
+
+// Diff is the difference of the destination manifest from the source manifest, each list sorted by the path.
+type Diff struct {
+	// Missing are the files of the source which are not on the destination.
+	Missing []string
+	// Mismatched are the files whose checksums differ on the destination.
+	Mismatched []string
+	// Extraneous are the files on the destination which are not on the source, e.g., as the extraneous files
+	// are not deleted by default. They do not make the manifests mismatch.
+	Extraneous []string
+}
+
+// Matches returns whether all the files of the source are on the destination with the same checksums.
+func (d Diff) Matches() bool {
+	return len(d.Missing) == 0 && len(d.Mismatched) == 0
+}
+
+// Parse parses the checksums of the files of a directory, with their paths relative to it, in the format
+// of sha256sum, but with the entries terminated by NUL instead of a newline, like the output of "sha256sum -z".
+func Parse(reader io.Reader) (Manifest, error) {
+	manifest := Manifest{}
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(scanNUL)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		matches := lineRegex.FindStringSubmatch(line)
+		if matches == nil {
+			return nil, fmt.Errorf("unexpected entry in the output of sha256sum: %q", line)
+		}
+
+		manifest[strings.TrimPrefix(matches[2], "./")] = matches[1]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the output of sha256sum: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// scanNUL is a bufio.SplitFunc returning the entries terminated by NUL.
+func scanNUL(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// Compare compares the destination manifest with the source manifest.
+func Compare(source, dest Manifest) Diff {
+	var diff Diff
+
+	for path, sum := range source {
+		destSum, ok := dest[path]
+
+		switch {
+		case !ok:
+			diff.Missing = append(diff.Missing, path)
+		case destSum != sum:
+			diff.Mismatched = append(diff.Mismatched, path)
+		}
+	}
+
+	for path := range dest {
+		if _, ok := source[path]; !ok {
+			diff.Extraneous = append(diff.Extraneous, path)
+		}
+	}
+
+	slices.Sort(diff.Missing)
+	slices.Sort(diff.Mismatched)
+	slices.Sort(diff.Extraneous)
+
+	return diff
+}
+
+// Write writes the manifest to the file at the given path in the format of sha256sum, sorted by the path,
+// so that it can be verified later with "sha256sum -c" in the migrated directory. As with sha256sum,
+// the lines of the paths with newlines or backslashes are prefixed with a backslash, and those are escaped.
+func (m Manifest) Write(path string) error {
+	paths := make([]string, 0, len(m))
+	for p := range m {
+		paths = append(paths, p)
+	}
+
+	slices.Sort(paths)
+
+	var builder strings.Builder
+	for _, p := range paths {
+		if escaped := pathEscaper.Replace(p); escaped != p {
+			fmt.Fprintf(&builder, "\\%s  %s\n", m[p], escaped)
+
+			continue
+		}
+
+		fmt.Fprintf(&builder, "%s  %s\n", m[p], p)
+	}
+
+	if err := os.WriteFile(path, []byte(builder.String()), fileMode); err != nil {
+		return fmt.Errorf("failed to write checksum manifest: %w", err)
+	}
+
+	return nil
+}
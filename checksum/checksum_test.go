@@ -0,0 +1,68 @@
+package checksum_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/checksum"
+)
+
+const (
+	sumA = "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"
+	sumB = "3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d"
+	sumC = "2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	manifest, err := checksum.Parse(strings.NewReader(
+		sumA + "  ./a.txt\x00" + sumB + "  ./dir/file with spaces\x00" + sumC + " *./binary\x00" +
+			sumA + "  ./new\nline\\back\x00"))
+	require.NoError(t, err)
+
+	assert.Equal(t, checksum.Manifest{
+		"a.txt":                sumA,
+		"dir/file with spaces": sumB,
+		"binary":               sumC,
+		"new\nline\\back":      sumA,
+	}, manifest)
+
+	_, err = checksum.Parse(strings.NewReader("find: ./secret: Permission denied\n"))
+	require.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	source := checksum.Manifest{"a": sumA, "b": sumB, "c": sumC}
+	dest := checksum.Manifest{"a": sumA, "b": sumC, "d": sumA}
+
+	diff := checksum.Compare(source, dest)
+
+	assert.False(t, diff.Matches())
+	assert.Equal(t, []string{"c"}, diff.Missing)
+	assert.Equal(t, []string{"b"}, diff.Mismatched)
+	assert.Equal(t, []string{"d"}, diff.Extraneous)
+
+	diff = checksum.Compare(source, checksum.Manifest{"a": sumA, "b": sumB, "c": sumC, "d": sumA})
+	assert.True(t, diff.Matches(), "extraneous files must not make the manifests mismatch")
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "manifest.sha256")
+
+	require.NoError(t, checksum.Manifest{"b/c": sumB, "a": sumA, "d\ne\\f": sumC}.Write(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, sumA+"  a\n"+sumB+"  b/c\n\\"+sumC+"  d\\ne\\\\f\n", string(data))
+}
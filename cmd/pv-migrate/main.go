@@ -4,6 +4,8 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	// load all auth plugins - needed for gcp, azure etc.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -28,6 +30,8 @@ func run() int {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	handleSignals(ctx, cancel)
+
 	rootCmd := app.BuildMigrateCmd(ctx, version, commit, date, false)
 
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
@@ -38,3 +42,23 @@ func run() int {
 
 	return 0
 }
+
+// handleSignals cancels the context on the first termination signal, for the running migration to stop
+// and clean up the resources it created on its way out. A second signal terminates the process immediately.
+func handleSignals(ctx context.Context, cancel context.CancelFunc) {
+	signalCh := make(chan os.Signal, 1)
+
+	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signalCh:
+			slog.Default().Warn("🔶 Received termination signal, cleaning up, send it again to exit immediately")
+
+			signal.Stop(signalCh)
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(signalCh)
+		}
+	}()
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
 
 	"github.com/utkuozdemir/pv-migrate/helm"
 )
@@ -20,3 +22,125 @@ func TestLoadChart(t *testing.T) {
 	assert.NotEmpty(t, chart.Values, "chart values should not be empty")
 	assert.NotEmpty(t, chart.Templates, "chart templates should not be empty")
 }
+
+func TestRenderKeepResources(t *testing.T) {
+	t.Parallel()
+
+	chart, err := helm.LoadChart()
+	require.NoError(t, err)
+
+	vals := map[string]any{
+		"keepResources": []any{"secret"},
+		"sshd": map[string]any{
+			"enabled":        true,
+			"namespace":      "ns",
+			"publicKeyMount": true,
+			"publicKey":      "public-key",
+		},
+	}
+
+	renderValues, err := chartutil.ToRenderValues(chart, vals,
+		chartutil.ReleaseOptions{Name: "pv-migrate-abcde", Namespace: "ns"}, nil)
+	require.NoError(t, err)
+
+	rendered, err := engine.Render(chart, renderValues)
+	require.NoError(t, err)
+
+	assert.Contains(t, rendered["pv-migrate/templates/sshd/secret.yaml"], "helm.sh/resource-policy: keep")
+	assert.NotContains(t, rendered["pv-migrate/templates/sshd/service.yaml"], "helm.sh/resource-policy")
+	assert.NotContains(t, rendered["pv-migrate/templates/sshd/deployment.yaml"], "helm.sh/resource-policy")
+}
+
+func TestRenderRsyncd(t *testing.T) {
+	t.Parallel()
+
+	chart, err := helm.LoadChart()
+	require.NoError(t, err)
+
+	vals := map[string]any{
+		"sshd": map[string]any{
+			"enabled":        true,
+			"namespace":      "ns",
+			"publicKeyMount": false,
+			"rsyncd":         map[string]any{"enabled": true, "password": "secret"},
+		},
+	}
+
+	renderValues, err := chartutil.ToRenderValues(chart, vals,
+		chartutil.ReleaseOptions{Name: "pv-migrate-abcde", Namespace: "ns"}, nil)
+	require.NoError(t, err)
+
+	rendered, err := engine.Render(chart, renderValues)
+	require.NoError(t, err)
+
+	deployment := rendered["pv-migrate/templates/sshd/deployment.yaml"]
+	assert.Contains(t, deployment, "rsync --daemon --no-detach")
+	assert.NotContains(t, deployment, "/usr/sbin/sshd")
+	assert.Contains(t, deployment, "containerPort: 873")
+	assert.Contains(t, rendered["pv-migrate/templates/sshd/secret.yaml"], "rsyncdSecrets:")
+	assert.Contains(t, rendered["pv-migrate/templates/sshd/service.yaml"], "targetPort: rsyncd")
+}
+
+func TestRenderHostKey(t *testing.T) {
+	t.Parallel()
+
+	chart, err := helm.LoadChart()
+	require.NoError(t, err)
+
+	vals := map[string]any{
+		"sshd": map[string]any{
+			"enabled":   true,
+			"namespace": "ns",
+			"publicKey": "public-key",
+			"hostKey":   "host-key",
+		},
+		"rsync": map[string]any{
+			"enabled":    true,
+			"namespace":  "ns",
+			"command":    "rsync",
+			"knownHosts": "pv-migrate-sshd ssh-ed25519 AAAA\n",
+		},
+	}
+
+	renderValues, err := chartutil.ToRenderValues(chart, vals,
+		chartutil.ReleaseOptions{Name: "pv-migrate-abcde", Namespace: "ns"}, nil)
+	require.NoError(t, err)
+
+	rendered, err := engine.Render(chart, renderValues)
+	require.NoError(t, err)
+
+	deployment := rendered["pv-migrate/templates/sshd/deployment.yaml"]
+	assert.Contains(t, deployment, "/usr/sbin/sshd -D -e -f /etc/ssh/sshd_config -h /tmp/ssh_host_key")
+	assert.Contains(t, deployment, "subPath: hostKey")
+	assert.Contains(t, rendered["pv-migrate/templates/sshd/secret.yaml"], "hostKey:")
+
+	assert.Contains(t, rendered["pv-migrate/templates/rsync/configmap.yaml"], "knownHosts:")
+	assert.Contains(t, rendered["pv-migrate/templates/rsync/job.yaml"], "mountPath: /etc/pv-migrate/known-hosts")
+}
+
+func TestRenderFailIfNotEmpty(t *testing.T) {
+	t.Parallel()
+
+	chart, err := helm.LoadChart()
+	require.NoError(t, err)
+
+	vals := map[string]any{
+		"rsync": map[string]any{
+			"enabled":        true,
+			"namespace":      "ns",
+			"command":        "rsync",
+			"failIfNotEmpty": "/dest",
+		},
+	}
+
+	renderValues, err := chartutil.ToRenderValues(chart, vals,
+		chartutil.ReleaseOptions{Name: "pv-migrate-abcde", Namespace: "ns"}, nil)
+	require.NoError(t, err)
+
+	rendered, err := engine.Render(chart, renderValues)
+	require.NoError(t, err)
+
+	// the entries are printed with the prefix the progress logger parses them with, to report them in the error
+	assert.Contains(t, rendered["pv-migrate/templates/rsync/job.yaml"],
+		`ls -A "/dest" | grep -vx 'lost+found' | head -n 20 | sed 's/^/destination is not empty, found: /'`)
+}
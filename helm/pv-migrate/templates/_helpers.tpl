@@ -48,3 +48,34 @@ app.kubernetes.io/instance: {{ .Release.Name }}
 {{- default "default" .Values.rsync.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Annotations of a resource of the given kind, with the Helm resource policy to keep it on uninstall
+if the kind is listed in keepResources.
+*/}}
+{{- define "pv-migrate.annotations" -}}
+{{- $annotations := deepCopy (.annotations | default dict) }}
+{{- if has .kind .context.Values.keepResources }}
+{{- $_ := set $annotations "helm.sh/resource-policy" "keep" }}
+{{- end }}
+{{- with $annotations }}
+annotations:
+  {{- toYaml . | nindent 2 }}
+{{- end }}
+{{- end }}
+
+{{/*
+Configuration of the rsync daemon run instead of SSHD, exposing its path as a single module.
+*/}}
+{{- define "pv-migrate.rsyncdConf" -}}
+port = {{ .port }}
+use chroot = no
+uid = root
+gid = root
+
+[{{ .module }}]
+    path = {{ .path }}
+    read only = {{ .readOnly }}
+    auth users = {{ .user }}
+    secrets file = {{ .mountPath }}/rsyncd.secrets
+{{- end }}
@@ -238,7 +238,7 @@ func TestDeleteExtraneousFiles(t *testing.T) {
 	_, err := execInPod(ctx, mainClusterCli, ns1, "dest", generateExtraDataShellCommand)
 	require.NoError(t, err)
 
-	cmd := fmt.Sprintf("%s --compress=false -d -i -n %s -N %s source dest", migrateLegacyCmdline, ns1, ns1)
+	cmd := fmt.Sprintf("%s --compress=false -d -y -i -n %s -N %s source dest", migrateLegacyCmdline, ns1, ns1)
 	require.NoError(t, runCliApp(ctx, cmd))
 
 	stdout, err := execInPod(ctx, mainClusterCli, ns1, "dest", printDataUIDGIDContentShellCommand)
@@ -499,14 +499,14 @@ func setup(ctx context.Context, logger *slog.Logger) error {
 
 	extraClusterKubeconfig = env.GetString("PVMIG_TEST_EXTRA_KUBECONFIG", homeDir+"/.kube/config")
 
-	mainCli, err := k8s.GetClusterClient("", "", logger)
+	mainCli, err := k8s.GetClusterClient("", "", k8s.TLSOptions{}, logger)
 	if err != nil {
 		return fmt.Errorf("failed to get main cluster client: %w", err)
 	}
 
 	mainClusterCli = mainCli
 
-	extraCli, err := k8s.GetClusterClient(extraClusterKubeconfig, "", logger)
+	extraCli, err := k8s.GetClusterClient(extraClusterKubeconfig, "", k8s.TLSOptions{}, logger)
 	if err != nil {
 		return fmt.Errorf("failed to get extra cluster client: %w", err)
 	}
@@ -3,53 +3,120 @@ package k8s
 import (
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 type ClusterClient struct {
 	RestConfig       *rest.Config
 	KubeClient       kubernetes.Interface
+	DynamicClient    dynamic.Interface
 	RESTClientGetter genericclioptions.RESTClientGetter
 	NsInContext      string
 }
 
-func GetClusterClient(kubeconfigPath string, context string, logger *slog.Logger) (*ClusterClient, error) {
-	config, rcGetter, namespace, err := buildK8sConfig(kubeconfigPath, context, logger)
+// TLSOptions are the overrides for the TLS settings of the cluster in the kubeconfig.
+type TLSOptions struct {
+	// InsecureSkipTLSVerify disables the verification of the API server certificate.
+	InsecureSkipTLSVerify bool
+	// CAFile is the path to a CA bundle to verify the API server certificate with.
+	CAFile string
+}
+
+func GetClusterClient(kubeconfigPath string, context string, tlsOptions TLSOptions,
+	logger *slog.Logger,
+) (*ClusterClient, error) {
+	config, rcGetter, namespace, err := buildK8sConfig(kubeconfigPath, context, tlsOptions, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClusterClient(config, rcGetter, namespace)
+}
+
+// GetClusterClientFromKubeconfig is like GetClusterClient, but with the content of the kubeconfig,
+// e.g., read from a secret, for it not to be written to a file.
+func GetClusterClientFromKubeconfig(kubeconfig []byte, context string, tlsOptions TLSOptions,
+	logger *slog.Logger,
+) (*ClusterClient, error) {
+	rawConfig, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	clientConfig := clientcmd.NewNonInteractiveClientConfig(*rawConfig, context,
+		buildConfigOverrides(context, tlsOptions), nil)
+
+	config, rcGetter, namespace, err := buildClientConfig(clientConfig, context, logger)
 	if err != nil {
 		return nil, err
 	}
 
+	return newClusterClient(config, rcGetter, namespace)
+}
+
+func newClusterClient(config *rest.Config, rcGetter genericclioptions.RESTClientGetter,
+	namespace string,
+) (*ClusterClient, error) {
 	kubeClient, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes dynamic client: %w", err)
+	}
+
 	return &ClusterClient{
 		RestConfig:       config,
 		KubeClient:       kubeClient,
+		DynamicClient:    dynamicClient,
 		RESTClientGetter: rcGetter,
 		NsInContext:      namespace,
 	}, nil
 }
 
 //nolint:ireturn,nolintlint
-func buildK8sConfig(kubeconfigPath string, context string, logger *slog.Logger) (*rest.Config,
-	genericclioptions.RESTClientGetter, string, error,
-) {
+func buildK8sConfig(kubeconfigPath string, context string, tlsOptions TLSOptions,
+	logger *slog.Logger,
+) (*rest.Config, genericclioptions.RESTClientGetter, string, error) {
 	clientConfigLoadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 	if kubeconfigPath != "" {
 		clientConfigLoadingRules.ExplicitPath = kubeconfigPath
 	}
 
 	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		clientConfigLoadingRules,
-		&clientcmd.ConfigOverrides{
-			CurrentContext: context,
-		})
+		clientConfigLoadingRules, buildConfigOverrides(context, tlsOptions))
+
+	return buildClientConfig(config, context, logger)
+}
+
+func buildConfigOverrides(context string, tlsOptions TLSOptions) *clientcmd.ConfigOverrides {
+	return &clientcmd.ConfigOverrides{
+		CurrentContext: context,
+		ClusterInfo: clientcmdapi.Cluster{
+			InsecureSkipTLSVerify: tlsOptions.InsecureSkipTLSVerify,
+			CertificateAuthority:  tlsOptions.CAFile,
+		},
+	}
+}
+
+//nolint:ireturn,nolintlint
+func buildClientConfig(config clientcmd.ClientConfig, context string,
+	logger *slog.Logger,
+) (*rest.Config, genericclioptions.RESTClientGetter, string, error) {
+	if err := checkContextExists(config, context); err != nil {
+		return nil, nil, "", err
+	}
 
 	namespace, _, err := config.Namespace()
 	if err != nil {
@@ -65,3 +132,24 @@ func buildK8sConfig(kubeconfigPath string, context string, logger *slog.Logger)
 
 	return clientConfig, rcGetter, namespace, nil
 }
+
+// checkContextExists checks that the given context exists in the kubeconfig, to fail early with
+// the list of the available contexts instead of a confusing error later on.
+func checkContextExists(config clientcmd.ClientConfig, context string) error {
+	if context == "" {
+		return nil
+	}
+
+	rawConfig, err := config.RawConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	if _, ok := rawConfig.Contexts[context]; ok {
+		return nil
+	}
+
+	available := slices.Sorted(maps.Keys(rawConfig.Contexts))
+
+	return fmt.Errorf("context '%s' not found, available: %s", context, strings.Join(available, ", "))
+}
@@ -3,11 +3,15 @@ package k8s
 import (
 	_ "embed"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/neilotoole/slogt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 //go:embed testdata/_kubeconfig_test.yaml
@@ -23,7 +27,7 @@ func TestGetClusterClient(t *testing.T) {
 
 	logger := slogt.New(t)
 
-	clusterClient, err := GetClusterClient(kubeconfig, "context-1", logger)
+	clusterClient, err := GetClusterClient(kubeconfig, "context-1", TLSOptions{}, logger)
 
 	require.NoError(t, err)
 
@@ -46,6 +50,27 @@ func TestGetClusterClient(t *testing.T) {
 	assert.NotNil(t, restMapper)
 }
 
+func TestGetClusterClientFromKubeconfig(t *testing.T) {
+	t.Parallel()
+
+	logger := slogt.New(t)
+
+	clusterClient, err := GetClusterClientFromKubeconfig([]byte(kubeconfigContent), "context-2", TLSOptions{}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, "namespace2", clusterClient.NsInContext)
+
+	ns, _, err := clusterClient.RESTClientGetter.ToRawKubeConfigLoader().Namespace()
+	require.NoError(t, err)
+	assert.Equal(t, "namespace2", ns)
+
+	clusterClient, err = GetClusterClientFromKubeconfig([]byte(kubeconfigContent), "", TLSOptions{}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, "namespace1", clusterClient.NsInContext)
+
+	_, err = GetClusterClientFromKubeconfig([]byte(kubeconfigContent), "context-nonexistent", TLSOptions{}, logger)
+	require.EqualError(t, err, "context 'context-nonexistent' not found, available: context-1, context-2")
+}
+
 func TestBuildK8sConfig(t *testing.T) {
 	t.Parallel()
 
@@ -56,18 +81,39 @@ func TestBuildK8sConfig(t *testing.T) {
 
 	logger := slogt.New(t)
 
-	config, _, namespace, err := buildK8sConfig(conf, "", logger)
+	config, _, namespace, err := buildK8sConfig(conf, "", TLSOptions{}, logger)
 	assert.NotNil(t, config)
 	assert.Equal(t, "namespace1", namespace)
 	require.NoError(t, err)
-	config, _, namespace, err = buildK8sConfig(conf, "context-2", logger)
+	config, _, namespace, err = buildK8sConfig(conf, "context-2", TLSOptions{}, logger)
 	require.NoError(t, err)
 	assert.Equal(t, "namespace2", namespace)
 	assert.NotNil(t, config)
-	config, _, namespace, err = buildK8sConfig(conf, "context-nonexistent", logger)
+	config, _, namespace, err = buildK8sConfig(conf, "context-nonexistent", TLSOptions{}, logger)
 	assert.Nil(t, config)
 	assert.Equal(t, "", namespace)
-	require.Error(t, err)
+	require.EqualError(t, err, "context 'context-nonexistent' not found, available: context-1, context-2")
+}
+
+func TestBuildK8sConfigInsecureSkipTLSVerify(t *testing.T) {
+	t.Parallel()
+
+	conf := prepareKubeconfig()
+	defer func() {
+		_ = os.Remove(conf)
+	}()
+
+	logger := slogt.New(t)
+
+	config, _, _, err := buildK8sConfig(conf, "", TLSOptions{}, logger)
+	require.NoError(t, err)
+	assert.False(t, config.Insecure)
+	assert.NotEmpty(t, config.CAData)
+
+	config, _, _, err = buildK8sConfig(conf, "", TLSOptions{InsecureSkipTLSVerify: true}, logger)
+	require.NoError(t, err)
+	assert.True(t, config.Insecure)
+	assert.Empty(t, config.CAData)
 }
 
 func prepareKubeconfig() string {
@@ -77,3 +123,67 @@ func prepareKubeconfig() string {
 
 	return testConfig.Name()
 }
+
+func TestGetClusterClientExecAuth(t *testing.T) {
+	t.Parallel()
+
+	logger := slogt.New(t)
+	dir := t.TempDir()
+
+	sourceKubeconfig := writeExecKubeconfig(t, dir, "source", "https://source.example.internal:6443",
+		"aws", "eks", "get-token", "--cluster-name", "source")
+	destKubeconfig := writeExecKubeconfig(t, dir, "dest", "https://dest.example.internal:6443",
+		"gke-gcloud-auth-plugin")
+
+	sourceClient, err := GetClusterClient(sourceKubeconfig, "", TLSOptions{}, logger)
+	require.NoError(t, err)
+
+	destClient, err := GetClusterClient(destKubeconfig, "", TLSOptions{}, logger)
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		client  *ClusterClient
+		host    string
+		command string
+		args    []string
+	}{
+		{
+			sourceClient, "https://source.example.internal:6443", "aws",
+			[]string{"eks", "get-token", "--cluster-name", "source"},
+		},
+		{destClient, "https://dest.example.internal:6443", "gke-gcloud-auth-plugin", nil},
+	} {
+		restConfig, err := testCase.client.RESTClientGetter.ToRESTConfig()
+		require.NoError(t, err)
+
+		for _, config := range []*rest.Config{testCase.client.RestConfig, restConfig} {
+			assert.Equal(t, testCase.host, config.Host)
+			require.NotNil(t, config.ExecProvider)
+			assert.Equal(t, testCase.command, config.ExecProvider.Command)
+			assert.Equal(t, testCase.args, config.ExecProvider.Args)
+		}
+
+		restConfig.Burst = 1000
+		assert.NotEqual(t, 1000, testCase.client.RestConfig.Burst)
+	}
+}
+
+func writeExecKubeconfig(t *testing.T, dir, name, server, command string, args ...string) string {
+	t.Helper()
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = &clientcmdapi.Cluster{Server: server}
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{
+		APIVersion:      "client.authentication.k8s.io/v1beta1",
+		Command:         command,
+		Args:            args,
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}}
+	config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	config.CurrentContext = name
+
+	path := filepath.Join(dir, name+".yaml")
+	require.NoError(t, clientcmd.WriteToFile(*config, path))
+
+	return path
+}
@@ -9,7 +9,7 @@ import (
 )
 
 func GetContexts(kubeconfigPath string, logger *slog.Logger) ([]string, error) {
-	client, err := GetClusterClient(kubeconfigPath, "", logger)
+	client, err := GetClusterClient(kubeconfigPath, "", TLSOptions{}, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -33,8 +33,10 @@ func GetContexts(kubeconfigPath string, logger *slog.Logger) ([]string, error) {
 	return contextNames, nil
 }
 
-func GetNamespaces(ctx context.Context, kubeconfigPath, kubectx string, logger *slog.Logger) ([]string, error) {
-	client, err := GetClusterClient(kubeconfigPath, kubectx, logger)
+func GetNamespaces(ctx context.Context, kubeconfigPath, kubectx string,
+	tlsOptions TLSOptions, logger *slog.Logger,
+) ([]string, error) {
+	client, err := GetClusterClient(kubeconfigPath, kubectx, tlsOptions, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -53,8 +55,10 @@ func GetNamespaces(ctx context.Context, kubeconfigPath, kubectx string, logger *
 	return nsNames, nil
 }
 
-func GetPVCs(ctx context.Context, kubeconfigPath, kubectx, namespace string, logger *slog.Logger) ([]string, error) {
-	client, err := GetClusterClient(kubeconfigPath, kubectx, logger)
+func GetPVCs(ctx context.Context, kubeconfigPath, kubectx, namespace string,
+	tlsOptions TLSOptions, logger *slog.Logger,
+) ([]string, error) {
+	client, err := GetClusterClient(kubeconfigPath, kubectx, tlsOptions, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -1,9 +1,15 @@
 package k8s
 
 import (
+	"encoding/json"
+	"fmt"
 	"log/slog"
 
+	"helm.sh/helm/v3/pkg/kube"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/rest"
@@ -11,6 +17,9 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+// FieldManager is the field manager of the resources created using server-side apply.
+const FieldManager = "pv-migrate"
+
 type HelmRESTClientGetter struct {
 	restConfig   *rest.Config
 	clientConfig clientcmd.ClientConfig
@@ -27,8 +36,10 @@ func NewRESTClientGetter(restConfig *rest.Config,
 	}
 }
 
+// ToRESTConfig returns a copy of the REST config, so that Helm tweaking it, e.g., its burst,
+// does not leak into the clients built from the original, like the ones of the other cluster of the migration.
 func (c *HelmRESTClientGetter) ToRESTConfig() (*rest.Config, error) {
-	return c.restConfig, nil
+	return rest.CopyConfig(c.restConfig), nil
 }
 
 //nolint:ireturn,nolintlint
@@ -65,3 +76,37 @@ func (c *HelmRESTClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
 func (c *HelmRESTClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
 	return c.clientConfig
 }
+
+// ServerSideApplyClient is a Helm kube client which creates the resources of a release using server-side apply
+// with FieldManager as the field manager, instead of creating them on the client side.
+// It is to coexist with the controllers managing the namespace using server-side apply, like Argo CD and Flux.
+type ServerSideApplyClient struct {
+	*kube.Client
+}
+
+func NewServerSideApplyClient(client *kube.Client) *ServerSideApplyClient {
+	return &ServerSideApplyClient{Client: client}
+}
+
+func (c *ServerSideApplyClient) Create(resources kube.ResourceList) (*kube.Result, error) {
+	c.Log("applying %d resource(s) server-side", len(resources))
+
+	for _, info := range resources {
+		data, err := json.Marshal(info.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s %s: %w", info.Mapping.GroupVersionKind.Kind, info.Name, err)
+		}
+
+		obj, err := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(FieldManager).
+			Patch(info.Namespace, info.Name, types.ApplyPatchType, data, &metav1.PatchOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply %s %s: %w", info.Mapping.GroupVersionKind.Kind, info.Name, err)
+		}
+
+		if err = info.Refresh(obj, true); err != nil {
+			return nil, fmt.Errorf("failed to refresh %s %s: %w", info.Mapping.GroupVersionKind.Kind, info.Name, err)
+		}
+	}
+
+	return &kube.Result{Created: resources}, nil
+}
@@ -0,0 +1,70 @@
+package k8s
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/scheme"
+	restfake "k8s.io/client-go/rest/fake"
+)
+
+func TestServerSideApplyClientCreate(t *testing.T) {
+	t.Parallel()
+
+	var requests []*http.Request
+
+	restClient := &restfake.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		Client: restfake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req)
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body: io.NopCloser(strings.NewReader(`{"apiVersion":"v1","kind":"ConfigMap",` +
+					`"metadata":{"name":"cm","namespace":"ns","resourceVersion":"1"}}`)),
+			}, nil
+		}),
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("ns")
+	obj.SetName("cm")
+
+	info := &resource.Info{
+		Client: restClient,
+		Mapping: &meta.RESTMapping{
+			Resource:         schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			Scope:            meta.RESTScopeNamespace,
+		},
+		Namespace: "ns",
+		Name:      "cm",
+		Object:    obj,
+	}
+
+	client := NewServerSideApplyClient(&kube.Client{Log: t.Logf})
+
+	result, err := client.Create(kube.ResourceList{info})
+	require.NoError(t, err)
+
+	assert.Equal(t, kube.ResourceList{info}, result.Created)
+	assert.Equal(t, "1", info.ResourceVersion)
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, http.MethodPatch, requests[0].Method)
+	assert.Equal(t, string(types.ApplyPatchType), requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, FieldManager, requests[0].URL.Query().Get("fieldManager"))
+}
@@ -6,28 +6,117 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"strings"
+	"time"
 
 	"golang.org/x/sync/errgroup"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
 
 	"github.com/utkuozdemir/pv-migrate/rsync/progress"
 )
 
+const (
+	// jobCompletionIndexLabel is the label set by Kubernetes on the pods of Indexed Jobs.
+	jobCompletionIndexLabel = "batch.kubernetes.io/job-completion-index"
+
+	// logDrainTimeout is the maximum time to wait for the logs of the terminated pods to be read to their end,
+	// for the last lines of rsync, e.g., its summary and the warnings printed at its exit, to be logged.
+	logDrainTimeout = 10 * time.Second
+)
+
+// JobFailedError is returned when the pod of a job fails.
+type JobFailedError struct {
+	Namespace string
+	Name      string
+	// ExitCode is the exit code of the container of the pod, -1 if it is unknown.
+	ExitCode int
+	// DestNotEmptyEntries are the entries found on the destination if the job failed as it was not empty.
+	DestNotEmptyEntries []string
+}
+
+func (e *JobFailedError) Error() string {
+	msg := fmt.Sprintf("job %s/%s failed", e.Namespace, e.Name)
+	if e.ExitCode >= 0 {
+		msg += fmt.Sprintf(" with exit code %d", e.ExitCode)
+	}
+
+	if len(e.DestNotEmptyEntries) > 0 {
+		msg += ": the destination is not empty, found: " + strings.Join(e.DestNotEmptyEntries, ", ")
+	}
+
+	return msg
+}
+
+// containerExitCode returns the exit code of the first terminated container of the pod, -1 if there is none.
+func containerExitCode(pod *corev1.Pod) int {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			return int(status.State.Terminated.ExitCode)
+		}
+	}
+
+	return -1
+}
+
 // WaitForJobCompletion waits for the Kubernetes job to complete.
 //
-
+// If the pod of the job is disrupted, e.g., evicted, the job is expected to replace it with a new pod,
+// up to maxPodRestarts times, and the new pod is followed in turn.
 func WaitForJobCompletion(ctx context.Context, cli kubernetes.Interface,
-	namespace string, name string, progressBarRequested bool, logger *slog.Logger,
-) (retErr error) {
+	namespace string, name string, progressBarRequested bool, maxPodRestarts int, logger *slog.Logger,
+) error {
 	canDisplayProgressBar := ctx.Value(progress.CanDisplayProgressBarContextKey{}) != nil
 	showProgressBar := progressBarRequested && canDisplayProgressBar
 	labelSelector := "job-name=" + name
 
-	pod, err := WaitForPod(ctx, cli, namespace, labelSelector)
-	if err != nil {
-		return err
+	var previousPods []string
+
+	for restarts := 0; ; restarts++ {
+		pod, err := waitForPod(ctx, cli, namespace, labelSelector, previousPods)
+		if err != nil {
+			return err
+		}
+
+		terminatedPod, destNotEmptyEntries, err := waitForJobPod(ctx, cli, pod, showProgressBar, logger)
+		if err != nil {
+			return err
+		}
+
+		if terminatedPod.Status.Phase == corev1.PodSucceeded {
+			return nil
+		}
+
+		if restarts >= maxPodRestarts || !isPodDisrupted(terminatedPod) {
+			return &JobFailedError{
+				Namespace:           pod.Namespace,
+				Name:                pod.Name,
+				ExitCode:            containerExitCode(terminatedPod),
+				DestNotEmptyEntries: destNotEmptyEntries,
+			}
+		}
+
+		logger.Warn("🔶 The rsync pod was disrupted, resuming the transfer in a new pod",
+			"pod", pod.Namespace+"/"+pod.Name, "reason", terminatedPod.Status.Reason,
+			"restart", restarts+1, "max_restarts", maxPodRestarts)
+
+		previousPods = append(previousPods, pod.Name)
 	}
+}
+
+// waitForJobPod tails the logs of the pod of a job until it terminates, and returns it in its terminated state,
+// along with the entries it found on the destination if it failed as the destination was not empty.
+func waitForJobPod(ctx context.Context, cli kubernetes.Interface, pod *corev1.Pod, showProgressBar bool,
+	logger *slog.Logger,
+) (_ *corev1.Pod, _ []string, retErr error) {
+	namespace := pod.Namespace
 
 	var eg errgroup.Group //nolint:varnamelen
 
@@ -50,18 +139,169 @@ func WaitForJobCompletion(ctx context.Context, cli kubernetes.Interface,
 		return progressLogger.Start(tailCtx, logger)
 	})
 
-	phase, err := waitForPodTermination(ctx, cli, pod.Namespace, pod.Name)
+	terminatedPod, err := waitForPodTermination(ctx, cli, pod.Namespace, pod.Name)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	if *phase != corev1.PodSucceeded {
-		return fmt.Errorf("job %s/%s failed", pod.Namespace, pod.Name)
+	if terminatedPod.Status.Phase == corev1.PodSucceeded {
+		if err = progressLogger.MarkAsComplete(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to mark progress logger as complete: %w", err)
+		}
 	}
 
-	if err = progressLogger.MarkAsComplete(ctx); err != nil {
-		return fmt.Errorf("failed to mark progress logger as complete: %w", err)
+	drainLogs(&eg)
+
+	return terminatedPod, progressLogger.DestNotEmptyEntries(), nil
+}
+
+// drainLogs waits for the goroutines tailing the logs of the terminated pods to reach the end of the logs,
+// up to logDrainTimeout, before they are cancelled.
+func drainLogs(eg *errgroup.Group) {
+	doneCh := make(chan struct{})
+
+	go func() {
+		// the error is returned by the deferred wait of the caller
+		_ = eg.Wait()
+
+		close(doneCh)
+	}()
+
+	select {
+	case <-doneCh:
+	case <-time.After(logDrainTimeout):
+	}
+}
+
+// WaitForParallelJobCompletion waits for the Kubernetes job running in the Indexed completion mode
+// with the given parallelism to complete.
+//
+// The logs of all the pods of the job are tailed, but no progress bar is displayed,
+// as the progress of the individual pods cannot be combined into one.
+func WaitForParallelJobCompletion(ctx context.Context, cli kubernetes.Interface,
+	namespace string, name string, parallelism int, logger *slog.Logger,
+) (retErr error) {
+	var eg errgroup.Group //nolint:varnamelen
+
+	defer func() {
+		retErr = errors.Join(retErr, eg.Wait())
+	}()
+
+	tailCtx, tailCancel := context.WithCancel(ctx)
+	defer tailCancel()
+
+	for index := range parallelism {
+		workerLogger := logger.With("worker", index)
+
+		eg.Go(func() error {
+			tailIndexedJobPodLogs(tailCtx, cli, namespace, name, index, workerLogger)
+
+			return nil
+		})
+	}
+
+	succeeded, err := waitForJobTermination(ctx, cli, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	drainLogs(&eg)
+
+	if !succeeded {
+		return fmt.Errorf("job %s/%s failed", namespace, name)
 	}
 
 	return nil
 }
+
+// tailIndexedJobPodLogs tails the logs of the pod with the given completion index of the job until the context is done.
+//
+// Failing to tail the logs is not fatal for the migration, so the errors are only logged.
+func tailIndexedJobPodLogs(ctx context.Context, cli kubernetes.Interface,
+	namespace string, name string, index int, logger *slog.Logger,
+) {
+	labelSelector := fmt.Sprintf("job-name=%s,%s=%d", name, jobCompletionIndexLabel, index)
+
+	// the pod might only be created after the pods of the other indexes complete, if the job runs fewer pods at once
+	pod, err := WaitForPod(ctx, cli, namespace, labelSelector)
+	for err != nil && ctx.Err() == nil {
+		pod, err = WaitForPod(ctx, cli, namespace, labelSelector)
+	}
+
+	if err != nil {
+		logger.Debug("failed to find the pod to tail the logs of", "error", err)
+
+		return
+	}
+
+	progressLogger := progress.NewLogger(progress.LoggerOptions{
+		LogStreamFunc: func(ctx context.Context) (io.ReadCloser, error) {
+			return cli.CoreV1().Pods(namespace).GetLogs(pod.Name,
+				&corev1.PodLogOptions{Follow: true}).Stream(ctx)
+		},
+	})
+
+	if err = progressLogger.Start(ctx, logger); err != nil {
+		logger.Debug("failed to tail the pod logs", "pod", pod.Name, "error", err)
+	}
+}
+
+func waitForJobTermination(ctx context.Context, cli kubernetes.Interface,
+	namespace string, name string,
+) (bool, error) {
+	var succeeded bool
+
+	resCli := cli.BatchV1().Jobs(namespace)
+	fieldSelector := fields.OneTermEqualSelector(metav1.ObjectNameField, name).String()
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+
+			list, err := resCli.List(ctx, options)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list jobs: %w", err)
+			}
+
+			return list, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+
+			resWatch, err := resCli.Watch(ctx, options)
+			if err != nil {
+				return nil, fmt.Errorf("failed to watch jobs: %w", err)
+			}
+
+			return resWatch, nil
+		},
+	}
+
+	if _, err := watchtools.UntilWithSync(ctx, listWatch, &batchv1.Job{}, nil,
+		func(event watch.Event) (bool, error) {
+			res, ok := event.Object.(*batchv1.Job)
+			if !ok {
+				return false, fmt.Errorf("unexpected type while watching jobs: %s/%s", namespace, name)
+			}
+
+			for _, condition := range res.Status.Conditions {
+				if condition.Status != corev1.ConditionTrue {
+					continue
+				}
+
+				switch condition.Type { //nolint:exhaustive
+				case batchv1.JobComplete:
+					succeeded = true
+
+					return true, nil
+				case batchv1.JobFailed:
+					return true, nil
+				}
+			}
+
+			return false, nil
+		}); err != nil {
+		return false, fmt.Errorf("failed to wait for job termination: %w", err)
+	}
+
+	return succeeded, nil
+}
@@ -0,0 +1,18 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobFailedError(t *testing.T) {
+	t.Parallel()
+
+	assert.EqualError(t, &JobFailedError{Namespace: "ns", Name: "job", ExitCode: -1}, "job ns/job failed")
+	assert.EqualError(t, &JobFailedError{Namespace: "ns", Name: "job", ExitCode: 1},
+		"job ns/job failed with exit code 1")
+	assert.EqualError(t, &JobFailedError{
+		Namespace: "ns", Name: "job", ExitCode: 1, DestNotEmptyEntries: []string{"data", "file.txt"},
+	}, "job ns/job failed with exit code 1: the destination is not empty, found: data, file.txt")
+}
@@ -3,6 +3,8 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"io"
+	"slices"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
@@ -11,7 +13,9 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/remotecommand"
 	watchtools "k8s.io/client-go/tools/watch"
 )
 
@@ -20,6 +24,14 @@ const (
 )
 
 func WaitForPod(ctx context.Context, cli kubernetes.Interface, namespace, labelSelector string) (*corev1.Pod, error) {
+	return waitForPod(ctx, cli, namespace, labelSelector, nil)
+}
+
+// waitForPod waits for a pod matching the label selector to leave the pending phase,
+// ignoring the pods with the given names, e.g., those of the previous attempts of a job.
+func waitForPod(ctx context.Context, cli kubernetes.Interface, namespace, labelSelector string,
+	ignoredPods []string,
+) (*corev1.Pod, error) {
 	var result *corev1.Pod
 
 	resCli := cli.CoreV1().Pods(namespace)
@@ -58,7 +70,7 @@ func WaitForPod(ctx context.Context, cli kubernetes.Interface, namespace, labelS
 			}
 
 			phase := res.Status.Phase
-			if phase != corev1.PodPending {
+			if phase != corev1.PodPending && !slices.Contains(ignoredPods, res.Name) {
 				result = res
 
 				return true, nil
@@ -72,10 +84,26 @@ func WaitForPod(ctx context.Context, cli kubernetes.Interface, namespace, labelS
 	return result, nil
 }
 
+// isPodDisrupted returns whether the pod failed because of a disruption, e.g., an eviction
+// or the drain of its node, rather than because of its containers.
+func isPodDisrupted(pod *corev1.Pod) bool {
+	if pod.Status.Reason == "Evicted" {
+		return true
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.DisruptionTarget && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
+
 func waitForPodTermination(ctx context.Context, cli kubernetes.Interface,
 	namespace string, name string,
-) (*corev1.PodPhase, error) {
-	var result *corev1.PodPhase
+) (*corev1.Pod, error) {
+	var result *corev1.Pod
 
 	resCli := cli.CoreV1().Pods(namespace)
 	fieldSelector := fields.OneTermEqualSelector(metav1.ObjectNameField, name).String()
@@ -109,9 +137,8 @@ func waitForPodTermination(ctx context.Context, cli kubernetes.Interface,
 				return false, fmt.Errorf("unexpected type while watching pods: %s/%s", namespace, name)
 			}
 
-			phase := res.Status.Phase
-			if phase != corev1.PodRunning {
-				result = &phase
+			if res.Status.Phase != corev1.PodRunning {
+				result = res
 
 				return true, nil
 			}
@@ -123,3 +150,29 @@ func waitForPodTermination(ctx context.Context, cli kubernetes.Interface,
 
 	return result, nil
 }
+
+// ExecInPod runs the command in the container of the pod, writing its output to the given writers.
+// It fails if the command cannot be started or exits with a non-zero code.
+func ExecInPod(ctx context.Context, client *ClusterClient, namespace, name, container string,
+	command []string, stdout, stderr io.Writer,
+) error {
+	req := client.KubeClient.CoreV1().RESTClient().Post().Resource("pods").
+		Name(name).Namespace(namespace).SubResource("exec")
+	req.VersionedParams(&corev1.PodExecOptions{
+		Container: container,
+		Command:   command,
+		Stdout:    true,
+		Stderr:    true,
+	}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(client.RestConfig, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create SPDY executor: %w", err)
+	}
+
+	if err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: stdout, Stderr: stderr}); err != nil {
+		return fmt.Errorf("failed to exec in pod %s/%s: %w", namespace, name, err)
+	}
+
+	return nil
+}
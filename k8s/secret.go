@@ -0,0 +1,24 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GetSecretKey returns the value of the key in the secret, failing if the secret or the key does not exist.
+func GetSecretKey(ctx context.Context, cli kubernetes.Interface, namespace, name, key string) ([]byte, error) {
+	secret, err := cli.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s/%s: %w", namespace, name, err)
+	}
+
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no key %s", namespace, name, key)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,32 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetSecretKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cli := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "kubeconfigs"},
+		Data:       map[string][]byte{"source": []byte("apiVersion: v1")},
+	})
+
+	value, err := GetSecretKey(ctx, cli, "ns", "kubeconfigs", "source")
+	require.NoError(t, err)
+	assert.Equal(t, "apiVersion: v1", string(value))
+
+	_, err = GetSecretKey(ctx, cli, "ns", "kubeconfigs", "dest")
+	require.EqualError(t, err, "secret ns/kubeconfigs has no key dest")
+
+	_, err = GetSecretKey(ctx, cli, "other", "kubeconfigs", "source")
+	require.ErrorContains(t, err, "failed to get secret other/kubeconfigs")
+}
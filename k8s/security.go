@@ -0,0 +1,51 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	SecurityProfileRuntimeDefault = "RuntimeDefault"
+	SecurityProfileUnconfined     = "Unconfined"
+	SecurityProfileLocalhost      = "Localhost"
+)
+
+// SecurityProfile is a seccomp or AppArmor profile of a pod.
+type SecurityProfile struct {
+	Type             string
+	LocalhostProfile string
+}
+
+// ParseSecurityProfile parses a seccomp or AppArmor profile in the form of
+// "RuntimeDefault", "Unconfined" or "Localhost/<profile>". The type is matched case-insensitively.
+func ParseSecurityProfile(value string) (SecurityProfile, error) {
+	profileType, localhostProfile, hasProfile := strings.Cut(value, "/")
+
+	switch {
+	case strings.EqualFold(profileType, SecurityProfileRuntimeDefault) && !hasProfile:
+		return SecurityProfile{Type: SecurityProfileRuntimeDefault}, nil
+	case strings.EqualFold(profileType, SecurityProfileUnconfined) && !hasProfile:
+		return SecurityProfile{Type: SecurityProfileUnconfined}, nil
+	case strings.EqualFold(profileType, SecurityProfileLocalhost):
+		if localhostProfile == "" {
+			return SecurityProfile{}, fmt.Errorf("profile name is required for the %s type, e.g., %s/my-profile",
+				SecurityProfileLocalhost, SecurityProfileLocalhost)
+		}
+
+		return SecurityProfile{Type: SecurityProfileLocalhost, LocalhostProfile: localhostProfile}, nil
+	default:
+		return SecurityProfile{}, fmt.Errorf("invalid profile %q, must be one of %s, %s or %s/<profile>",
+			value, SecurityProfileRuntimeDefault, SecurityProfileUnconfined, SecurityProfileLocalhost)
+	}
+}
+
+// HelmValues returns the profile in the form of the seccompProfile and appArmorProfile fields of a security context.
+func (p SecurityProfile) HelmValues() map[string]any {
+	result := map[string]any{"type": p.Type}
+	if p.LocalhostProfile != "" {
+		result["localhostProfile"] = p.LocalhostProfile
+	}
+
+	return result
+}
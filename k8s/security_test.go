@@ -0,0 +1,31 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSecurityProfile(t *testing.T) {
+	t.Parallel()
+
+	profile, err := ParseSecurityProfile("runtimedefault")
+	require.NoError(t, err)
+	assert.Equal(t, SecurityProfile{Type: SecurityProfileRuntimeDefault}, profile)
+	assert.Equal(t, map[string]any{"type": "RuntimeDefault"}, profile.HelmValues())
+
+	profile, err = ParseSecurityProfile("Unconfined")
+	require.NoError(t, err)
+	assert.Equal(t, SecurityProfile{Type: SecurityProfileUnconfined}, profile)
+
+	profile, err = ParseSecurityProfile("Localhost/profiles/rsync.json")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"type": "Localhost", "localhostProfile": "profiles/rsync.json"},
+		profile.HelmValues())
+
+	for _, value := range []string{"", "Localhost", "Localhost/", "RuntimeDefault/x", "Default"} {
+		_, err = ParseSecurityProfile(value)
+		require.Error(t, err, value)
+	}
+}
@@ -0,0 +1,160 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	SnapshotAPIGroup = "snapshot.storage.k8s.io"
+	SnapshotKind     = "VolumeSnapshot"
+
+	defaultSnapshotClassAnnotation = "snapshot.storage.kubernetes.io/is-default-class"
+
+	snapshotPollInterval = 2 * time.Second
+)
+
+var (
+	volumeSnapshotGVR = schema.GroupVersionResource{
+		Group:    SnapshotAPIGroup,
+		Version:  "v1",
+		Resource: "volumesnapshots",
+	}
+	volumeSnapshotClassGVR = schema.GroupVersionResource{
+		Group:    SnapshotAPIGroup,
+		Version:  "v1",
+		Resource: "volumesnapshotclasses",
+	}
+
+	// ErrNoSnapshotClass is returned when no VolumeSnapshotClass can be found for a CSI driver.
+	ErrNoSnapshotClass = errors.New("no volume snapshot class found")
+)
+
+// FindVolumeSnapshotClass returns the name of the VolumeSnapshotClass to be used for the given CSI driver.
+//
+// The class marked as default is preferred if there are multiple classes for the driver.
+// If the snapshot API is not installed in the cluster or there is no matching class, ErrNoSnapshotClass is returned.
+func FindVolumeSnapshotClass(ctx context.Context, cli dynamic.Interface, driver string) (string, error) {
+	list, err := cli.Resource(volumeSnapshotClassGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", ErrNoSnapshotClass
+		}
+
+		return "", fmt.Errorf("failed to list volume snapshot classes: %w", err)
+	}
+
+	var result string
+
+	for _, item := range list.Items {
+		itemDriver, _, _ := unstructured.NestedString(item.Object, "driver")
+		if itemDriver != driver {
+			continue
+		}
+
+		if item.GetAnnotations()[defaultSnapshotClassAnnotation] == "true" {
+			return item.GetName(), nil
+		}
+
+		if result == "" {
+			result = item.GetName()
+		}
+	}
+
+	if result == "" {
+		return "", ErrNoSnapshotClass
+	}
+
+	return result, nil
+}
+
+// CreateVolumeSnapshot creates a VolumeSnapshot of the given PVC using the given VolumeSnapshotClass.
+func CreateVolumeSnapshot(ctx context.Context, cli dynamic.Interface,
+	namespace, name, pvcName, snapshotClass string, labels map[string]string,
+) error {
+	snapshot := &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": volumeSnapshotGVR.GroupVersion().String(),
+			"kind":       SnapshotKind,
+			"metadata": map[string]any{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]any{
+				"volumeSnapshotClassName": snapshotClass,
+				"source": map[string]any{
+					"persistentVolumeClaimName": pvcName,
+				},
+			},
+		},
+	}
+
+	snapshot.SetLabels(labels)
+
+	if _, err := cli.Resource(volumeSnapshotGVR).Namespace(namespace).
+		Create(ctx, snapshot, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create volume snapshot %s/%s: %w", namespace, name, err)
+	}
+
+	return nil
+}
+
+// VolumeSnapshotExists returns whether the VolumeSnapshot exists.
+func VolumeSnapshotExists(ctx context.Context, cli dynamic.Interface, namespace, name string) (bool, error) {
+	if _, err := cli.Resource(volumeSnapshotGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to get volume snapshot %s/%s: %w", namespace, name, err)
+	}
+
+	return true, nil
+}
+
+// WaitForVolumeSnapshotReady waits until the VolumeSnapshot is ready to be used as a data source.
+func WaitForVolumeSnapshotReady(ctx context.Context, cli dynamic.Interface,
+	namespace, name string, timeout time.Duration,
+) error {
+	resCli := cli.Resource(volumeSnapshotGVR).Namespace(namespace)
+
+	if err := wait.PollUntilContextTimeout(ctx, snapshotPollInterval, timeout, true,
+		func(ctx context.Context) (bool, error) {
+			snapshot, err := resCli.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, fmt.Errorf("failed to get volume snapshot: %w", err)
+			}
+
+			if message, found, _ := unstructured.NestedString(snapshot.Object,
+				"status", "error", "message"); found && message != "" {
+				return false, fmt.Errorf("volume snapshot failed: %s", message)
+			}
+
+			ready, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+
+			return ready, nil
+		}); err != nil {
+		return fmt.Errorf("failed to wait for volume snapshot %s/%s to be ready: %w", namespace, name, err)
+	}
+
+	return nil
+}
+
+// DeleteVolumeSnapshot deletes the VolumeSnapshot. It is not an error if it does not exist.
+func DeleteVolumeSnapshot(ctx context.Context, cli dynamic.Interface, namespace, name string) error {
+	err := cli.Resource(volumeSnapshotGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete volume snapshot %s/%s: %w", namespace, name, err)
+	}
+
+	return nil
+}
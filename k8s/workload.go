@@ -0,0 +1,69 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GetWorkloadPVCNames returns the names of the PVCs used by the given workload,
+// in the form of "<kind>/<name>" where kind is either "deployment" or "statefulset".
+//
+// For StatefulSets, the PVCs created from the volume claim templates for each replica
+// are included as well. The volumes which are not PVCs are skipped.
+func GetWorkloadPVCNames(ctx context.Context, cli kubernetes.Interface, namespace, workload string) ([]string, error) {
+	kind, name, found := strings.Cut(workload, "/")
+	if !found || name == "" {
+		return nil, fmt.Errorf("invalid workload %q, expected the form <kind>/<name>", workload)
+	}
+
+	switch strings.ToLower(kind) {
+	case "deployment", "deployments", "deploy":
+		deployment, err := cli.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
+		}
+
+		return podSpecPVCNames(&deployment.Spec.Template.Spec), nil
+	case "statefulset", "statefulsets", "sts":
+		statefulSet, err := cli.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s/%s: %w", namespace, name, err)
+		}
+
+		replicas := int32(1)
+		if statefulSet.Spec.Replicas != nil {
+			replicas = *statefulSet.Spec.Replicas
+		}
+
+		pvcNames := podSpecPVCNames(&statefulSet.Spec.Template.Spec)
+
+		for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+			for ordinal := range replicas {
+				pvcNames = append(pvcNames, fmt.Sprintf("%s-%s-%d", template.Name, statefulSet.Name, ordinal))
+			}
+		}
+
+		return pvcNames, nil
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %q, must be one of: deployment, statefulset", kind)
+	}
+}
+
+func podSpecPVCNames(spec *corev1.PodSpec) []string {
+	var pvcNames []string
+
+	for _, volume := range spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+
+		pvcNames = append(pvcNames, volume.PersistentVolumeClaim.ClaimName)
+	}
+
+	return pvcNames
+}
@@ -0,0 +1,63 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetWorkloadPVCNames(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	podSpec := corev1.PodSpec{
+		Volumes: []corev1.Volume{
+			{Name: "data", VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "shared"},
+			}},
+			{Name: "config", VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{},
+			}},
+		},
+	}
+
+	replicas := int32(2)
+	cli := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+			Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec}},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "db"},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: &replicas,
+				Template: corev1.PodTemplateSpec{Spec: podSpec},
+				VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+					{ObjectMeta: metav1.ObjectMeta{Name: "www"}},
+				},
+			},
+		},
+	)
+
+	pvcNames, err := GetWorkloadPVCNames(ctx, cli, "ns", "deployment/app")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shared"}, pvcNames)
+
+	pvcNames, err = GetWorkloadPVCNames(ctx, cli, "ns", "sts/db")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shared", "www-db-0", "www-db-1"}, pvcNames)
+
+	_, err = GetWorkloadPVCNames(ctx, cli, "ns", "daemonset/agent")
+	require.Error(t, err)
+
+	_, err = GetWorkloadPVCNames(ctx, cli, "ns", "app")
+	require.Error(t, err)
+}
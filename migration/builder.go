@@ -0,0 +1,122 @@
+package migration
+
+import (
+	"strings"
+	"time"
+
+	"github.com/utkuozdemir/pv-migrate/ssh"
+)
+
+// The defaults of the fields of the request, shared by the Builder and the flags of the command line.
+const (
+	DefaultSourceMountReadOnly = true
+	DefaultCompress            = true
+	DefaultKeyAlgorithm        = ssh.Ed25519KeyAlgorithm
+	DefaultHelmTimeout         = 1 * time.Minute
+	DefaultLBSvcTimeout        = 2 * time.Minute
+	DefaultSyncInterval        = 1 * time.Minute
+	DefaultRsyncdPort          = 873
+	DefaultRsyncVerbose        = 1
+	DefaultParallel            = 1
+)
+
+// MissingFieldsError is returned by Builder.Build when the required fields of the request are not set.
+type MissingFieldsError struct {
+	Fields []string
+}
+
+func (e *MissingFieldsError) Error() string {
+	return "missing required fields of the request: " + strings.Join(e.Fields, ", ")
+}
+
+// Option sets an optional field of the request being built.
+type Option func(request *Request)
+
+// Builder builds a Request for using pv-migrate as a library.
+//
+// The fields which are not set have the same defaults as the flags of the command line,
+// except for the strategies, which are required.
+type Builder struct {
+	request Request
+}
+
+// NewBuilder returns a new Builder.
+func NewBuilder() *Builder {
+	return &Builder{
+		request: Request{
+			SourceMountReadOnly: DefaultSourceMountReadOnly,
+			Compress:            DefaultCompress,
+			KeyAlgorithm:        DefaultKeyAlgorithm,
+			HelmTimeout:         DefaultHelmTimeout,
+			LBSvcTimeout:        DefaultLBSvcTimeout,
+			SyncInterval:        DefaultSyncInterval,
+			RsyncdPort:          DefaultRsyncdPort,
+			RsyncVerbose:        DefaultRsyncVerbose,
+			Parallel:            DefaultParallel,
+		},
+	}
+}
+
+// WithSource sets the source PVC.
+func (b *Builder) WithSource(source PVCInfo) *Builder {
+	b.request.Source = &source
+
+	return b
+}
+
+// WithDest sets the destination PVC.
+func (b *Builder) WithDest(dest PVCInfo) *Builder {
+	b.request.Dest = &dest
+
+	return b
+}
+
+// WithStrategies sets the names of the strategies to attempt, in order, e.g., strategy.DefaultStrategies.
+func (b *Builder) WithStrategies(strategies ...string) *Builder {
+	b.request.Strategies = strategies
+
+	return b
+}
+
+// With applies the given options to the request, e.g., to set the rsync options.
+func (b *Builder) With(options ...Option) *Builder {
+	for _, option := range options {
+		option(&b.request)
+	}
+
+	return b
+}
+
+// Build returns the built request, or a *MissingFieldsError listing all the required fields which are not set.
+// The request is then validated with Request.Validate, as the command line does.
+func (b *Builder) Build() (*Request, error) {
+	var missing []string
+
+	if b.request.Source == nil || b.request.Source.Name == "" {
+		missing = append(missing, "source")
+	}
+
+	if b.request.Dest == nil || b.request.Dest.Name == "" {
+		missing = append(missing, "dest")
+	}
+
+	if len(b.request.Strategies) == 0 {
+		missing = append(missing, "strategies")
+	}
+
+	if b.request.KeyAlgorithm == "" {
+		missing = append(missing, "key algorithm")
+	}
+
+	if len(missing) > 0 {
+		return nil, &MissingFieldsError{Fields: missing}
+	}
+
+	request := b.request
+
+	if err := request.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
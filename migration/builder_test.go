@@ -0,0 +1,79 @@
+package migration_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+
+	request, err := migration.NewBuilder().
+		WithSource(migration.PVCInfo{Namespace: "ns1", Name: "source"}).
+		WithDest(migration.PVCInfo{Namespace: "ns2", Name: "dest"}).
+		WithStrategies("mnt2", "svc").
+		With(func(request *migration.Request) {
+			request.Compress = true
+		}).
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "source", request.Source.Name)
+	assert.Equal(t, "dest", request.Dest.Name)
+	assert.Equal(t, []string{"mnt2", "svc"}, request.Strategies)
+	assert.True(t, request.Compress)
+	assert.Equal(t, "ed25519", request.KeyAlgorithm)
+	assert.Equal(t, 1, request.Parallel)
+	assert.NotZero(t, request.HelmTimeout)
+}
+
+func TestBuilderDefaults(t *testing.T) {
+	t.Parallel()
+
+	request, err := migration.NewBuilder().
+		WithSource(migration.PVCInfo{Namespace: "ns1", Name: "source"}).
+		WithDest(migration.PVCInfo{Namespace: "ns2", Name: "dest"}).
+		WithStrategies("mnt2").
+		Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, migration.DefaultSourceMountReadOnly, request.SourceMountReadOnly)
+	assert.Equal(t, migration.DefaultCompress, request.Compress)
+	assert.Equal(t, migration.DefaultHelmTimeout, request.HelmTimeout)
+	assert.Equal(t, migration.DefaultLBSvcTimeout, request.LBSvcTimeout)
+	assert.Equal(t, migration.DefaultSyncInterval, request.SyncInterval)
+	assert.Equal(t, migration.DefaultRsyncdPort, request.RsyncdPort)
+	assert.Equal(t, migration.DefaultRsyncVerbose, request.RsyncVerbose)
+	assert.True(t, request.SourceMountReadOnly, "the source must be mounted read-only by default as with the CLI")
+}
+
+func TestBuilderValidates(t *testing.T) {
+	t.Parallel()
+
+	_, err := migration.NewBuilder().
+		WithSource(migration.PVCInfo{Namespace: "ns1", Name: "source"}).
+		WithDest(migration.PVCInfo{Namespace: "ns2", Name: "dest"}).
+		WithStrategies("mnt2").
+		With(func(request *migration.Request) {
+			request.Parallel = 4
+			request.DeleteExtraneousFiles = true
+		}).
+		Build()
+	require.EqualError(t, err, "--parallel cannot be used together with --dest-delete-extraneous-files")
+}
+
+func TestBuilderMissingFields(t *testing.T) {
+	t.Parallel()
+
+	_, err := migration.NewBuilder().
+		WithSource(migration.PVCInfo{Namespace: "ns1"}).
+		Build()
+
+	var missingFieldsErr *migration.MissingFieldsError
+	require.ErrorAs(t, err, &missingFieldsErr)
+	assert.Equal(t, []string{"source", "dest", "strategies"}, missingFieldsErr.Fields)
+}
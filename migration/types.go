@@ -5,15 +5,33 @@ import (
 
 	"helm.sh/helm/v3/pkg/chart"
 
+	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/rsync"
 )
 
 type PVCInfo struct {
 	KubeconfigPath string
-	Context        string
-	Namespace      string
-	Name           string
-	Path           string
+	// Kubeconfig is the content of the kubeconfig, e.g., read from a secret, used instead of KubeconfigPath if set.
+	Kubeconfig            []byte
+	Context               string
+	Namespace             string
+	Name                  string
+	Path                  string
+	InsecureSkipTLSVerify bool
+	CAFile                string
+}
+
+const (
+	ExtraVolumeKindConfigMap = "configmap"
+	ExtraVolumeKindSecret    = "secret"
+)
+
+// ExtraVolume is an existing ConfigMap or Secret in the namespace of the rsync pod, to be mounted into it.
+type ExtraVolume struct {
+	Kind      string
+	Name      string
+	MountPath string
 }
 
 type Request struct {
@@ -21,20 +39,103 @@ type Request struct {
 	Dest                  *PVCInfo
 	DeleteExtraneousFiles bool
 	IgnoreMounted         bool
+	ScaleDownDest         bool
+	DestReclaimPolicy     string
 	NoChown               bool
+	NoOwner               bool
+	NoGroup               bool
 	SkipCleanup           bool
+	KeepResources         []string
+	AddCapabilities       []string
+	DropCapabilities      []string
+	ServerSideApply       bool
 	NoProgressBar         bool
+	ETAInterval           time.Duration
 	SourceMountReadOnly   bool
+	SourcePrepareCommand  string
 	KeyAlgorithm          string
+	RsyncUser             string
+	StrictHostKeyChecking bool
 	HelmTimeout           time.Duration
+	CoordinationNamespace string
 	HelmValuesFiles       []string
 	HelmValues            []string
 	HelmFileValues        []string
 	HelmStringValues      []string
 	Strategies            []string
 	DestHostOverride      string
+	NoDestMkdir           bool
+	IncludeLostFound      bool
+	FailIfDestNotEmpty    bool
+	DestSSHHost           string
+	DestSSHPort           int
+	SSHServiceName        string
+	SSHClusterIP          string
+	RsyncdPort            int
 	LBSvcTimeout          time.Duration
+	IntermediateBucket    string
+	IntermediateSecret    string
+	IntermediatePhase     string
+	IntermediateImage     string
 	Compress              bool
+	SSHCompression        bool
+	AutoCompress          bool
+	SnapshotClass         string
+	Itemize               bool
+	RsyncVerbose          int
+	Parallel              int
+	MaxConcurrentPods     int
+	TopologySpread        string
+	Watch                 bool
+	SyncInterval          time.Duration
+	SSHConnectRetries     int
+	BlockSize             int
+	HardLinks             bool
+	OmitDirTimes          bool
+	DirsOnly              bool
+	NumericIDs            bool
+	Chmod                 string
+	Iconv                 string
+	FilesFrom             string
+	Since                 time.Time
+	CompareDest           string
+	Backup                bool
+	BackupDir             string
+	FilterFile            string
+	IOTimeout             int
+	ConnectTimeout        *int
+	Update                bool
+	IgnoreExisting        bool
+	DelayUpdates          bool
+	WholeFile             *bool
+	MaxDelete             *int
+	EvictionRetries       int
+	Protocol              int
+	SockOpts              string
+	BwLimitSchedule       []rsync.BwLimitWindow
+	RespectTopology       bool
+	CheckTopology         bool
+	StrictFS              bool
+	WebhookURL            string
+	ResultFile            string
+	ChecksumManifest      string
+	FromSnapshot          bool
+	SourcePV              string
+	SnapshotAfter         bool
+	SnapshotAfterClass    string
+	PreserveSnapshotBase  bool
+	SeccompProfile        *k8s.SecurityProfile
+	AppArmorProfile       *k8s.SecurityProfile
+	RuntimeClass          string
+	AllowSidecars         bool
+	PodDNSPolicy          string
+	PodDNSNameservers     []string
+	PreserveSELinux       bool
+	ClientImage           string
+	ServerImage           string
+	ExtraVolumes          []ExtraVolume
+	ValidateOnly          bool
+	PrintCommand          bool
 }
 
 type Migration struct {
@@ -42,10 +143,22 @@ type Migration struct {
 	Request    *Request
 	SourceInfo *pvc.Info
 	DestInfo   *pvc.Info
+	// NoCommonNode is set when no node can mount both the source and the destination PVCs,
+	// as found by the topology check, for them not to be mounted in a single pod.
+	NoCommonNode bool
 }
 
 type Attempt struct {
 	ID                    string
 	HelmReleaseNamePrefix string
 	Migration             *Migration
+	// OnAccepted is called, if set, when the strategy accepts the migration, i.e., before it starts running it.
+	OnAccepted func()
+}
+
+// Accept is called by the strategy once it accepts the migration, i.e., it is able to run it.
+func (a *Attempt) Accept() {
+	if a.OnAccepted != nil {
+		a.OnAccepted()
+	}
 }
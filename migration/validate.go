@@ -0,0 +1,326 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/utkuozdemir/pv-migrate/rsync"
+	"github.com/utkuozdemir/pv-migrate/util"
+)
+
+const (
+	// IntermediatePhaseAll, IntermediatePhaseUpload and IntermediatePhaseDownload are the phases of the objstore
+	// strategy to run, i.e., both of them in order, or only one of them, e.g., to run them at different times.
+	IntermediatePhaseAll      = "all"
+	IntermediatePhaseUpload   = "upload"
+	IntermediatePhaseDownload = "download"
+
+	// MaxRsyncVerbosity is the highest verbosity of rsync, i.e., "-vvv".
+	MaxRsyncVerbosity = 3
+	// MaxPodDNSNameservers is the maximum number of nameservers Kubernetes allows in the DNS config of a pod.
+	MaxPodDNSNameservers = 3
+
+	maxPort           = 65535
+	maxRsyncBlockSize = 128 * 1024
+
+	// shellSpecialChars are the characters which cannot be passed as is in the commands run in the pods.
+	shellSpecialChars = " \t\n'\"\\`$"
+)
+
+var (
+	// IntermediatePhases are the phases of the objstore strategy which can be run.
+	IntermediatePhases = []string{IntermediatePhaseAll, IntermediatePhaseUpload, IntermediatePhaseDownload}
+
+	// ReclaimPolicies are the reclaim policies which can be set on the PV of the destination PVC.
+	ReclaimPolicies = []string{
+		string(corev1.PersistentVolumeReclaimRetain), string(corev1.PersistentVolumeReclaimDelete),
+		string(corev1.PersistentVolumeReclaimRecycle),
+	}
+
+	// PodDNSPolicies are the DNS policies which can be set on the migration pods.
+	PodDNSPolicies = []string{
+		string(corev1.DNSClusterFirst), string(corev1.DNSClusterFirstWithHostNet),
+		string(corev1.DNSDefault), string(corev1.DNSNone),
+	}
+
+	// rsyncUserRegex matches the portable user names, as valid for useradd.
+	rsyncUserRegex = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+)
+
+// Validate validates the values of the fields of the request and their combinations, for a request which cannot
+// succeed to fail before anything is created in the clusters. The fields are named after the flags
+// of the command line in the errors.
+func (r *Request) Validate() error {
+	for _, validate := range []func() error{
+		r.validateLimits, r.validateNames, r.validatePodOptions, r.validateRsyncOptions, r.validateCombinations,
+	} {
+		if err := validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Request) validateLimits() error {
+	for _, field := range []struct {
+		name  string
+		value int
+	}{
+		{"eviction-retries", r.EvictionRetries},
+		{"max-concurrent-pods", r.MaxConcurrentPods},
+		{"ssh-connect-retries", r.SSHConnectRetries},
+		{"io-timeout", r.IOTimeout},
+		{"protocol", r.Protocol},
+	} {
+		if field.value < 0 {
+			return fmt.Errorf("--%s cannot be negative", field.name)
+		}
+	}
+
+	if r.MaxDelete != nil && *r.MaxDelete < 0 {
+		return errors.New("--max-delete cannot be negative")
+	}
+
+	if r.ConnectTimeout != nil && *r.ConnectTimeout < 0 {
+		return errors.New("--connect-timeout cannot be negative")
+	}
+
+	if r.ETAInterval < 0 {
+		return errors.New("--eta-interval cannot be negative")
+	}
+
+	if r.Parallel < 1 {
+		return errors.New("--parallel must be at least 1")
+	}
+
+	if r.RsyncVerbose < 1 || r.RsyncVerbose > MaxRsyncVerbosity {
+		return fmt.Errorf("--rsync-verbose must be between 1 and %d", MaxRsyncVerbosity)
+	}
+
+	if r.BlockSize < 0 || r.BlockSize > maxRsyncBlockSize {
+		return fmt.Errorf("--block-size must be a positive number of bytes up to %d", maxRsyncBlockSize)
+	}
+
+	if r.RsyncdPort <= 0 || r.RsyncdPort > maxPort {
+		return fmt.Errorf("--rsyncd-port must be a port number between 1 and %d", maxPort)
+	}
+
+	if r.DestSSHPort != 0 {
+		if r.DestSSHHost == "" {
+			return errors.New("--dest-ssh-port can only be used together with --dest-ssh-host")
+		}
+
+		if r.DestSSHPort < 0 || r.DestSSHPort > maxPort {
+			return fmt.Errorf("--dest-ssh-port must be a port number between 1 and %d", maxPort)
+		}
+	}
+
+	if r.Watch && r.SyncInterval <= 0 {
+		return errors.New("--sync-interval must be positive")
+	}
+
+	return nil
+}
+
+func (r *Request) validateNames() error {
+	if r.SSHServiceName != "" {
+		if errs := validation.IsDNS1035Label(r.SSHServiceName); len(errs) > 0 {
+			return fmt.Errorf("invalid --ssh-service-name: %s", strings.Join(errs, ", "))
+		}
+	}
+
+	if r.TopologySpread != "" {
+		if errs := validation.IsQualifiedName(r.TopologySpread); len(errs) > 0 {
+			return fmt.Errorf("invalid --topology-spread: %s", strings.Join(errs, ", "))
+		}
+	}
+
+	if r.CoordinationNamespace != "" {
+		if errs := validation.IsDNS1123Label(r.CoordinationNamespace); len(errs) > 0 {
+			return fmt.Errorf("invalid --coordination-namespace: %s", strings.Join(errs, ", "))
+		}
+	}
+
+	if r.DestSSHHost != "" && net.ParseIP(r.DestSSHHost) == nil {
+		if errs := validation.IsDNS1123Subdomain(r.DestSSHHost); len(errs) > 0 {
+			return fmt.Errorf("--dest-ssh-host must be an IP address or a DNS name: %s", strings.Join(errs, ", "))
+		}
+	}
+
+	if r.SSHClusterIP != "" && net.ParseIP(r.SSHClusterIP) == nil {
+		return errors.New("--ssh-cluster-ip must be an IP address")
+	}
+
+	if r.RsyncUser != "" && !rsyncUserRegex.MatchString(r.RsyncUser) {
+		return errors.New("--rsync-user must be a valid user name, i.e., start with a lowercase letter " +
+			"or an underscore, followed by up to 31 lowercase letters, digits, underscores or dashes")
+	}
+
+	if r.WebhookURL != "" {
+		if parsed, err := url.Parse(r.WebhookURL); err != nil || parsed.Host == "" ||
+			(parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return errors.New("--webhook-url must be an http or https URL")
+		}
+	}
+
+	return r.validateIntermediate()
+}
+
+// validateIntermediate validates the fields of the objstore strategy.
+func (r *Request) validateIntermediate() error {
+	if strings.ContainsAny(r.IntermediateBucket, shellSpecialChars) {
+		return errors.New("--intermediate-bucket cannot contain whitespace, quotes or shell special characters")
+	}
+
+	if r.IntermediateBucket != "" && r.IntermediateSecret == "" {
+		return errors.New("--intermediate-bucket requires --intermediate-secret")
+	}
+
+	if r.IntermediatePhase != "" && !slices.Contains(IntermediatePhases, r.IntermediatePhase) {
+		return fmt.Errorf("--intermediate-phase must be one of: %s", strings.Join(IntermediatePhases, ", "))
+	}
+
+	if r.IntermediateImage != "" {
+		if _, _, err := util.ParseImage(r.IntermediateImage); err != nil {
+			return fmt.Errorf("invalid --intermediate-image: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validatePodOptions validates the options of the migration pods.
+func (r *Request) validatePodOptions() error {
+	if r.DestReclaimPolicy != "" && !slices.Contains(ReclaimPolicies, r.DestReclaimPolicy) {
+		return fmt.Errorf("--dest-reclaim-policy must be one of: %s", strings.Join(ReclaimPolicies, ", "))
+	}
+
+	if r.PodDNSPolicy != "" && !slices.Contains(PodDNSPolicies, r.PodDNSPolicy) {
+		return fmt.Errorf("--pod-dns-policy must be one of: %s", strings.Join(PodDNSPolicies, ", "))
+	}
+
+	if len(r.PodDNSNameservers) > MaxPodDNSNameservers {
+		return fmt.Errorf("--pod-dns-nameserver can be specified at most %d times", MaxPodDNSNameservers)
+	}
+
+	for _, nameserver := range r.PodDNSNameservers {
+		if net.ParseIP(nameserver) == nil {
+			return fmt.Errorf("--pod-dns-nameserver must be an IP address: %q", nameserver)
+		}
+	}
+
+	if r.PodDNSPolicy == string(corev1.DNSNone) && len(r.PodDNSNameservers) == 0 {
+		return fmt.Errorf("--pod-dns-nameserver is required when --pod-dns-policy is %s", corev1.DNSNone)
+	}
+
+	if r.SourcePrepareCommand != "" && r.SourcePV != "" {
+		return errors.New("--source-prepare-command cannot be used together with --source-pv, " +
+			"as the PV is not mounted by any pod")
+	}
+
+	return nil
+}
+
+// validateRsyncOptions validates the syntax of the options passed to rsync.
+func (r *Request) validateRsyncOptions() error {
+	if r.Chmod != "" {
+		if err := rsync.ValidateChmod(r.Chmod); err != nil {
+			return fmt.Errorf("invalid --chmod: %w", err)
+		}
+	}
+
+	if r.Iconv != "" {
+		if err := rsync.ValidateIconv(r.Iconv); err != nil {
+			return fmt.Errorf("invalid --iconv: %w", err)
+		}
+	}
+
+	if strings.Contains(r.SockOpts, "'") {
+		return errors.New("--sockopts cannot contain single quotes")
+	}
+
+	if err := validateDestDir("compare-dest", r.CompareDest); err != nil {
+		return err
+	}
+
+	return validateDestDir("backup-dir", r.BackupDir)
+}
+
+// validateDestDir validates the path of a directory in the destination PVC, relative to the root of the PVC.
+func validateDestDir(name, dir string) error {
+	if dir == "" {
+		return nil
+	}
+
+	if strings.ContainsAny(dir, shellSpecialChars) {
+		return fmt.Errorf("--%s cannot contain whitespace, quotes or shell special characters", name)
+	}
+
+	if path.Clean("/"+dir) == "/" {
+		return fmt.Errorf("--%s cannot be the root of the destination PVC", name)
+	}
+
+	if slices.Contains(strings.Split(dir, "/"), "..") {
+		return fmt.Errorf("--%s must be inside the destination PVC", name)
+	}
+
+	return nil
+}
+
+// validateCombinations validates the combinations of the fields which cannot be used together.
+func (r *Request) validateCombinations() error {
+	since := !r.Since.IsZero()
+
+	switch {
+	case r.MaxDelete != nil && !r.DeleteExtraneousFiles:
+		return errors.New("--max-delete requires --dest-delete-extraneous-files")
+	case r.TopologySpread != "" && r.Parallel <= 1:
+		return errors.New("--topology-spread requires --parallel to be greater than 1")
+	case r.Parallel > 1 && r.DeleteExtraneousFiles:
+		return errors.New("--parallel cannot be used together with --dest-delete-extraneous-files")
+	case r.FilesFrom != "" && (r.Parallel > 1 || r.DeleteExtraneousFiles):
+		return errors.New("--files-from cannot be used together with --parallel or --dest-delete-extraneous-files")
+	case since && (r.FilesFrom != "" || r.Parallel > 1 || r.DeleteExtraneousFiles):
+		return errors.New("--since cannot be used together with --files-from, --parallel " +
+			"or --dest-delete-extraneous-files")
+	case len(r.BwLimitSchedule) > 0 && (since || r.Parallel > 1):
+		return errors.New("--bwlimit-schedule cannot be used together with --since or --parallel")
+	// the data transferred by a pod would fail the check of the pods running after it
+	case r.FailIfDestNotEmpty && (r.Parallel > 1 || r.Watch || r.EvictionRetries > 0):
+		return errors.New("--fail-if-dest-not-empty cannot be used together with --parallel, --watch " +
+			"or --eviction-retries")
+	// the files to transfer are listed explicitly by these, and the manifest would miss all the files
+	case r.DirsOnly && (r.FilesFrom != "" || since || r.ChecksumManifest != ""):
+		return errors.New("--dirs-only cannot be used together with --files-from, --since or --checksum-manifest")
+	}
+
+	if r.ChecksumManifest == "" {
+		return nil
+	}
+
+	switch {
+	// the existing files are not updated with the former, and the names of the files differ with the latter
+	case r.IgnoreExisting || r.Iconv != "":
+		return errors.New("--checksum-manifest cannot be used together with --conflict=skip-existing or --iconv")
+	// the destination is not migrated yet after the upload phase
+	case r.IntermediatePhase == IntermediatePhaseUpload:
+		return fmt.Errorf("--checksum-manifest cannot be used together with --intermediate-phase=%s",
+			IntermediatePhaseUpload)
+	// these leave the destination different from the source on purpose, or keep changing the source
+	case r.Watch || r.FilesFrom != "" || since || r.FilterFile != "" || r.CompareDest != "" || r.Update:
+		return errors.New("--checksum-manifest cannot be used together with --watch, --files-from, --since, " +
+			"--filter-file, --compare-dest or --update")
+	}
+
+	return nil
+}
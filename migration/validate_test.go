@@ -0,0 +1,159 @@
+package migration_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/rsync"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	negative := -1
+	maxDelete := 10
+
+	tests := []struct {
+		name   string
+		modify func(request *migration.Request)
+		err    string
+	}{
+		{
+			name:   "valid",
+			modify: func(*migration.Request) {},
+		},
+		{
+			name:   "parallel",
+			modify: func(request *migration.Request) { request.Parallel = 0 },
+			err:    "--parallel must be at least 1",
+		},
+		{
+			name:   "negative connect timeout",
+			modify: func(request *migration.Request) { request.ConnectTimeout = &negative },
+			err:    "--connect-timeout cannot be negative",
+		},
+		{
+			name:   "dest ssh port without host",
+			modify: func(request *migration.Request) { request.DestSSHPort = 2222 },
+			err:    "--dest-ssh-port can only be used together with --dest-ssh-host",
+		},
+		{
+			name:   "ssh service name",
+			modify: func(request *migration.Request) { request.SSHServiceName = "Not_A_Label" },
+			err:    "invalid --ssh-service-name",
+		},
+		{
+			name:   "rsync user",
+			modify: func(request *migration.Request) { request.RsyncUser = "Root" },
+			err:    "--rsync-user must be a valid user name",
+		},
+		{
+			name:   "webhook url",
+			modify: func(request *migration.Request) { request.WebhookURL = "ftp://example.com" },
+			err:    "--webhook-url must be an http or https URL",
+		},
+		{
+			name:   "intermediate bucket without secret",
+			modify: func(request *migration.Request) { request.IntermediateBucket = "bucket" },
+			err:    "--intermediate-bucket requires --intermediate-secret",
+		},
+		{
+			name:   "pod dns policy none without nameservers",
+			modify: func(request *migration.Request) { request.PodDNSPolicy = "None" },
+			err:    "--pod-dns-nameserver is required when --pod-dns-policy is None",
+		},
+		{
+			name:   "chmod",
+			modify: func(request *migration.Request) { request.Chmod = "x=y" },
+			err:    "invalid --chmod",
+		},
+		{
+			name:   "sockopts",
+			modify: func(request *migration.Request) { request.SockOpts = "SO_SNDBUF='1'" },
+			err:    "--sockopts cannot contain single quotes",
+		},
+		{
+			name:   "compare dest outside the destination",
+			modify: func(request *migration.Request) { request.CompareDest = "../other" },
+			err:    "--compare-dest must be inside the destination PVC",
+		},
+		{
+			name:   "backup dir at the root",
+			modify: func(request *migration.Request) { request.BackupDir = "/" },
+			err:    "--backup-dir cannot be the root of the destination PVC",
+		},
+		{
+			name:   "max delete without delete",
+			modify: func(request *migration.Request) { request.MaxDelete = &maxDelete },
+			err:    "--max-delete requires --dest-delete-extraneous-files",
+		},
+		{
+			name: "files from in parallel",
+			modify: func(request *migration.Request) {
+				request.FilesFrom = "a\n"
+				request.Parallel = 2
+			},
+			err: "--files-from cannot be used together with --parallel or --dest-delete-extraneous-files",
+		},
+		{
+			name: "bwlimit schedule with since",
+			modify: func(request *migration.Request) {
+				request.BwLimitSchedule = []rsync.BwLimitWindow{{Limit: "10M"}}
+				request.Since = time.Now()
+			},
+			err: "--bwlimit-schedule cannot be used together with --since or --parallel",
+		},
+		{
+			name: "fail if dest not empty with watch",
+			modify: func(request *migration.Request) {
+				request.FailIfDestNotEmpty = true
+				request.Watch = true
+			},
+			err: "--fail-if-dest-not-empty cannot be used together with --parallel, --watch or --eviction-retries",
+		},
+		{
+			name: "checksum manifest with the upload phase",
+			modify: func(request *migration.Request) {
+				request.ChecksumManifest = "manifest.sha256"
+				request.IntermediatePhase = migration.IntermediatePhaseUpload
+			},
+			err: "--checksum-manifest cannot be used together with --intermediate-phase=upload",
+		},
+		{
+			name: "checksum manifest with update",
+			modify: func(request *migration.Request) {
+				request.ChecksumManifest = "manifest.sha256"
+				request.Update = true
+			},
+			err: "--checksum-manifest cannot be used together with --watch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := migration.Request{
+				Parallel:     migration.DefaultParallel,
+				RsyncVerbose: migration.DefaultRsyncVerbose,
+				RsyncdPort:   migration.DefaultRsyncdPort,
+				SyncInterval: migration.DefaultSyncInterval,
+			}
+			tt.modify(&request)
+
+			err := request.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
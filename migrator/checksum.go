@@ -0,0 +1,69 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/utkuozdemir/pv-migrate/checksum"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/strategy"
+	"github.com/utkuozdemir/pv-migrate/util"
+)
+
+// maxLoggedChecksumMismatches limits the number of the mismatched files logged, for the logs to stay readable.
+const maxLoggedChecksumMismatches = 10
+
+// verifyChecksums computes the checksum manifests of the source and the destination after the migration,
+// writes the one of the source to the file requested, and fails if any file of the source is missing
+// on the destination or differs from it. The extraneous files on the destination are only logged.
+func verifyChecksums(ctx context.Context, mig *migration.Migration, logger *slog.Logger) error {
+	attemptID := util.RandomHexadecimalString(attemptIDLength)
+	attempt := migration.Attempt{
+		ID:                    attemptID,
+		HelmReleaseNamePrefix: "pv-migrate-" + attemptID + "-checksum",
+		Migration:             mig,
+	}
+
+	logger.Info("🧮 Computing the checksums of the files on the source and the destination")
+
+	source, dest, err := strategy.ChecksumManifests(ctx, &attempt, logger.With("attempt_id", attemptID))
+	if err != nil {
+		return err
+	}
+
+	path := mig.Request.ChecksumManifest
+	if err = source.Write(path); err != nil {
+		return err
+	}
+
+	diff := checksum.Compare(source, dest)
+
+	if len(diff.Extraneous) > 0 {
+		logger.Info("📋 Files found only on the destination", "count", len(diff.Extraneous),
+			"files", firstPaths(diff.Extraneous, maxLoggedChecksumMismatches))
+	}
+
+	if !diff.Matches() {
+		logger.Warn("🔶 Checksums of the destination do not match the source",
+			"missing", len(diff.Missing), "mismatched", len(diff.Mismatched),
+			"missing_files", firstPaths(diff.Missing, maxLoggedChecksumMismatches),
+			"mismatched_files", firstPaths(diff.Mismatched, maxLoggedChecksumMismatches))
+
+		return fmt.Errorf("%d files are missing and %d files differ on the destination",
+			len(diff.Missing), len(diff.Mismatched))
+	}
+
+	logger.Info("✅ Checksums of the destination match the source", "files", len(source), "manifest", path)
+
+	return nil
+}
+
+// firstPaths returns the first paths up to the limit.
+func firstPaths(paths []string, limit int) []string {
+	if len(paths) > limit {
+		return paths[:limit]
+	}
+
+	return paths
+}
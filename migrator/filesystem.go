@@ -0,0 +1,151 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+// filesystemFeature is a feature of the source filesystem which is lost when migrating to one without it.
+type filesystemFeature struct {
+	name        string
+	consequence string
+	filesystems []string
+}
+
+var posixFilesystems = []string{"ext2", "ext3", "ext4", "xfs", "btrfs", "zfs"}
+
+var filesystemFeatures = []filesystemFeature{
+	{
+		name:        "ownership and permissions",
+		consequence: "the owners and the permissions of the files cannot be preserved",
+		filesystems: posixFilesystems,
+	},
+	{
+		name:        "symlinks and hard links",
+		consequence: "the links cannot be created",
+		filesystems: posixFilesystems,
+	},
+	{
+		name:        "reflinks",
+		consequence: "the files sharing their data are copied separately and take up more space",
+		filesystems: []string{"xfs", "btrfs"},
+	},
+	{
+		name:        "project quotas",
+		consequence: "the project quotas are not enforced",
+		filesystems: []string{"xfs", "ext4"},
+	},
+}
+
+// checkFilesystems compares the filesystem types of the source and the destination PVCs, and warns about the
+// features of the source filesystem which the destination filesystem lacks. If strict is set, it fails instead.
+//
+// The declared filesystem types are compared, i.e., those of the persistent volumes or the storage classes,
+// not those the volumes are actually formatted with. Unless strict is set, the check is skipped if the filesystem
+// types cannot be determined, e.g., without the permission to get persistent volumes, or if the filesystem type
+// of either side is not declared. If strict is set, it fails then, and warns if the source filesystem is unknown
+// to the check, as its features cannot be compared.
+func checkFilesystems(ctx context.Context, sourceInfo, destInfo *pvc.Info, strict bool, logger *slog.Logger) error {
+	if sourceInfo.BlockMode {
+		return nil
+	}
+
+	sourceFS, destFS, err := filesystemTypes(ctx, sourceInfo, destInfo)
+	if err != nil {
+		if strict {
+			return err
+		}
+
+		logger.Debug("skipping the filesystem compatibility check", "error", err)
+
+		return nil
+	}
+
+	if sourceFS == "" || destFS == "" {
+		if strict {
+			return fmt.Errorf("the filesystem type of the %s PVC is not declared, the filesystems cannot be compared",
+				undeclaredSide(sourceFS))
+		}
+
+		logger.Debug("skipping the filesystem compatibility check, the filesystem type is not declared",
+			"source_fs", sourceFS, "dest_fs", destFS)
+
+		return nil
+	}
+
+	if strict && !knownFilesystem(sourceFS) {
+		logger.Warn("🔶 The source filesystem is unknown to the filesystem compatibility check, "+
+			"the features which would be lost in the migration cannot be determined",
+			"source_fs", sourceFS, "dest_fs", destFS)
+	}
+
+	incompatibilities := filesystemIncompatibilities(sourceFS, destFS)
+	if len(incompatibilities) == 0 {
+		return nil
+	}
+
+	for _, incompatibility := range incompatibilities {
+		logger.Warn("🔶 The destination filesystem does not support a feature of the source filesystem",
+			"source_fs", sourceFS, "dest_fs", destFS, "feature", incompatibility.name,
+			"consequence", incompatibility.consequence)
+	}
+
+	if strict {
+		names := make([]string, 0, len(incompatibilities))
+		for _, incompatibility := range incompatibilities {
+			names = append(names, incompatibility.name)
+		}
+
+		return fmt.Errorf("the destination filesystem %s does not support the features of the source filesystem %s: %s",
+			destFS, sourceFS, strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+func filesystemTypes(ctx context.Context, sourceInfo, destInfo *pvc.Info) (string, string, error) {
+	sourceFS, err := sourceInfo.FilesystemType(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to determine the filesystem type of the source PVC: %w", err)
+	}
+
+	destFS, err := destInfo.FilesystemType(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to determine the filesystem type of the destination PVC: %w", err)
+	}
+
+	return sourceFS, destFS, nil
+}
+
+func undeclaredSide(sourceFS string) string {
+	if sourceFS == "" {
+		return "source"
+	}
+
+	return "destination"
+}
+
+// knownFilesystem returns whether the filesystem has any of the features the compatibility check knows about.
+func knownFilesystem(fs string) bool {
+	return slices.ContainsFunc(filesystemFeatures, func(feature filesystemFeature) bool {
+		return slices.Contains(feature.filesystems, fs)
+	})
+}
+
+// filesystemIncompatibilities returns the features of the source filesystem which the destination filesystem lacks.
+func filesystemIncompatibilities(sourceFS, destFS string) []filesystemFeature {
+	var result []filesystemFeature
+
+	for _, feature := range filesystemFeatures {
+		if slices.Contains(feature.filesystems, sourceFS) && !slices.Contains(feature.filesystems, destFS) {
+			result = append(result, feature)
+		}
+	}
+
+	return result
+}
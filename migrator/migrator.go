@@ -1,18 +1,33 @@
 package migrator
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/netip"
+	"os"
+	"slices"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/utkuozdemir/pv-migrate/helm"
 	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
 	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/result"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
 	"github.com/utkuozdemir/pv-migrate/strategy"
+	"github.com/utkuozdemir/pv-migrate/tracing"
 	"github.com/utkuozdemir/pv-migrate/util"
+	"github.com/utkuozdemir/pv-migrate/webhook"
 )
 
 const (
@@ -21,9 +36,24 @@ const (
 
 type (
 	strategyMapGetter   func(names []string) (map[string]strategy.Strategy, error)
-	clusterClientGetter func(kubeconfigPath, context string, logger *slog.Logger) (*k8s.ClusterClient, error)
+	clusterClientGetter func(kubeconfigPath, context string, tlsOptions k8s.TLSOptions,
+		logger *slog.Logger) (*k8s.ClusterClient, error)
 )
 
+// preflightError wraps the errors of the checks done before running the strategies, e.g., a PVC which is not found,
+// which are not resolved by retrying the migration.
+type preflightError struct {
+	err error
+}
+
+func (e *preflightError) Error() string {
+	return e.err.Error()
+}
+
+func (e *preflightError) Unwrap() error {
+	return e.err
+}
+
 type Migrator struct {
 	getKubeClient  clusterClientGetter
 	getStrategyMap strategyMapGetter
@@ -38,17 +68,213 @@ func New() *Migrator {
 }
 
 func (m *Migrator) Run(ctx context.Context, request *migration.Request, logger *slog.Logger) error {
-	nameToStrategyMap, err := m.getStrategyMap(request.Strategies)
+	if request.ValidateOnly {
+		return m.validate(ctx, request, logger)
+	}
+
+	var (
+		notifier  *webhook.Notifier
+		recorder  *result.Recorder
+		reporters progress.Reporters
+	)
+
+	source := sourceName(request)
+	dest := request.Dest.Namespace + "/" + request.Dest.Name
+
+	if request.WebhookURL != "" {
+		notifier = webhook.New(request.WebhookURL, source, dest, logger)
+		reporters = append(reporters, notifier)
+
+		defer notifier.Close()
+	}
+
+	if request.ResultFile != "" {
+		recorder = result.New(request.ResultFile, source, dest)
+		reporters = append(reporters, recorder)
+	}
+
+	if len(reporters) > 0 {
+		ctx = context.WithValue(ctx, progress.ReporterContextKey{}, reporters)
+	}
+
+	if request.ETAInterval > 0 {
+		ctx = context.WithValue(ctx, progress.ETAIntervalContextKey{}, request.ETAInterval)
+	}
+
+	if request.Watch {
+		return m.runWatch(ctx, request, notifier, recorder, logger)
+	}
+
+	return m.runOnce(ctx, request, notifier, recorder, logger)
+}
+
+// sourceName returns the name of the source of the migration to be reported, in the form of "ns/name",
+// or "pv/name" when migrating from a PV.
+func sourceName(request *migration.Request) string {
+	if request.SourcePV != "" {
+		return "pv/" + request.SourcePV
+	}
+
+	return request.Source.Namespace + "/" + request.Source.Name
+}
+
+// validate runs the pre-flight checks of the migration without creating any resources.
+func (m *Migrator) validate(ctx context.Context, request *migration.Request, logger *slog.Logger) error {
+	if _, err := m.getStrategyMap(request.Strategies); err != nil {
+		return err
+	}
+
+	logger = logger.With("source", sourceName(request), "dest", request.Dest.Namespace+"/"+request.Dest.Name)
+
+	if request.SourcePV != "" {
+		// the checks of the PVCs need the temporary PVC bound to the source PV, which is not created
+		if err := m.validateSourcePV(ctx, request, logger); err != nil {
+			return err
+		}
+	} else if _, err := m.buildMigration(ctx, request, logger); err != nil {
+		return err
+	}
+
+	logger.Info("✅ Validation succeeded, exiting without migrating as --validate-only is set")
+
+	return nil
+}
+
+// runWatch runs the migration repeatedly with the sync interval in between, until it is interrupted.
+//
+// As rsync only transfers the changes, the iterations after the first one keep the destination up-to-date quickly.
+// An iteration failed by a transient error is retried in the next one, while a failure of the preflight checks,
+// e.g., a PVC which is not found, stops the watch.
+// If the watch is interrupted after a failed iteration, its error is returned.
+func (m *Migrator) runWatch(ctx context.Context, request *migration.Request,
+	notifier *webhook.Notifier, recorder *result.Recorder, logger *slog.Logger,
+) error {
+	logger.Info("👀 Watch mode is enabled, the data will be synced until interrupted",
+		"sync_interval", request.SyncInterval)
+
+	for iteration := 1; ; iteration++ {
+		iterationLogger := logger.With("iteration", iteration)
+
+		err := m.runOnce(ctx, request, notifier, recorder, iterationLogger)
+		if ctx.Err() != nil {
+			return stopWatch(err, logger)
+		}
+
+		var preflightErr *preflightError
+		if errors.As(err, &preflightErr) {
+			return err
+		}
+
+		if err != nil {
+			iterationLogger.Warn("🔶 Sync failed, will retry in the next iteration", "error", err)
+		}
+
+		iterationLogger.Info("⏳ Waiting for the next sync", "sync_interval", request.SyncInterval)
+
+		select {
+		case <-ctx.Done():
+			return stopWatch(err, logger)
+		case <-time.After(request.SyncInterval):
+		}
+	}
+}
+
+// stopWatch stops the watch mode on interrupt, returning the error of the last iteration if it failed.
+func stopWatch(lastErr error, logger *slog.Logger) error {
+	logger.Info("🛑 Received termination signal, stopping the watch mode")
+
+	if lastErr != nil {
+		return fmt.Errorf("watch mode interrupted after a failed sync: %w", lastErr)
+	}
+
+	return nil
+}
+
+func (m *Migrator) runOnce(ctx context.Context, request *migration.Request,
+	notifier *webhook.Notifier, recorder *result.Recorder, logger *slog.Logger,
+) error {
+	ctx, span := tracing.Start(ctx, "migration",
+		attribute.String("pv_migrate.source", sourceName(request)),
+		attribute.String("pv_migrate.dest", request.Dest.Namespace+"/"+request.Dest.Name))
+
+	notifier.Started(ctx)
+	recorder.Started()
+
+	err := m.runStrategies(ctx, request, notifier, recorder, logger)
+
+	if resultErr := recorder.Finished(err); resultErr != nil {
+		logger.Warn("🔶 Failed to write the result file", "path", request.ResultFile, "error", resultErr)
+	}
+
 	if err != nil {
+		notifier.Failed(ctx, err)
+		tracing.End(span, err)
+
 		return err
 	}
 
+	notifier.Completed(ctx)
+	tracing.End(span, nil)
+
+	return nil
+}
+
+func (m *Migrator) runStrategies(ctx context.Context, request *migration.Request,
+	notifier *webhook.Notifier, recorder *result.Recorder, logger *slog.Logger,
+) error {
+	nameToStrategyMap, err := m.getStrategyMap(request.Strategies)
+	if err != nil {
+		return &preflightError{err: err}
+	}
+
+	if request.SourcePV != "" {
+		sourceRequest, cleanup, sourcePVErr := m.useSourcePV(ctx, request, logger)
+		if sourcePVErr != nil {
+			return fmt.Errorf("failed to migrate from the source PV: %w", sourcePVErr)
+		}
+
+		defer cleanup()
+
+		request = sourceRequest
+	}
+
+	if request.ScaleDownDest {
+		restore, scaleErr := m.scaleDownDest(ctx, request, logger)
+		if scaleErr != nil {
+			return fmt.Errorf("failed to scale down the workloads using the destination PVC: %w", scaleErr)
+		}
+
+		defer restore()
+	}
+
 	logger = logger.With("source", request.Source.Namespace+"/"+request.Source.Name,
 		"dest", request.Dest.Namespace+"/"+request.Dest.Name)
 
-	mig, err := m.buildMigration(ctx, request, logger)
+	preflightCtx, preflightSpan := tracing.Start(ctx, "preflight")
+	mig, err := m.buildMigration(preflightCtx, request, logger)
+	tracing.End(preflightSpan, err)
+
 	if err != nil {
-		return err
+		return &preflightError{err: err}
+	}
+
+	if request.ChecksumManifest != "" && mig.SourceInfo.BlockMode {
+		return &preflightError{err: errors.New("checksum manifest is not supported for PVCs with the Block volume mode")}
+	}
+
+	if request.SourcePrepareCommand != "" {
+		if err = runSourcePrepareCommand(ctx, mig, logger); err != nil {
+			return err
+		}
+	}
+
+	if request.FromSnapshot {
+		cleanup, snapshotErr := useSourceSnapshot(ctx, mig, logger)
+		if snapshotErr != nil {
+			return fmt.Errorf("failed to migrate from a snapshot of the source PVC: %w", snapshotErr)
+		}
+
+		defer cleanup()
 	}
 
 	logger.Info("💭 Attempting migration", "strategies", strings.Join(request.Strategies, ","))
@@ -60,29 +286,70 @@ func (m *Migrator) Run(ctx context.Context, request *migration.Request, logger *
 
 		attemptLogger.Info("🚁 Attempt using strategy")
 
+		recorder.AttemptStarted(name, attemptID)
+
 		attempt := migration.Attempt{
 			ID:                    attemptID,
 			HelmReleaseNamePrefix: "pv-migrate-" + attemptID,
 			Migration:             mig,
+			// the strategy is only reported as selected once it accepts the migration
+			OnAccepted: func() { notifier.StrategySelected(ctx, name, attemptID) },
 		}
 
 		s := nameToStrategyMap[name]
 
-		if runErr := s.Run(ctx, &attempt, attemptLogger); runErr != nil {
-			if errors.Is(err, strategy.ErrUnaccepted) {
+		attemptCtx, attemptSpan := tracing.Start(ctx, "strategy",
+			attribute.String("pv_migrate.strategy", name), attribute.String("pv_migrate.attempt_id", attemptID))
+		runErr := s.Run(attemptCtx, &attempt, attemptLogger)
+		tracing.End(attemptSpan, runErr)
+
+		if runErr != nil {
+			if errors.Is(runErr, strategy.ErrUnaccepted) {
+				recorder.AttemptFinished(result.StatusUnaccepted, runErr)
 				attemptLogger.Info("🦊 This strategy cannot handle this migration, will try the next one")
 
 				continue
 			}
 
+			recorder.AttemptFinished(result.StatusFailed, runErr)
+
+			if ctx.Err() != nil {
+				return fmt.Errorf("migration interrupted: %w", runErr)
+			}
+
 			attemptLogger.Warn("🔶 Migration failed with this strategy, "+
 				"will try with the remaining strategies", "error", runErr)
 
 			continue
 		}
 
+		recorder.AttemptFinished(result.StatusSucceeded, nil)
 		attemptLogger.Info("✅ Migration succeeded")
 
+		if request.DestReclaimPolicy != "" {
+			if err = applyDestReclaimPolicy(ctx, mig, destBindTimeout, logger); err != nil {
+				return fmt.Errorf("failed to set the reclaim policy of the destination PV: %w", err)
+			}
+		}
+
+		if request.ChecksumManifest != "" {
+			if err = verifyChecksums(ctx, mig, logger); err != nil {
+				return fmt.Errorf("failed to verify the checksums of the destination: %w", err)
+			}
+		}
+
+		if request.PreserveSnapshotBase {
+			if err = snapshotDest(ctx, mig, true, logger); err != nil {
+				return fmt.Errorf("failed to take the base snapshot of the destination PVC: %w", err)
+			}
+		}
+
+		if request.SnapshotAfter {
+			if err = snapshotDest(ctx, mig, false, logger); err != nil {
+				return fmt.Errorf("failed to take a snapshot of the destination PVC: %w", err)
+			}
+		}
+
 		return nil
 	}
 
@@ -125,20 +392,86 @@ func (m *Migrator) buildMigration(ctx context.Context, request *migration.Reques
 		return nil, fmt.Errorf("failed to get PVC info for destination PVC: %w", err)
 	}
 
-	err = handleMountedPVCs(request, sourcePvcInfo, destPvcInfo, logger)
+	err = handleMountedPVCs(ctx, request, sourcePvcInfo, destPvcInfo, logger)
 	if err != nil {
 		return nil, err
 	}
 
+	if request.RespectTopology {
+		for _, info := range []*pvc.Info{sourcePvcInfo, destPvcInfo} {
+			if err = respectVolumeTopology(ctx, info, logger); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if !(destPvcInfo.SupportsRWO || destPvcInfo.SupportsRWX) {
 		return nil, errors.New("destination PVC is not writable")
 	}
 
+	if err = checkVolumeModes(sourcePvcInfo, destPvcInfo, logger); err != nil {
+		return nil, err
+	}
+
+	if err = checkFilesystems(ctx, sourcePvcInfo, destPvcInfo, request.StrictFS, logger); err != nil {
+		return nil, err
+	}
+
+	if err = checkExtraVolumes(ctx, destPvcInfo, request.ExtraVolumes); err != nil {
+		return nil, err
+	}
+
+	if request.IntermediateBucket != "" && slices.Contains(request.Strategies, strategy.ObjStoreStrategy) &&
+		sourceClient.RestConfig.Host != destClient.RestConfig.Host {
+		if err = checkIntermediateSecret(ctx, request, sourcePvcInfo, destPvcInfo); err != nil {
+			return nil, err
+		}
+	}
+
+	if request.RuntimeClass != "" {
+		if err = checkRuntimeClass(ctx, sourceClient, request.RuntimeClass, "source"); err != nil {
+			return nil, err
+		}
+
+		if destClient != sourceClient {
+			if err = checkRuntimeClass(ctx, destClient, request.RuntimeClass, "destination"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if namespace := request.CoordinationNamespace; namespace != "" {
+		if err = checkCoordinationNamespace(ctx, sourceClient, namespace, "source"); err != nil {
+			return nil, err
+		}
+
+		if destClient != sourceClient {
+			if err = checkCoordinationNamespace(ctx, destClient, namespace, "destination"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if request.SSHClusterIP != "" {
+		if err = checkSSHClusterIP(ctx, sourceClient, request.SSHClusterIP, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	var noCommonNode bool
+
+	if request.CheckTopology {
+		if noCommonNode, err = checkTopology(ctx, request, sourcePvcInfo, destPvcInfo, logger); err != nil {
+			return nil, err
+		}
+	}
+
 	mig := migration.Migration{
-		Chart:      chart,
-		Request:    request,
-		SourceInfo: sourcePvcInfo,
-		DestInfo:   destPvcInfo,
+		Chart:        chart,
+		Request:      request,
+		SourceInfo:   sourcePvcInfo,
+		DestInfo:     destPvcInfo,
+		NoCommonNode: noCommonNode,
 	}
 
 	return &mig, nil
@@ -150,31 +483,313 @@ func (m *Migrator) getClusterClients(r *migration.Request,
 	source := r.Source
 	dest := r.Dest
 
-	sourceClient, err := m.getKubeClient(source.KubeconfigPath, source.Context, logger)
+	sourceTLSOptions := buildTLSOptions(source, logger.With("side", "source"))
+	destTLSOptions := buildTLSOptions(dest, logger.With("side", "dest"))
+
+	sourceClient, err := m.getPVCClusterClient(source, sourceTLSOptions, logger)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	destClient := sourceClient
-	if source.KubeconfigPath != dest.KubeconfigPath || source.Context != dest.Context {
-		destClient, err = m.getKubeClient(dest.KubeconfigPath, dest.Context, logger)
+	if source.KubeconfigPath != dest.KubeconfigPath || !bytes.Equal(source.Kubeconfig, dest.Kubeconfig) ||
+		source.Context != dest.Context || sourceTLSOptions != destTLSOptions {
+		destClient, err = m.getPVCClusterClient(dest, destTLSOptions, logger)
 		if err != nil {
 			return nil, nil, err
 		}
 	}
 
+	if err = checkClusterReachable(sourceClient, "source"); err != nil {
+		return nil, nil, err
+	}
+
+	if destClient != sourceClient {
+		if err = checkClusterReachable(destClient, "destination"); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	return sourceClient, destClient, nil
 }
 
-func handleMountedPVCs(r *migration.Request, sourcePvcInfo, destPvcInfo *pvc.Info, logger *slog.Logger) error {
-	ignoreMounted := r.IgnoreMounted
+// getPVCClusterClient returns the client of the cluster of the PVC, built from the content of its kubeconfig
+// if it is given, e.g., when read from a secret, or from the kubeconfig file otherwise.
+func (m *Migrator) getPVCClusterClient(info *migration.PVCInfo, tlsOptions k8s.TLSOptions,
+	logger *slog.Logger,
+) (*k8s.ClusterClient, error) {
+	if info.Kubeconfig != nil {
+		return k8s.GetClusterClientFromKubeconfig(info.Kubeconfig, info.Context, tlsOptions, logger) //nolint:wrapcheck
+	}
+
+	return m.getKubeClient(info.KubeconfigPath, info.Context, tlsOptions, logger)
+}
+
+// checkClusterReachable checks that the API server of the cluster is reachable before the migration starts.
+func checkClusterReachable(client *k8s.ClusterClient, side string) error {
+	if _, err := client.KubeClient.Discovery().ServerVersion(); err != nil {
+		return fmt.Errorf("API server of the %s cluster is not reachable: %w", side, err)
+	}
+
+	return nil
+}
+
+func buildTLSOptions(info *migration.PVCInfo, logger *slog.Logger) k8s.TLSOptions {
+	if info.InsecureSkipTLSVerify {
+		logger.Warn("⚠️ TLS certificate verification of the Kubernetes API server is disabled, " +
+			"the connection is insecure and might be subject to man-in-the-middle attacks")
+	}
+
+	return k8s.TLSOptions{
+		InsecureSkipTLSVerify: info.InsecureSkipTLSVerify,
+		CAFile:                info.CAFile,
+	}
+}
+
+// checkVolumeModes checks that the PVCs have the same volume mode, and that the destination block device
+// is not smaller than the source. The size check is skipped with a warning if a size cannot be determined.
+func checkVolumeModes(sourcePvcInfo, destPvcInfo *pvc.Info, logger *slog.Logger) error {
+	if sourcePvcInfo.BlockMode != destPvcInfo.BlockMode {
+		return fmt.Errorf("cannot migrate between PVCs with different volume modes: source: %s, destination: %s",
+			volumeModeName(sourcePvcInfo), volumeModeName(destPvcInfo))
+	}
+
+	if !sourcePvcInfo.BlockMode {
+		return nil
+	}
+
+	sourceCapacity, sourceOK := claimSize(sourcePvcInfo.Claim)
+	destCapacity, destOK := claimSize(destPvcInfo.Claim)
+
+	if !sourceOK || !destOK {
+		logger.Warn("🔶 Cannot determine the size of the block devices, skipping the size check",
+			"source", sourceCapacity.String(), "dest", destCapacity.String())
+
+		return nil
+	}
+
+	if destCapacity.Cmp(sourceCapacity) < 0 {
+		return fmt.Errorf("destination block device is smaller than the source: source: %s, destination: %s",
+			sourceCapacity.String(), destCapacity.String())
+	}
+
+	return nil
+}
+
+// claimSize returns the size of the PVC, falling back to the requested size if it is not bound yet,
+// e.g., when it waits for its first consumer. It returns false if neither is known.
+func claimSize(claim *corev1.PersistentVolumeClaim) (resource.Quantity, bool) {
+	size, ok := claim.Status.Capacity[corev1.ResourceStorage]
+	if !ok || size.IsZero() {
+		size = claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	}
+
+	return size, !size.IsZero()
+}
+
+// checkExtraVolumes checks that the ConfigMaps and Secrets to be mounted into the rsync pod exist.
+// The rsync pod always runs in the namespace of the destination PVC.
+func checkExtraVolumes(ctx context.Context, destInfo *pvc.Info, volumes []migration.ExtraVolume) error {
+	cli := destInfo.ClusterClient.KubeClient
+	namespace := destInfo.Claim.Namespace
+
+	for _, volume := range volumes {
+		var err error
+
+		switch volume.Kind {
+		case migration.ExtraVolumeKindSecret:
+			_, err = cli.CoreV1().Secrets(namespace).Get(ctx, volume.Name, metav1.GetOptions{})
+		default:
+			_, err = cli.CoreV1().ConfigMaps(namespace).Get(ctx, volume.Name, metav1.GetOptions{})
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to get %s %s/%s to mount as an extra volume: %w",
+				volume.Kind, namespace, volume.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// checkIntermediateSecret checks that the credentials of the object store exist in the namespaces of the PVCs
+// of the phases of the objstore strategy to run, as their pods would otherwise hang in ContainerCreating
+// until the timeout.
+func checkIntermediateSecret(ctx context.Context, request *migration.Request, sourceInfo, destInfo *pvc.Info) error {
+	var infos []*pvc.Info
+
+	if request.IntermediatePhase != strategy.ObjStorePhaseDownload {
+		infos = append(infos, sourceInfo)
+	}
+
+	if request.IntermediatePhase != strategy.ObjStorePhaseUpload {
+		infos = append(infos, destInfo)
+	}
+
+	for _, info := range infos {
+		if _, err := k8s.GetSecretKey(ctx, info.ClusterClient.KubeClient, info.Claim.Namespace,
+			request.IntermediateSecret, strategy.IntermediateCredentialsKey); err != nil {
+			return fmt.Errorf("invalid credentials of the object store: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// checkRuntimeClass checks that the RuntimeClass to run the migration pods with exists in the cluster,
+// as the pods would otherwise be rejected only after the Helm releases are installed.
+func checkRuntimeClass(ctx context.Context, client *k8s.ClusterClient, name, side string) error {
+	if _, err := client.KubeClient.NodeV1().RuntimeClasses().Get(ctx, name, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("failed to get RuntimeClass %s in the %s cluster: %w", name, side, err)
+	}
+
+	return nil
+}
+
+// checkCoordinationNamespace checks that the Helm releases of the migration can be stored in the coordination
+// namespace of the cluster, i.e., that the user is allowed to manage the objects of the Helm storage driver in it.
+// Otherwise, the migration would only fail on the installation of the first release.
+func checkCoordinationNamespace(ctx context.Context, client *k8s.ClusterClient, namespace, side string) error {
+	resource := helmStorageResource(os.Getenv("HELM_DRIVER"))
+	if resource == "" {
+		return nil
+	}
+
+	var denied []string
+
+	for _, verb := range []string{"get", "list", "create", "update", "delete"} {
+		review, err := client.KubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx,
+			&authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace: namespace,
+						Verb:      verb,
+						Resource:  resource,
+					},
+				},
+			}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to review the access to %s in the coordination namespace %s "+
+				"in the %s cluster: %w", resource, namespace, side, err)
+		}
+
+		if !review.Status.Allowed {
+			denied = append(denied, verb)
+		}
+	}
+
+	if len(denied) > 0 {
+		return fmt.Errorf("not allowed to %s %s in the coordination namespace %s in the %s cluster",
+			strings.Join(denied, ", "), resource, namespace, side)
+	}
+
+	return nil
+}
+
+// helmStorageResource returns the resource the Helm releases are stored as with the given storage driver,
+// or an empty string if they are not stored in the cluster as namespaced objects, e.g., with the SQL driver.
+func helmStorageResource(driver string) string {
+	switch driver {
+	case "", "secret", "secrets":
+		return "secrets"
+	case "configmap", "configmaps":
+		return "configmaps"
+	default:
+		return ""
+	}
+}
+
+// checkSSHClusterIP checks that the fixed cluster IP of the SSH service is in one of the service CIDRs
+// of the source cluster, where the service is created.
+//
+// The check is skipped if the service CIDRs cannot be listed, e.g., on the clusters without the ServiceCIDR API.
+// An IP out of the range is then only rejected when the service is created.
+func checkSSHClusterIP(ctx context.Context, client *k8s.ClusterClient, ip string, logger *slog.Logger) error {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return fmt.Errorf("failed to parse the cluster IP %s of the SSH service: %w", ip, err)
+	}
+
+	list, err := client.KubeClient.NetworkingV1beta1().ServiceCIDRs().List(ctx, metav1.ListOptions{})
+	if err != nil || len(list.Items) == 0 {
+		logger.Debug("skipping the check of the cluster IP of the SSH service, "+
+			"the service CIDRs cannot be determined", "error", err)
+
+		return nil
+	}
 
-	err := handleMounted(sourcePvcInfo, ignoreMounted, logger)
+	var cidrs []string
+
+	for _, serviceCIDR := range list.Items {
+		for _, cidr := range serviceCIDR.Spec.CIDRs {
+			if prefix, parseErr := netip.ParsePrefix(cidr); parseErr == nil && prefix.Contains(addr) {
+				return nil
+			}
+
+			cidrs = append(cidrs, cidr)
+		}
+	}
+
+	return fmt.Errorf("the cluster IP %s of the SSH service is not in the service CIDRs of the source cluster: %s",
+		ip, strings.Join(cidrs, ", "))
+}
+
+func volumeModeName(info *pvc.Info) corev1.PersistentVolumeMode {
+	if info.BlockMode {
+		return corev1.PersistentVolumeBlock
+	}
+
+	return corev1.PersistentVolumeFilesystem
+}
+
+func respectVolumeTopology(ctx context.Context, info *pvc.Info, logger *slog.Logger) error {
+	terms, err := info.RespectVolumeTopology(ctx)
 	if err != nil {
+		return fmt.Errorf("failed to determine the topology of PVC %s/%s: %w",
+			info.Claim.Namespace, info.Claim.Name, err)
+	}
+
+	if len(terms) == 0 {
+		return nil
+	}
+
+	logger.Info("🗺️ Detected volume topology constraints", "pvc", info.Claim.Namespace+"/"+info.Claim.Name,
+		"constraints", pvc.FormatNodeSelectorTerms(terms))
+
+	return nil
+}
+
+func handleMountedPVCs(ctx context.Context, r *migration.Request, sourcePvcInfo, destPvcInfo *pvc.Info,
+	logger *slog.Logger,
+) error {
+	ignoreMounted := r.IgnoreMounted
+
+	// when migrating from a snapshot, the source PVC itself is not mounted by the migration
+	if !r.FromSnapshot {
+		if err := handleMounted(sourcePvcInfo, ignoreMounted, logger); err != nil {
+			return err
+		}
+	}
+
+	// the workloads using the destination PVC are only scaled down when migrating, not when validating
+	if r.ScaleDownDest && destPvcInfo.MountedNode != "" {
+		workloads, err := findDestWorkloads(ctx, destPvcInfo.ClusterClient.KubeClient,
+			destPvcInfo.Claim.Namespace, destPvcInfo.Claim.Name)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("💡 Destination PVC is mounted, the workloads using it will be scaled down during the migration",
+			"workloads", workloads)
+
+		return nil
+	}
+
+	if err := checkDestAttachment(ctx, destPvcInfo, ignoreMounted); err != nil {
 		return err
 	}
 
-	err = handleMounted(destPvcInfo, ignoreMounted, logger)
+	err := handleMounted(destPvcInfo, ignoreMounted, logger)
 	if err != nil {
 		return err
 	}
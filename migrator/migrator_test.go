@@ -2,20 +2,39 @@ package migrator
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/neilotoole/slogt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	nodev1 "k8s.io/api/node/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
 
 	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
 	"github.com/utkuozdemir/pv-migrate/strategy"
+	"github.com/utkuozdemir/pv-migrate/webhook"
 )
 
 const (
@@ -127,6 +146,44 @@ func TestRunStrategiesInOrder(t *testing.T) {
 	assert.Equal(t, []int{3, 1, 2}, result)
 }
 
+func TestCheckVolumeModes(t *testing.T) {
+	t.Parallel()
+
+	logger := slogt.New(t)
+	fsInfo := &pvc.Info{Claim: buildTestPVC(sourceNS, sourcePVC, corev1.ReadWriteOnce)}
+
+	smallBlockInfo := buildTestBlockPVCInfo("1Gi")
+	largeBlockInfo := buildTestBlockPVCInfo("2Gi")
+
+	require.NoError(t, checkVolumeModes(fsInfo, fsInfo, logger))
+	require.NoError(t, checkVolumeModes(smallBlockInfo, largeBlockInfo, logger))
+	require.NoError(t, checkVolumeModes(largeBlockInfo, largeBlockInfo, logger))
+	require.ErrorContains(t, checkVolumeModes(largeBlockInfo, smallBlockInfo, logger), "smaller")
+	require.ErrorContains(t, checkVolumeModes(fsInfo, smallBlockInfo, logger), "different volume modes")
+	require.ErrorContains(t, checkVolumeModes(smallBlockInfo, fsInfo, logger), "different volume modes")
+
+	pendingBlockInfo := buildTestBlockPVCInfo("1Gi")
+	pendingBlockInfo.Claim.Status.Capacity = nil
+	pendingBlockInfo.Claim.Spec.Resources.Requests = nil
+
+	require.NoError(t, checkVolumeModes(largeBlockInfo, pendingBlockInfo, logger))
+
+	pendingBlockInfo.Claim.Spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("1Gi"),
+	}
+
+	require.ErrorContains(t, checkVolumeModes(largeBlockInfo, pendingBlockInfo, logger), "smaller")
+}
+
+func buildTestBlockPVCInfo(capacity string) *pvc.Info {
+	claim := buildTestPVC(sourceNS, sourcePVC, corev1.ReadWriteOnce)
+	claim.Status.Capacity = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse(capacity),
+	}
+
+	return &pvc.Info{Claim: claim, BlockMode: true}
+}
+
 func buildMigration(ignoreMounted bool) *migration.Request {
 	return buildMigrationRequestWithStrategies(strategy.DefaultStrategies, ignoreMounted)
 }
@@ -152,7 +209,7 @@ func fakeClusterClientGetter() clusterClientGetter {
 	podA := buildTestPod(sourceNS, sourcePod, sourceNode, sourcePVC)
 	podB := buildTestPod(destNS, destPod, destNode, destPVC)
 
-	return func(string, string, *slog.Logger) (*k8s.ClusterClient, error) {
+	return func(string, string, k8s.TLSOptions, *slog.Logger) (*k8s.ClusterClient, error) {
 		return &k8s.ClusterClient{
 			KubeClient: fake.NewSimpleClientset(pvcA, pvcB, podA, podB),
 		}, nil
@@ -204,3 +261,927 @@ type mockStrategy struct {
 func (m *mockStrategy) Run(ctx context.Context, attempt *migration.Attempt, _ *slog.Logger) error {
 	return m.runFunc(ctx, attempt)
 }
+
+func TestRunStrategySelectedOnAccept(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var (
+		lock   sync.Mutex
+		events []webhook.Event
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		var event webhook.Event
+
+		if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+			lock.Lock()
+			events = append(events, event)
+			lock.Unlock()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	unaccepted := mockStrategy{
+		runFunc: func(context.Context, *migration.Attempt) error {
+			return strategy.ErrUnaccepted
+		},
+	}
+
+	accepted := mockStrategy{
+		runFunc: func(_ context.Context, attempt *migration.Attempt) error {
+			attempt.Accept()
+
+			return nil
+		},
+	}
+
+	migrator := Migrator{
+		getKubeClient: fakeClusterClientGetter(),
+		getStrategyMap: func([]string) (map[string]strategy.Strategy, error) {
+			return map[string]strategy.Strategy{"unaccepted": &unaccepted, "accepted": &accepted}, nil
+		},
+	}
+
+	request := buildMigrationRequestWithStrategies([]string{"unaccepted", "accepted"}, true)
+	request.WebhookURL = server.URL
+
+	require.NoError(t, migrator.Run(ctx, request, slogt.New(t)))
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	require.Len(t, events, 3)
+	assert.Equal(t, webhook.EventStarted, events[0].Type)
+	assert.Equal(t, webhook.EventStrategySelected, events[1].Type)
+	assert.Equal(t, "accepted", events[1].Strategy, "only the strategy accepting the migration must be reported")
+	assert.Equal(t, webhook.EventCompleted, events[2].Type)
+}
+
+func TestRunWatch(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	logger := slogt.New(t)
+
+	runs := 0
+
+	str := mockStrategy{
+		runFunc: func(_ context.Context, _ *migration.Attempt) error {
+			runs++
+
+			if runs == 3 {
+				cancel()
+			}
+
+			if runs == 2 {
+				return errors.New("test error")
+			}
+
+			return nil
+		},
+	}
+
+	migrator := Migrator{
+		getKubeClient: fakeClusterClientGetter(),
+		getStrategyMap: func([]string) (map[string]strategy.Strategy, error) {
+			return map[string]strategy.Strategy{"str": &str}, nil
+		},
+	}
+
+	request := buildMigrationRequestWithStrategies([]string{"str"}, true)
+	request.Watch = true
+	request.SyncInterval = time.Millisecond
+
+	err := migrator.Run(ctx, request, logger)
+	require.NoError(t, err, "cancelling the context stops the watch mode")
+	assert.Equal(t, 3, runs)
+}
+
+func TestRunWatchStopsOnPreflightError(t *testing.T) {
+	t.Parallel()
+
+	logger := slogt.New(t)
+
+	str := mockStrategy{
+		runFunc: func(_ context.Context, _ *migration.Attempt) error {
+			t.Fatal("the strategy must not run when the source PVC is not found")
+
+			return nil
+		},
+	}
+
+	migrator := Migrator{
+		getKubeClient: fakeClusterClientGetter(),
+		getStrategyMap: func([]string) (map[string]strategy.Strategy, error) {
+			return map[string]strategy.Strategy{"str": &str}, nil
+		},
+	}
+
+	request := buildMigrationRequestWithStrategies([]string{"str"}, true)
+	request.Source.Name = "nonexistent"
+	request.Watch = true
+	request.SyncInterval = time.Millisecond
+
+	err := migrator.Run(context.Background(), request, logger)
+	require.Error(t, err, "the watch mode is stopped as retrying does not find the source PVC")
+}
+
+func TestRunWatchInterruptedAfterFailure(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	logger := slogt.New(t)
+
+	runs := 0
+
+	str := mockStrategy{
+		runFunc: func(_ context.Context, _ *migration.Attempt) error {
+			runs++
+
+			if runs == 2 {
+				cancel()
+
+				return errors.New("test error")
+			}
+
+			return nil
+		},
+	}
+
+	migrator := Migrator{
+		getKubeClient: fakeClusterClientGetter(),
+		getStrategyMap: func([]string) (map[string]strategy.Strategy, error) {
+			return map[string]strategy.Strategy{"str": &str}, nil
+		},
+	}
+
+	request := buildMigrationRequestWithStrategies([]string{"str"}, true)
+	request.Watch = true
+	request.SyncInterval = time.Millisecond
+
+	err := migrator.Run(ctx, request, logger)
+	require.Error(t, err, "the failure of the last sync is returned when the watch mode is interrupted")
+	assert.Equal(t, 2, runs)
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	logger := slogt.New(t)
+
+	str1 := mockStrategy{
+		runFunc: func(ctx context.Context, _ *migration.Attempt) error {
+			cancel()
+
+			return ctx.Err()
+		},
+	}
+
+	str2 := mockStrategy{
+		runFunc: func(_ context.Context, _ *migration.Attempt) error {
+			t.Fatal("the remaining strategies must not run after the migration is interrupted")
+
+			return nil
+		},
+	}
+
+	migrator := Migrator{
+		getKubeClient: fakeClusterClientGetter(),
+		getStrategyMap: func([]string) (map[string]strategy.Strategy, error) {
+			return map[string]strategy.Strategy{"str1": &str1, "str2": &str2}, nil
+		},
+	}
+
+	request := buildMigrationRequestWithStrategies([]string{"str1", "str2"}, true)
+
+	require.ErrorIs(t, migrator.Run(ctx, request, logger), context.Canceled)
+}
+
+func TestRunValidateOnly(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	logger := slogt.New(t)
+
+	str := mockStrategy{
+		runFunc: func(_ context.Context, _ *migration.Attempt) error {
+			t.Fatal("strategy must not run in validate-only mode")
+
+			return nil
+		},
+	}
+
+	migrator := Migrator{
+		getKubeClient: fakeClusterClientGetter(),
+		getStrategyMap: func([]string) (map[string]strategy.Strategy, error) {
+			return map[string]strategy.Strategy{"str": &str}, nil
+		},
+	}
+
+	request := buildMigrationRequestWithStrategies([]string{"str"}, true)
+	request.ValidateOnly = true
+
+	require.NoError(t, migrator.Run(ctx, request, logger))
+
+	request = buildMigrationRequestWithStrategies([]string{"str"}, false)
+	request.ValidateOnly = true
+
+	require.Error(t, migrator.Run(ctx, request, logger))
+}
+
+func TestCreatePVCFromSnapshot(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	storageClass := "csi-storage"
+	source := buildTestPVC(sourceNS, sourcePVC, corev1.ReadWriteOnce)
+	source.Spec.StorageClassName = &storageClass
+	source.Status.Capacity = corev1.ResourceList{
+		corev1.ResourceStorage: resource.MustParse("1Gi"),
+	}
+
+	cli := fake.NewSimpleClientset(source)
+
+	require.NoError(t, createPVCFromSnapshot(ctx, cli, source, "snap", map[string]string{"a": "b"}))
+
+	claim, err := cli.CoreV1().PersistentVolumeClaims(sourceNS).Get(ctx, "snap", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "b", claim.Labels["a"])
+	assert.Equal(t, &storageClass, claim.Spec.StorageClassName)
+	assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, claim.Spec.AccessModes)
+	assert.Equal(t, "1Gi", claim.Spec.Resources.Requests.Storage().String())
+	require.NotNil(t, claim.Spec.DataSource)
+	assert.Equal(t, k8s.SnapshotKind, claim.Spec.DataSource.Kind)
+	assert.Equal(t, "snap", claim.Spec.DataSource.Name)
+}
+
+func TestUseSourceSnapshotCleansUp(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		prepare func(cancel context.CancelFunc, kubeClient *fake.Clientset, dynamicClient *dynamicfake.FakeDynamicClient)
+	}{
+		{
+			name: "interrupted",
+			prepare: func(cancel context.CancelFunc, _ *fake.Clientset, dynamicClient *dynamicfake.FakeDynamicClient) {
+				dynamicClient.PrependReactor("get", "volumesnapshots",
+					func(k8stesting.Action) (bool, runtime.Object, error) {
+						cancel()
+
+						return false, nil, nil
+					})
+			},
+		},
+		{
+			name: "create failed",
+			prepare: func(_ context.CancelFunc, kubeClient *fake.Clientset, dynamicClient *dynamicfake.FakeDynamicClient) {
+				dynamicClient.PrependReactor("get", "volumesnapshots",
+					func(k8stesting.Action) (bool, runtime.Object, error) {
+						return true, &unstructured.Unstructured{Object: map[string]any{
+							"status": map[string]any{"readyToUse": true},
+						}}, nil
+					})
+				kubeClient.PrependReactor("create", "persistentvolumeclaims",
+					func(k8stesting.Action) (bool, runtime.Object, error) {
+						return true, nil, errors.New("quota exceeded")
+					})
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			kubeClient := fake.NewSimpleClientset()
+			dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+			tc.prepare(cancel, kubeClient, dynamicClient)
+
+			mig := migration.Migration{
+				Request: &migration.Request{SnapshotClass: "class1"},
+				SourceInfo: &pvc.Info{
+					Claim:         buildTestPVC(sourceNS, sourcePVC, corev1.ReadWriteOnce),
+					ClusterClient: &k8s.ClusterClient{KubeClient: kubeClient, DynamicClient: dynamicClient},
+				},
+			}
+
+			_, err := useSourceSnapshot(ctx, &mig, slogt.New(t))
+			require.Error(t, err)
+
+			var created, deleted []string
+
+			for _, action := range dynamicClient.Actions() {
+				switch action := action.(type) {
+				case k8stesting.CreateAction:
+					if object, ok := action.GetObject().(*unstructured.Unstructured); ok {
+						created = append(created, object.GetName())
+					}
+				case k8stesting.DeleteAction:
+					deleted = append(deleted, action.GetName())
+				}
+			}
+
+			require.Len(t, created, 1)
+			assert.Equal(t, created, deleted, "the snapshot must be deleted")
+
+			claims, err := kubeClient.CoreV1().PersistentVolumeClaims(sourceNS).List(context.Background(),
+				metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, claims.Items, "the temporary PVC must be deleted")
+		})
+	}
+}
+
+func TestSnapshotDestSkipped(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	mig := migration.Migration{
+		Request:  &migration.Request{SnapshotAfter: true},
+		DestInfo: &pvc.Info{Claim: buildTestPVC(destNS, destPVC, corev1.ReadWriteOnce)},
+	}
+
+	require.NoError(t, snapshotDest(ctx, &mig, false, slogt.New(t)))
+}
+
+func TestSnapshotDestBaseExists(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	base := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "snapshot.storage.k8s.io/v1",
+		"kind":       k8s.SnapshotKind,
+		"metadata":   map[string]any{"name": destPVC + "-pv-migrate-base", "namespace": destNS},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), base)
+
+	mig := migration.Migration{
+		Request: &migration.Request{PreserveSnapshotBase: true, SnapshotAfterClass: "class1"},
+		DestInfo: &pvc.Info{
+			Claim:         buildTestPVC(destNS, destPVC, corev1.ReadWriteOnce),
+			ClusterClient: &k8s.ClusterClient{DynamicClient: dynamicClient},
+		},
+	}
+
+	require.NoError(t, snapshotDest(ctx, &mig, true, slogt.New(t)))
+
+	for _, action := range dynamicClient.Actions() {
+		assert.NotEqual(t, "create", action.GetVerb())
+	}
+}
+
+func TestApplyDestReclaimPolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv1"},
+		Spec:       corev1.PersistentVolumeSpec{PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete},
+	}
+
+	bound := buildTestPVC(destNS, destPVC, corev1.ReadWriteOnce)
+	bound.Spec.VolumeName = pv.Name
+
+	cli := fake.NewSimpleClientset(pv, bound)
+
+	reclaimPolicy := func() corev1.PersistentVolumeReclaimPolicy {
+		current, err := cli.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+		require.NoError(t, err)
+
+		return current.Spec.PersistentVolumeReclaimPolicy
+	}
+
+	mig := migration.Migration{
+		Request:  &migration.Request{DestReclaimPolicy: "Retain"},
+		DestInfo: &pvc.Info{Claim: bound, ClusterClient: &k8s.ClusterClient{KubeClient: cli}},
+	}
+
+	require.NoError(t, applyDestReclaimPolicy(ctx, &mig, destBindTimeout, slogt.New(t)))
+	assert.Equal(t, corev1.PersistentVolumeReclaimDelete, reclaimPolicy(), "the PV was bound before the migration")
+
+	mig.DestInfo.Claim = buildTestPVC(destNS, destPVC, corev1.ReadWriteOnce)
+
+	require.NoError(t, applyDestReclaimPolicy(ctx, &mig, destBindTimeout, slogt.New(t)))
+	assert.Equal(t, corev1.PersistentVolumeReclaimRetain, reclaimPolicy())
+}
+
+func TestApplyDestReclaimPolicyPending(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	pending := buildTestPVC(destNS, destPVC, corev1.ReadWriteOnce)
+	pending.Status.Phase = corev1.ClaimPending
+
+	cli := fake.NewSimpleClientset(pending)
+
+	mig := migration.Migration{
+		Request:  &migration.Request{DestReclaimPolicy: "Retain"},
+		DestInfo: &pvc.Info{Claim: pending, ClusterClient: &k8s.ClusterClient{KubeClient: cli}},
+	}
+
+	require.NoError(t, applyDestReclaimPolicy(ctx, &mig, 10*time.Millisecond, slogt.New(t)),
+		"the migration must not fail when the destination PVC is not bound yet")
+
+	for _, action := range cli.Actions() {
+		assert.NotEqual(t, "patch", action.GetVerb())
+	}
+}
+
+func TestCheckSourcePV(t *testing.T) {
+	t.Parallel()
+
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv1"},
+		Spec:       corev1.PersistentVolumeSpec{PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain},
+		Status:     corev1.PersistentVolumeStatus{Phase: corev1.VolumeReleased},
+	}
+	require.NoError(t, checkSourcePV(pv))
+
+	pv.Status.Phase = corev1.VolumeBound
+	require.Error(t, checkSourcePV(pv))
+
+	pv.Status.Phase = corev1.VolumeAvailable
+	pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimDelete
+	require.Error(t, checkSourcePV(pv))
+}
+
+func TestCreatePVCForPV(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv1"},
+		Spec: corev1.PersistentVolumeSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Capacity:    corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			ClaimRef:    &corev1.ObjectReference{Namespace: sourceNS, Name: "deleted", UID: "uid"},
+		},
+	}
+
+	cli := fake.NewSimpleClientset(pv)
+
+	require.NoError(t, bindPVToClaim(ctx, cli, pv, sourceNS, "tmp"))
+
+	patched, err := cli.CoreV1().PersistentVolumes().Get(ctx, "pv1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "tmp", patched.Spec.ClaimRef.Name)
+	assert.Empty(t, patched.Spec.ClaimRef.UID)
+
+	claim, err := createPVCForPV(ctx, cli, pv, sourceNS, "tmp")
+	require.NoError(t, err)
+
+	assert.Equal(t, "pv1", claim.Spec.VolumeName)
+	assert.Equal(t, "", *claim.Spec.StorageClassName)
+	assert.Equal(t, "1Gi", claim.Spec.Resources.Requests.Storage().String())
+}
+
+func TestUseSourcePVRestoresClaimRef(t *testing.T) {
+	t.Parallel()
+
+	claimRef := &corev1.ObjectReference{
+		APIVersion: "v1", Kind: "PersistentVolumeClaim",
+		Namespace: sourceNS, Name: "deleted", UID: "uid", ResourceVersion: "1",
+	}
+
+	for _, tc := range []struct {
+		name     string
+		reaction func(cancel context.CancelFunc) k8stesting.ReactionFunc
+	}{
+		{
+			name: "create failed",
+			reaction: func(context.CancelFunc) k8stesting.ReactionFunc {
+				return func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("quota exceeded")
+				}
+			},
+		},
+		{
+			name: "interrupted",
+			reaction: func(cancel context.CancelFunc) k8stesting.ReactionFunc {
+				return func(k8stesting.Action) (bool, runtime.Object, error) {
+					cancel()
+
+					return false, nil, nil
+				}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+
+			pv := &corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv1"},
+				Spec: corev1.PersistentVolumeSpec{
+					ClaimRef:                      claimRef.DeepCopy(),
+					PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+				},
+				Status: corev1.PersistentVolumeStatus{Phase: corev1.VolumeReleased},
+			}
+
+			cli := fake.NewSimpleClientset(pv)
+			cli.PrependReactor("create", "persistentvolumeclaims", tc.reaction(cancel))
+
+			migrator := Migrator{
+				getKubeClient: func(string, string, k8s.TLSOptions, *slog.Logger) (*k8s.ClusterClient, error) {
+					return &k8s.ClusterClient{KubeClient: cli}, nil
+				},
+			}
+
+			request := buildMigrationRequestWithStrategies(nil, true)
+			request.SourcePV = "pv1"
+
+			_, _, err := migrator.useSourcePV(ctx, request, slogt.New(t))
+			require.Error(t, err)
+
+			restored, err := cli.CoreV1().PersistentVolumes().Get(context.Background(), "pv1", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, claimRef, restored.Spec.ClaimRef)
+
+			claims, err := cli.CoreV1().PersistentVolumeClaims(sourceNS).List(context.Background(),
+				metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, claims.Items, "the temporary PVC must be deleted")
+		})
+	}
+}
+
+func TestCheckExtraVolumes(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: destNS, Name: "filters"}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: destNS, Name: "credentials"}}
+
+	info := &pvc.Info{
+		ClusterClient: &k8s.ClusterClient{KubeClient: fake.NewSimpleClientset(configMap, secret)},
+		Claim:         buildTestPVC(destNS, destPVC, corev1.ReadWriteOnce),
+	}
+
+	require.NoError(t, checkExtraVolumes(ctx, info, []migration.ExtraVolume{
+		{Kind: migration.ExtraVolumeKindConfigMap, Name: "filters", MountPath: "/etc/filters"},
+		{Kind: migration.ExtraVolumeKindSecret, Name: "credentials", MountPath: "/etc/credentials"},
+	}))
+
+	require.Error(t, checkExtraVolumes(ctx, info, []migration.ExtraVolume{
+		{Kind: migration.ExtraVolumeKindSecret, Name: "filters", MountPath: "/etc/filters"},
+	}))
+}
+
+func TestCheckIntermediateSecret(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: sourceNS, Name: "rclone"},
+		Data:       map[string][]byte{strategy.IntermediateCredentialsKey: []byte("[intermediate]")},
+	}
+	noKeySecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: destNS, Name: "rclone"}}
+
+	sourceInfo := &pvc.Info{
+		ClusterClient: &k8s.ClusterClient{KubeClient: fake.NewSimpleClientset(secret)},
+		Claim:         buildTestPVC(sourceNS, sourcePVC, corev1.ReadWriteOnce),
+	}
+	destInfo := &pvc.Info{
+		ClusterClient: &k8s.ClusterClient{KubeClient: fake.NewSimpleClientset(noKeySecret)},
+		Claim:         buildTestPVC(destNS, destPVC, corev1.ReadWriteOnce),
+	}
+
+	request := &migration.Request{IntermediateSecret: "rclone", IntermediatePhase: strategy.ObjStorePhaseUpload}
+	require.NoError(t, checkIntermediateSecret(ctx, request, sourceInfo, destInfo))
+
+	request.IntermediatePhase = strategy.ObjStorePhaseAll
+	require.ErrorContains(t, checkIntermediateSecret(ctx, request, sourceInfo, destInfo), "has no key rclone.conf")
+
+	request.IntermediateSecret = "missing"
+	request.IntermediatePhase = strategy.ObjStorePhaseUpload
+	require.Error(t, checkIntermediateSecret(ctx, request, sourceInfo, destInfo))
+}
+
+func TestCheckRuntimeClass(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	runtimeClass := nodev1.RuntimeClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "gvisor"},
+		Handler:    "runsc",
+	}
+
+	client := &k8s.ClusterClient{KubeClient: fake.NewSimpleClientset(&runtimeClass)}
+
+	require.NoError(t, checkRuntimeClass(ctx, client, "gvisor", "source"))
+
+	err := checkRuntimeClass(ctx, client, "kata", "destination")
+	require.ErrorContains(t, err, "failed to get RuntimeClass kata in the destination cluster")
+}
+
+func TestCheckCoordinationNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	cli := fake.NewSimpleClientset()
+	cli.PrependReactor("create", "selfsubjectaccessreviews",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review, _ := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			attrs := review.Spec.ResourceAttributes
+			review.Status.Allowed = attrs.Namespace == "pv-migrate" || attrs.Verb == "get"
+
+			return true, review, nil
+		})
+
+	client := &k8s.ClusterClient{KubeClient: cli}
+
+	require.NoError(t, checkCoordinationNamespace(ctx, client, "pv-migrate", "source"))
+
+	err := checkCoordinationNamespace(ctx, client, "other", "destination")
+	require.EqualError(t, err, "not allowed to list, create, update, delete secrets "+
+		"in the coordination namespace other in the destination cluster")
+
+	assert.Equal(t, "configmaps", helmStorageResource("configmap"))
+	assert.Empty(t, helmStorageResource("sql"))
+}
+
+func TestCheckSSHClusterIP(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	logger := slogt.New(t)
+
+	client := &k8s.ClusterClient{KubeClient: fake.NewSimpleClientset()}
+	require.NoError(t, checkSSHClusterIP(ctx, client, "192.168.0.10", logger))
+
+	client = &k8s.ClusterClient{KubeClient: fake.NewSimpleClientset(&networkingv1beta1.ServiceCIDR{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubernetes"},
+		Spec:       networkingv1beta1.ServiceCIDRSpec{CIDRs: []string{"10.96.0.0/12", "fd00:10:96::/108"}},
+	})}
+
+	require.NoError(t, checkSSHClusterIP(ctx, client, "10.96.0.50", logger))
+	require.NoError(t, checkSSHClusterIP(ctx, client, "fd00:10:96::50", logger))
+	require.Error(t, checkSSHClusterIP(ctx, client, "192.168.0.10", logger))
+}
+
+func TestCheckFilesystems(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	logger := slogt.New(t)
+
+	buildInfo := func(name, fsType string) *pvc.Info {
+		storageClassName := name
+		storageClass := storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: storageClassName},
+			Provisioner: "csi.example.com",
+			Parameters:  map[string]string{"csi.storage.k8s.io/fstype": fsType},
+		}
+
+		claim := buildTestPVC(sourceNS, name, corev1.ReadWriteOnce)
+		claim.Spec.StorageClassName = &storageClassName
+
+		return &pvc.Info{
+			ClusterClient: &k8s.ClusterClient{KubeClient: fake.NewSimpleClientset(&storageClass)},
+			Claim:         claim,
+		}
+	}
+
+	xfs := buildInfo("xfs", "xfs")
+	ext4 := buildInfo("ext4", "ext4")
+	vfat := buildInfo("vfat", "vfat")
+
+	require.NoError(t, checkFilesystems(ctx, xfs, xfs, true, logger))
+	require.NoError(t, checkFilesystems(ctx, ext4, xfs, true, logger))
+	require.NoError(t, checkFilesystems(ctx, xfs, ext4, false, logger))
+
+	err := checkFilesystems(ctx, xfs, ext4, true, logger)
+	require.ErrorContains(t, err, "reflinks")
+	require.NotContains(t, err.Error(), "project quotas")
+
+	err = checkFilesystems(ctx, ext4, vfat, true, logger)
+	require.ErrorContains(t, err, "ownership and permissions")
+
+	undeclared := buildInfo("undeclared", "")
+
+	require.NoError(t, checkFilesystems(ctx, undeclared, xfs, false, logger))
+	require.ErrorContains(t, checkFilesystems(ctx, undeclared, xfs, true, logger),
+		"filesystem type of the source PVC is not declared")
+	require.ErrorContains(t, checkFilesystems(ctx, xfs, undeclared, true, logger),
+		"filesystem type of the destination PVC is not declared")
+}
+
+func TestScaleDownDest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	logger := slogt.New(t)
+
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: destNS, Name: "app"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace: destNS, Name: "app-abc",
+		OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "apps/v1", Kind: "Deployment", Name: "app", Controller: ptr.To(true)},
+		},
+	}}
+	pod := buildTestPod(destNS, destPod, destNode, destPVC)
+	pod.OwnerReferences = []metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-abc", Controller: ptr.To(true)},
+	}
+
+	cli := fake.NewSimpleClientset(buildTestPVC(destNS, destPVC, corev1.ReadWriteOnce), deployment, replicaSet, pod)
+	cli.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updated, _ := action.(k8stesting.UpdateAction).GetObject().(*appsv1.Deployment)
+		if *updated.Spec.Replicas == 0 {
+			require.NoError(t, cli.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), destNS, destPod))
+		}
+
+		return false, nil, nil
+	})
+
+	m := Migrator{getKubeClient: func(string, string, k8s.TLSOptions, *slog.Logger) (*k8s.ClusterClient, error) {
+		return &k8s.ClusterClient{KubeClient: cli}, nil
+	}}
+
+	request := buildMigration(false)
+	request.ScaleDownDest = true
+
+	restore, err := m.scaleDownDest(ctx, request, logger)
+	require.NoError(t, err)
+
+	getReplicas := func() int32 {
+		result, getErr := cli.AppsV1().Deployments(destNS).Get(ctx, "app", metav1.GetOptions{})
+		require.NoError(t, getErr)
+
+		return *result.Spec.Replicas
+	}
+
+	assert.Equal(t, int32(0), getReplicas())
+
+	restore()
+
+	assert.Equal(t, int32(2), getReplicas())
+
+	bare := fake.NewSimpleClientset(buildTestPod(destNS, destPod, destNode, destPVC))
+	_, err = findDestWorkloads(ctx, bare, destNS, destPVC)
+	require.ErrorContains(t, err, "not controlled by a workload")
+}
+
+func TestCheckDestAttachment(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: destNode}}
+	cordoned := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: destNode}, Spec: corev1.NodeSpec{Unschedulable: true}}
+
+	claim := buildTestPVC(destNS, destPVC, corev1.ReadWriteOnce)
+	pod := buildTestPod(destNS, destPod, destNode, destPVC)
+
+	newInfo := func(objects ...runtime.Object) *pvc.Info {
+		client := &k8s.ClusterClient{KubeClient: fake.NewSimpleClientset(append(objects, claim, pod)...)}
+
+		info, err := pvc.New(ctx, client, destNS, destPVC)
+		require.NoError(t, err)
+
+		return info
+	}
+
+	err := checkDestAttachment(ctx, newInfo(node), false)
+	require.ErrorContains(t, err, "mounted by the pods pod2 on node node2")
+
+	require.NoError(t, checkDestAttachment(ctx, newInfo(node), true))
+
+	err = checkDestAttachment(ctx, newInfo(cordoned), true)
+	require.ErrorContains(t, err, "the node is unschedulable")
+}
+
+func TestSourcePrepareTarget(t *testing.T) {
+	t.Parallel()
+
+	newPod := func(name string, phase corev1.PodPhase, containers ...string) corev1.Pod {
+		pod := buildTestPod(sourceNS, name, sourceNode, sourcePVC)
+		pod.Status.Phase = phase
+
+		for _, container := range containers {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container})
+		}
+
+		return *pod
+	}
+
+	_, _, err := sourcePrepareTarget([]corev1.Pod{newPod("pending", corev1.PodPending, "db")})
+	require.ErrorContains(t, err, "no running pod")
+
+	terminating := newPod("terminating", corev1.PodRunning, "db")
+	terminating.DeletionTimestamp = ptr.To(metav1.Now())
+	running := newPod("running", corev1.PodRunning, "db", "sidecar")
+
+	pod, container, err := sourcePrepareTarget([]corev1.Pod{terminating, running})
+	require.NoError(t, err)
+
+	assert.Equal(t, "running", pod.Name)
+	assert.Equal(t, "db", container)
+
+	running.Annotations = map[string]string{defaultContainerAnnotation: "sidecar"}
+
+	_, container, err = sourcePrepareTarget([]corev1.Pod{running})
+	require.NoError(t, err)
+
+	assert.Equal(t, "sidecar", container)
+}
+
+func TestCheckTopology(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	zonalPV := func(name, zone string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeSpec{
+				NodeAffinity: &corev1.VolumeNodeAffinity{
+					Required: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{zone}},
+						}},
+					}},
+				},
+			},
+		}
+	}
+
+	zonalNode := func(name, zone string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{corev1.LabelTopologyZone: zone},
+		}}
+	}
+
+	pvcInfo := func(cli *fake.Clientset, namespace, name, volumeName string) *pvc.Info {
+		claim := buildTestPVC(namespace, name, corev1.ReadWriteOnce)
+		claim.Spec.VolumeName = volumeName
+
+		return &pvc.Info{
+			Claim:         claim,
+			ClusterClient: &k8s.ClusterClient{KubeClient: cli, RestConfig: &rest.Config{}},
+			SupportsRWO:   true,
+		}
+	}
+
+	cli := fake.NewSimpleClientset(zonalNode(sourceNode, "zone-a"), zonalNode(destNode, "zone-b"),
+		zonalPV("pv1", "zone-a"), zonalPV("pv2", "zone-b"), zonalPV("pv3", "zone-c"))
+	request := &migration.Request{Strategies: []string{strategy.Mnt2Strategy, strategy.SvcStrategy}}
+
+	noCommonNode, err := checkTopology(ctx, request, pvcInfo(cli, sourceNS, sourcePVC, "pv1"),
+		pvcInfo(cli, destNS, destPVC, "pv1"), slogt.New(t))
+	require.NoError(t, err)
+	assert.False(t, noCommonNode)
+
+	noCommonNode, err = checkTopology(ctx, request, pvcInfo(cli, sourceNS, sourcePVC, "pv1"),
+		pvcInfo(cli, destNS, destPVC, "pv2"), slogt.New(t))
+	require.NoError(t, err)
+	assert.True(t, noCommonNode, "the volumes are in different zones")
+
+	request.Strategies = []string{strategy.Mnt2Strategy}
+
+	_, err = checkTopology(ctx, request, pvcInfo(cli, sourceNS, sourcePVC, "pv1"),
+		pvcInfo(cli, destNS, destPVC, "pv2"), slogt.New(t))
+	require.ErrorContains(t, err, "the source PVC requires topology.kubernetes.io/zone in (zone-a), matched by node1")
+
+	_, err = checkTopology(ctx, request, pvcInfo(cli, sourceNS, sourcePVC, "pv1"),
+		pvcInfo(cli, destNS, destPVC, "pv3"), slogt.New(t))
+	require.ErrorContains(t, err, "no node of the cluster can mount the destination PVC")
+}
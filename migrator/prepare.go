@@ -0,0 +1,74 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+)
+
+// defaultContainerAnnotation is the annotation of the pods naming the container for kubectl exec to use by default.
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// runSourcePrepareCommand runs the prepare command in a running pod mounting the source PVC, e.g., to flush
+// the data of a database to the disk before the transfer. The command is run with "sh -c" in the default container
+// of the pod, and the migration fails if it exits with a non-zero code.
+func runSourcePrepareCommand(ctx context.Context, mig *migration.Migration, logger *slog.Logger) error {
+	sourceInfo := mig.SourceInfo
+	namespace := sourceInfo.Claim.Namespace
+
+	pods, err := podsMountingPVC(ctx, sourceInfo.ClusterClient.KubeClient, namespace, sourceInfo.Claim.Name)
+	if err != nil {
+		return err
+	}
+
+	pod, container, err := sourcePrepareTarget(pods)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("🧪 Running the prepare command in the source pod",
+		"pod", namespace+"/"+pod.Name, "container", container)
+
+	var output bytes.Buffer
+
+	err = k8s.ExecInPod(ctx, sourceInfo.ClusterClient, namespace, pod.Name, container,
+		[]string{"sh", "-c", mig.Request.SourcePrepareCommand}, &output, &output)
+
+	if trimmed := strings.TrimSpace(output.String()); trimmed != "" {
+		logger.Info("📋 Output of the prepare command", "output", trimmed)
+	}
+
+	if err != nil {
+		return fmt.Errorf("prepare command failed in pod %s/%s: %w", namespace, pod.Name, err)
+	}
+
+	return nil
+}
+
+// sourcePrepareTarget picks the first running pod among the given ones which is not being deleted,
+// and the container of it to run the prepare command in, i.e., the one named by the default container
+// annotation of kubectl if present, or the first container.
+func sourcePrepareTarget(pods []corev1.Pod) (*corev1.Pod, string, error) {
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil || len(pod.Spec.Containers) == 0 {
+			continue
+		}
+
+		if container := pod.Annotations[defaultContainerAnnotation]; container != "" {
+			return pod, container, nil
+		}
+
+		return pod, pod.Spec.Containers[0].Name, nil
+	}
+
+	return nil, "", errors.New("no running pod mounting the source PVC to run the prepare command in")
+}
@@ -0,0 +1,92 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+)
+
+const (
+	// destBindTimeout is how long to wait for the destination PVC to be bound before setting the reclaim policy,
+	// e.g., after it is recreated by the snapshot strategy.
+	destBindTimeout      = 2 * time.Minute
+	destBindPollInterval = 2 * time.Second
+)
+
+// applyDestReclaimPolicy sets the reclaim policy of the request on the PV bound to the destination PVC,
+// e.g., Retain for the migrated data to survive the deletion of the PVC.
+//
+// It only changes the PVs provisioned during the migration, i.e., when the destination PVC was not bound yet
+// when the migration was built, e.g., with the WaitForFirstConsumer binding mode or the snapshot strategy.
+// The reclaim policy of a PV the destination PVC was already bound to is kept, as it was chosen before.
+//
+// It waits up to bindTimeout for the destination PVC to be bound, and skips with a warning if it is still not,
+// e.g., when the PVC recreated by the snapshot strategy waits for its first consumer.
+func applyDestReclaimPolicy(ctx context.Context, mig *migration.Migration, bindTimeout time.Duration,
+	logger *slog.Logger,
+) error {
+	policy := corev1.PersistentVolumeReclaimPolicy(mig.Request.DestReclaimPolicy)
+	destInfo := mig.DestInfo
+	cli := destInfo.ClusterClient.KubeClient
+	namespace, name := destInfo.Claim.Namespace, destInfo.Claim.Name
+
+	var volumeName string
+
+	err := wait.PollUntilContextTimeout(ctx, destBindPollInterval, bindTimeout, true,
+		func(ctx context.Context) (bool, error) {
+			claim, err := cli.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, fmt.Errorf("failed to get PVC %s/%s: %w", namespace, name, err)
+			}
+
+			volumeName = claim.Spec.VolumeName
+
+			return volumeName != "", nil
+		})
+	if err != nil {
+		if wait.Interrupted(err) && ctx.Err() == nil {
+			logger.Warn("🔶 Not setting the reclaim policy of the destination PV, the destination PVC "+
+				"is not bound to a persistent volume yet", "pvc", namespace+"/"+name, "reclaim_policy", policy)
+
+			return nil
+		}
+
+		return fmt.Errorf("failed to wait for PVC %s/%s to be bound: %w", namespace, name, err)
+	}
+
+	pv, err := cli.CoreV1().PersistentVolumes().Get(ctx, volumeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get persistent volume %s: %w", volumeName, err)
+	}
+
+	if pv.Spec.PersistentVolumeReclaimPolicy == policy {
+		return nil
+	}
+
+	if destInfo.Claim.Spec.VolumeName != "" {
+		logger.Warn("🔶 Not changing the reclaim policy of the destination PV, "+
+			"it was not provisioned during the migration", "pv", volumeName,
+			"reclaim_policy", pv.Spec.PersistentVolumeReclaimPolicy)
+
+		return nil
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"persistentVolumeReclaimPolicy":%q}}`, policy)
+
+	if _, err = cli.CoreV1().PersistentVolumes().Patch(ctx, volumeName, types.StrategicMergePatchType,
+		[]byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to set the reclaim policy of persistent volume %s: %w", volumeName, err)
+	}
+
+	logger.Info("🔒 Set the reclaim policy of the destination PV", "pv", volumeName, "reclaim_policy", policy)
+
+	return nil
+}
@@ -0,0 +1,318 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+const (
+	scaleDownTimeout      = 5 * time.Minute
+	scaleDownPollInterval = 2 * time.Second
+)
+
+// destWorkload is a workload whose pods mount the destination PVC.
+type destWorkload struct {
+	kind     string
+	name     string
+	replicas int32
+}
+
+func (w destWorkload) String() string {
+	return w.kind + "/" + w.name
+}
+
+// scaleDownDest scales the Deployments and the StatefulSets whose pods mount the destination PVC down to zero,
+// and waits for their pods to terminate, for the migration pods to be able to mount a ReadWriteOnce PVC.
+//
+// It returns a function which scales the workloads back to their original replicas.
+func (m *Migrator) scaleDownDest(ctx context.Context, request *migration.Request,
+	logger *slog.Logger,
+) (func(), error) {
+	_, destClient, err := m.getClusterClients(request, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := request.Dest.Namespace
+	if namespace == "" {
+		namespace = destClient.NsInContext
+	}
+
+	cli := destClient.KubeClient
+
+	workloads, err := findDestWorkloads(ctx, cli, namespace, request.Dest.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(workloads) > 0 {
+		original := make([]string, 0, len(workloads))
+		for _, workload := range workloads {
+			original = append(original, fmt.Sprintf("%s=%d", workload, workload.replicas))
+		}
+
+		logger.Info("📝 Original replicas of the workloads using the destination PVC, "+
+			"in case they need to be scaled back up manually", "namespace", namespace,
+			"replicas", strings.Join(original, ","))
+	}
+
+	var scaledDown []destWorkload
+
+	restore := func() {
+		restoreCtx := context.WithoutCancel(ctx)
+
+		for _, workload := range scaledDown {
+			logger.Info("🔼 Scaling the workload using the destination PVC back up",
+				"workload", namespace+"/"+workload.String(), "replicas", workload.replicas)
+
+			if scaleErr := scaleWorkload(restoreCtx, cli, namespace, workload, workload.replicas); scaleErr != nil {
+				logger.Warn("🔶 Failed to scale the workload back up, it needs to be scaled up manually",
+					"workload", namespace+"/"+workload.String(), "replicas", workload.replicas, "error", scaleErr)
+			}
+		}
+	}
+
+	for _, workload := range workloads {
+		logger.Info("🔽 Scaling down the workload using the destination PVC",
+			"workload", namespace+"/"+workload.String(), "replicas", workload.replicas)
+
+		if err = scaleWorkload(ctx, cli, namespace, workload, 0); err != nil {
+			restore()
+
+			return nil, err
+		}
+
+		scaledDown = append(scaledDown, workload)
+	}
+
+	if err = waitForPVCUnmounted(ctx, cli, namespace, request.Dest.Name); err != nil {
+		restore()
+
+		return nil, err
+	}
+
+	return restore, nil
+}
+
+// findDestWorkloads returns the workloads whose pods mount the PVC with the given name.
+//
+// It fails if a pod mounting the PVC is not controlled by a Deployment or a StatefulSet, as it cannot be scaled down.
+func findDestWorkloads(ctx context.Context, cli kubernetes.Interface, namespace, claimName string,
+) ([]destWorkload, error) {
+	pods, err := podsMountingPVC(ctx, cli, namespace, claimName)
+	if err != nil {
+		return nil, err
+	}
+
+	var workloads []destWorkload
+
+	seen := make(map[string]struct{})
+
+	for _, pod := range pods {
+		workload, workloadErr := podWorkload(ctx, cli, &pod)
+		if workloadErr != nil {
+			return nil, workloadErr
+		}
+
+		if _, ok := seen[workload.String()]; ok {
+			continue
+		}
+
+		seen[workload.String()] = struct{}{}
+
+		workloads = append(workloads, workload)
+	}
+
+	return workloads, nil
+}
+
+func podsMountingPVC(ctx context.Context, cli kubernetes.Interface, namespace, claimName string,
+) ([]corev1.Pod, error) {
+	podList, err := cli.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	var pods []corev1.Pod
+
+	for _, pod := range podList.Items {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+				pods = append(pods, pod)
+
+				break
+			}
+		}
+	}
+
+	return pods, nil
+}
+
+func podWorkload(ctx context.Context, cli kubernetes.Interface, pod *corev1.Pod) (destWorkload, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return destWorkload{}, fmt.Errorf("pod %s/%s using the destination PVC is not controlled by a workload, "+
+			"it cannot be scaled down", pod.Namespace, pod.Name)
+	}
+
+	switch owner.Kind {
+	case "StatefulSet":
+		statefulSet, err := cli.AppsV1().StatefulSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return destWorkload{}, fmt.Errorf("failed to get statefulset %s/%s: %w", pod.Namespace, owner.Name, err)
+		}
+
+		return destWorkload{
+			kind: "statefulset", name: statefulSet.Name, replicas: replicasOf(statefulSet.Spec.Replicas),
+		}, nil
+	case "ReplicaSet":
+		replicaSet, err := cli.AppsV1().ReplicaSets(pod.Namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return destWorkload{}, fmt.Errorf("failed to get replicaset %s/%s: %w", pod.Namespace, owner.Name, err)
+		}
+
+		deploymentOwner := metav1.GetControllerOf(replicaSet)
+		if deploymentOwner == nil || deploymentOwner.Kind != "Deployment" {
+			return destWorkload{}, fmt.Errorf("replicaset %s/%s using the destination PVC is not controlled "+
+				"by a deployment, it cannot be scaled down", pod.Namespace, replicaSet.Name)
+		}
+
+		deployment, err := cli.AppsV1().Deployments(pod.Namespace).Get(ctx, deploymentOwner.Name, metav1.GetOptions{})
+		if err != nil {
+			return destWorkload{}, fmt.Errorf("failed to get deployment %s/%s: %w",
+				pod.Namespace, deploymentOwner.Name, err)
+		}
+
+		return destWorkload{
+			kind: "deployment", name: deployment.Name, replicas: replicasOf(deployment.Spec.Replicas),
+		}, nil
+	default:
+		return destWorkload{}, fmt.Errorf("pod %s/%s using the destination PVC is controlled by a %s, "+
+			"only deployments and statefulsets can be scaled down", pod.Namespace, pod.Name, owner.Kind)
+	}
+}
+
+func replicasOf(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+
+	return *replicas
+}
+
+func scaleWorkload(ctx context.Context, cli kubernetes.Interface, namespace string,
+	workload destWorkload, replicas int32,
+) error {
+	switch workload.kind {
+	case "statefulset":
+		statefulSet, err := cli.AppsV1().StatefulSets(namespace).Get(ctx, workload.name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get statefulset %s/%s: %w", namespace, workload.name, err)
+		}
+
+		statefulSet.Spec.Replicas = &replicas
+
+		if _, err = cli.AppsV1().StatefulSets(namespace).Update(ctx, statefulSet, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to scale statefulset %s/%s: %w", namespace, workload.name, err)
+		}
+	case "deployment":
+		deployment, err := cli.AppsV1().Deployments(namespace).Get(ctx, workload.name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get deployment %s/%s: %w", namespace, workload.name, err)
+		}
+
+		deployment.Spec.Replicas = &replicas
+
+		if _, err = cli.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to scale deployment %s/%s: %w", namespace, workload.name, err)
+		}
+	default:
+		return fmt.Errorf("unsupported workload kind %q", workload.kind)
+	}
+
+	return nil
+}
+
+func waitForPVCUnmounted(ctx context.Context, cli kubernetes.Interface, namespace, claimName string) error {
+	var remaining []string
+
+	err := wait.PollUntilContextTimeout(ctx, scaleDownPollInterval, scaleDownTimeout, true,
+		func(ctx context.Context) (bool, error) {
+			pods, err := podsMountingPVC(ctx, cli, namespace, claimName)
+			if err != nil {
+				return false, err
+			}
+
+			remaining = remaining[:0]
+			for _, pod := range pods {
+				remaining = append(remaining, pod.Name)
+			}
+
+			return len(pods) == 0, nil
+		})
+	if err != nil {
+		return fmt.Errorf("failed to wait for the pods using the destination PVC to terminate, remaining: %s: %w",
+			strings.Join(remaining, ", "), err)
+	}
+
+	return nil
+}
+
+// checkDestAttachment fails with the pods using the destination PVC if it is only ReadWriteOnce and mounted
+// to a node the migration pods cannot be scheduled on, as they would otherwise wait for the volume until the timeout.
+//
+// Unless ignoreMounted is set, any node is considered a conflict, as the migration would run on the node of the
+// workload while it is writing to the same volume.
+func checkDestAttachment(ctx context.Context, destInfo *pvc.Info, ignoreMounted bool) error {
+	if destInfo.MountedNode == "" || destInfo.SupportsRWX {
+		return nil
+	}
+
+	cli := destInfo.ClusterClient.KubeClient
+
+	if ignoreMounted {
+		node, err := cli.CoreV1().Nodes().Get(ctx, destInfo.MountedNode, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+				return nil
+			}
+
+			return fmt.Errorf("failed to get node %s: %w", destInfo.MountedNode, err)
+		}
+
+		if !node.Spec.Unschedulable {
+			return nil
+		}
+	}
+
+	pods, err := podsMountingPVC(ctx, cli, destInfo.Claim.Namespace, destInfo.Claim.Name)
+	if err != nil {
+		return err
+	}
+
+	podNames := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		podNames = append(podNames, pod.Name)
+	}
+
+	reason := "it would be written to by the migration while they are running"
+	if ignoreMounted {
+		reason = "the node is unschedulable, the migration pods cannot mount it"
+	}
+
+	return fmt.Errorf("destination PVC %s/%s is ReadWriteOnce and is mounted by the pods %s on node %s, %s: "+
+		"stop the workload using it, or use --scale-down-dest to scale it down during the migration",
+		destInfo.Claim.Namespace, destInfo.Claim.Name, strings.Join(podNames, ", "), destInfo.MountedNode, reason)
+}
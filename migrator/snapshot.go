@@ -0,0 +1,229 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/strategy"
+	"github.com/utkuozdemir/pv-migrate/util"
+)
+
+const (
+	snapshotReadyTimeout = 10 * time.Minute
+
+	// baseSnapshotSuffix is appended to the name of the destination PVC to name its base snapshot.
+	baseSnapshotSuffix = "-pv-migrate-base"
+)
+
+// useSourceSnapshot takes a VolumeSnapshot of the source PVC and replaces the source of the migration
+// with a temporary PVC restored from it. This way, a consistent point-in-time copy of the source is migrated
+// without having to stop the workload using it.
+//
+// It returns a function which deletes the temporary PVC and the snapshot.
+func useSourceSnapshot(ctx context.Context, mig *migration.Migration, logger *slog.Logger) (func(), error) {
+	sourceInfo := mig.SourceInfo
+	claim := sourceInfo.Claim
+	dynamicClient := sourceInfo.ClusterClient.DynamicClient
+
+	snapshotClass, err := strategy.ResolveSnapshotClass(ctx, mig.Request.SnapshotClass, sourceInfo)
+	if err != nil {
+		if errors.Is(err, k8s.ErrNoSnapshotClass) {
+			return nil, fmt.Errorf("source PVC %s/%s does not support volume snapshots: %w",
+				claim.Namespace, claim.Name, err)
+		}
+
+		return nil, err
+	}
+
+	name := "pv-migrate-" + util.RandomHexadecimalString(attemptIDLength) + "-src"
+	labels := map[string]string{
+		"app.kubernetes.io/name":     "pv-migrate",
+		"app.kubernetes.io/instance": name,
+	}
+
+	logger.Info("📸 Creating volume snapshot of the source PVC to migrate from", "snapshot", name,
+		"snapshot_class", snapshotClass)
+
+	// the cleanup does not use the context of the migration, for the snapshot and the PVC not to be left behind
+	// when the migration is interrupted
+	cleanup := func() {
+		deleteSourceSnapshot(context.WithoutCancel(ctx), mig, sourceInfo, name, logger)
+	}
+
+	succeeded := false
+
+	defer func() {
+		if !succeeded {
+			cleanup()
+		}
+	}()
+
+	// the snapshot might have been created even if the request failed, e.g., when it was interrupted
+	if err = k8s.CreateVolumeSnapshot(ctx, dynamicClient, claim.Namespace, name, claim.Name,
+		snapshotClass, labels); err != nil {
+		return nil, err
+	}
+
+	if err = k8s.WaitForVolumeSnapshotReady(ctx, dynamicClient, claim.Namespace,
+		name, snapshotReadyTimeout); err != nil {
+		return nil, err
+	}
+
+	if err = createPVCFromSnapshot(ctx, sourceInfo.ClusterClient.KubeClient, claim, name, labels); err != nil {
+		return nil, err
+	}
+
+	snapshotInfo, err := pvc.New(ctx, sourceInfo.ClusterClient, claim.Namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PVC info for the snapshot of the source PVC: %w", err)
+	}
+
+	succeeded = true
+
+	logger.Info("💡 Migrating from the temporary PVC restored from the snapshot", "pvc", claim.Namespace+"/"+name)
+
+	mig.SourceInfo = snapshotInfo
+
+	return cleanup, nil
+}
+
+// snapshotDest takes a VolumeSnapshot of the destination PVC to be kept as a checkpoint of the migrated data,
+// and waits until it is ready.
+//
+// It is skipped with a warning if no VolumeSnapshotClass is given and none matches the destination PVC.
+//
+// If base is set, the snapshot is the base snapshot of the destination PVC, which is only taken once,
+// after the first full copy, for the later incremental snapshots of the storage system to build upon.
+// It is skipped if it already exists.
+func snapshotDest(ctx context.Context, mig *migration.Migration, base bool, logger *slog.Logger) error {
+	destInfo := mig.DestInfo
+	claim := destInfo.Claim
+
+	snapshotClass, err := strategy.ResolveSnapshotClass(ctx, mig.Request.SnapshotAfterClass, destInfo)
+	if err != nil {
+		if errors.Is(err, k8s.ErrNoSnapshotClass) {
+			logger.Warn("🔶 Skipping the snapshot of the destination PVC, "+
+				"its storage class does not support volume snapshots", "pvc", claim.Namespace+"/"+claim.Name)
+
+			return nil
+		}
+
+		return err
+	}
+
+	name := claim.Name + "-pv-migrate-" + util.RandomHexadecimalString(attemptIDLength)
+
+	dynamicClient := destInfo.ClusterClient.DynamicClient
+
+	if base {
+		name = claim.Name + baseSnapshotSuffix
+
+		exists, existsErr := k8s.VolumeSnapshotExists(ctx, dynamicClient, claim.Namespace, name)
+		if existsErr != nil {
+			return existsErr
+		}
+
+		if exists {
+			logger.Info("📸 Base volume snapshot of the destination PVC already exists, keeping it",
+				"snapshot", claim.Namespace+"/"+name)
+
+			return nil
+		}
+	}
+
+	labels := map[string]string{
+		"app.kubernetes.io/name":     "pv-migrate",
+		"app.kubernetes.io/instance": name,
+	}
+
+	logger.Info("📸 Creating volume snapshot of the destination PVC", "snapshot", name,
+		"snapshot_class", snapshotClass)
+
+	if err = k8s.CreateVolumeSnapshot(ctx, dynamicClient, claim.Namespace, name, claim.Name,
+		snapshotClass, labels); err != nil {
+		return err
+	}
+
+	if err = k8s.WaitForVolumeSnapshotReady(ctx, dynamicClient, claim.Namespace,
+		name, snapshotReadyTimeout); err != nil {
+		return err
+	}
+
+	logger.Info("✨ Volume snapshot of the destination PVC is ready", "snapshot", claim.Namespace+"/"+name)
+
+	return nil
+}
+
+func createPVCFromSnapshot(ctx context.Context, cli kubernetes.Interface, source *corev1.PersistentVolumeClaim,
+	snapshotName string, labels map[string]string,
+) error {
+	size, ok := source.Status.Capacity[corev1.ResourceStorage]
+	if !ok {
+		size = source.Spec.Resources.Requests[corev1.ResourceStorage]
+	}
+
+	apiGroup := k8s.SnapshotAPIGroup
+	claim := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      snapshotName,
+			Namespace: source.Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      source.Spec.AccessModes,
+			StorageClassName: source.Spec.StorageClassName,
+			VolumeMode:       source.Spec.VolumeMode,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     k8s.SnapshotKind,
+				Name:     snapshotName,
+			},
+		},
+	}
+
+	if _, err := cli.CoreV1().PersistentVolumeClaims(source.Namespace).
+		Create(ctx, &claim, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create PVC %s/%s from the snapshot: %w", source.Namespace, snapshotName, err)
+	}
+
+	return nil
+}
+
+func deleteSourceSnapshot(ctx context.Context, mig *migration.Migration, sourceInfo *pvc.Info,
+	name string, logger *slog.Logger,
+) {
+	if mig.Request.SkipCleanup {
+		logger.Info("🧹 Cleanup of the source snapshot skipped", "snapshot", name)
+
+		return
+	}
+
+	namespace := sourceInfo.Claim.Namespace
+
+	err := sourceInfo.ClusterClient.KubeClient.CoreV1().PersistentVolumeClaims(namespace).
+		Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		err = fmt.Errorf("failed to delete PVC %s/%s: %w", namespace, name, err)
+	} else {
+		err = k8s.DeleteVolumeSnapshot(ctx, sourceInfo.ClusterClient.DynamicClient, namespace, name)
+	}
+
+	if err != nil {
+		logger.Warn("🔶 Cleanup of the source snapshot failed, you might want to clean up manually",
+			"snapshot", namespace+"/"+name, "error", err)
+	}
+}
@@ -0,0 +1,240 @@
+package migrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/util"
+)
+
+const (
+	sourcePVBindTimeout      = 2 * time.Minute
+	sourcePVBindPollInterval = 2 * time.Second
+)
+
+// useSourcePV creates a temporary PVC bound to the source PV of the request in the source namespace,
+// e.g., to rescue the data of a PV whose PVC was deleted, and returns a copy of the request
+// with the temporary PVC as its source.
+//
+// It returns a function which deletes the temporary PVC. As the PV is required to have the Retain reclaim policy,
+// it is then released again, keeping its data, and its original claim reference is restored.
+func (m *Migrator) useSourcePV(ctx context.Context, request *migration.Request,
+	logger *slog.Logger,
+) (*migration.Request, func(), error) {
+	sourceClient, _, err := m.getClusterClients(request, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	namespace := request.Source.Namespace
+	if namespace == "" {
+		namespace = sourceClient.NsInContext
+	}
+
+	cli := sourceClient.KubeClient
+
+	pv, err := cli.CoreV1().PersistentVolumes().Get(ctx, request.SourcePV, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get persistent volume %s: %w", request.SourcePV, err)
+	}
+
+	if err = checkSourcePV(pv); err != nil {
+		return nil, nil, err
+	}
+
+	name := "pv-migrate-" + util.RandomHexadecimalString(attemptIDLength) + "-pv"
+
+	logger.Info("🔗 Creating temporary PVC bound to the source PV", "pv", pv.Name, "pvc", namespace+"/"+name)
+
+	if err = bindPVToClaim(ctx, cli, pv, namespace, name); err != nil {
+		return nil, nil, err
+	}
+
+	// the claim reference is restored on any failure below, including the cancellation of the context
+	// on a termination signal, for the PV not to stay bound to a PVC which does not exist
+	var claim *corev1.PersistentVolumeClaim
+
+	cleanup := func() {
+		cleanupCtx := context.WithoutCancel(ctx)
+
+		if request.SkipCleanup {
+			logger.Info("🧹 Cleanup of the temporary PVC of the source PV skipped",
+				"pv", pv.Name, "pvc", namespace+"/"+name)
+
+			return
+		}
+
+		if claim != nil {
+			deleteSourcePVClaim(cleanupCtx, cli, claim, logger)
+		}
+
+		restorePVClaimRef(cleanupCtx, cli, pv, logger)
+	}
+
+	succeeded := false
+
+	defer func() {
+		if !succeeded {
+			cleanup()
+		}
+	}()
+
+	if claim, err = createPVCForPV(ctx, cli, pv, namespace, name); err != nil {
+		return nil, nil, err
+	}
+
+	if err = waitForPVCBound(ctx, cli, namespace, name); err != nil {
+		return nil, nil, err
+	}
+
+	succeeded = true
+
+	source := *request.Source
+	source.Namespace = namespace
+	source.Name = name
+
+	sourceRequest := *request
+	sourceRequest.Source = &source
+
+	return &sourceRequest, cleanup, nil
+}
+
+// checkSourcePV checks that the PV can be bound to a new PVC, and that its data is kept when the PVC is deleted.
+func checkSourcePV(pv *corev1.PersistentVolume) error {
+	if phase := pv.Status.Phase; phase != corev1.VolumeReleased && phase != corev1.VolumeAvailable {
+		return fmt.Errorf("persistent volume %s is %s, it must be %s or %s to be migrated from",
+			pv.Name, phase, corev1.VolumeReleased, corev1.VolumeAvailable)
+	}
+
+	if policy := pv.Spec.PersistentVolumeReclaimPolicy; policy != corev1.PersistentVolumeReclaimRetain {
+		return fmt.Errorf("persistent volume %s has the %s reclaim policy, it must be %s to be migrated from, "+
+			"as its data would be deleted with the temporary PVC otherwise",
+			pv.Name, policy, corev1.PersistentVolumeReclaimRetain)
+	}
+
+	return nil
+}
+
+// bindPVToClaim points the claim reference of the PV to the PVC with the given name, replacing the reference
+// to the deleted PVC of a released PV, so that it is bound to the PVC once it is created.
+func bindPVToClaim(ctx context.Context, cli kubernetes.Interface,
+	pv *corev1.PersistentVolume, namespace, name string,
+) error {
+	patch := fmt.Sprintf(`{"spec":{"claimRef":{"apiVersion":"v1","kind":"PersistentVolumeClaim",`+
+		`"namespace":%q,"name":%q,"uid":null,"resourceVersion":null}}}`, namespace, name)
+
+	if _, err := cli.CoreV1().PersistentVolumes().Patch(ctx, pv.Name, types.StrategicMergePatchType,
+		[]byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to bind persistent volume %s to PVC %s/%s: %w", pv.Name, namespace, name, err)
+	}
+
+	return nil
+}
+
+func createPVCForPV(ctx context.Context, cli kubernetes.Interface,
+	pv *corev1.PersistentVolume, namespace, name string,
+) (*corev1.PersistentVolumeClaim, error) {
+	// an empty storage class is set explicitly for the default storage class not to be assigned
+	storageClassName := pv.Spec.StorageClassName
+
+	claim := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":     "pv-migrate",
+				"app.kubernetes.io/instance": name,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      pv.Spec.AccessModes,
+			StorageClassName: &storageClassName,
+			VolumeMode:       pv.Spec.VolumeMode,
+			VolumeName:       pv.Name,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: pv.Spec.Capacity[corev1.ResourceStorage]},
+			},
+		},
+	}
+
+	created, err := cli.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, &claim, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PVC %s/%s for persistent volume %s: %w", namespace, name, pv.Name, err)
+	}
+
+	return created, nil
+}
+
+func waitForPVCBound(ctx context.Context, cli kubernetes.Interface, namespace, name string) error {
+	if err := wait.PollUntilContextTimeout(ctx, sourcePVBindPollInterval, sourcePVBindTimeout, true,
+		func(ctx context.Context) (bool, error) {
+			claim, err := cli.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return false, fmt.Errorf("failed to get PVC: %w", err)
+			}
+
+			return claim.Status.Phase == corev1.ClaimBound, nil
+		}); err != nil {
+		return fmt.Errorf("failed to wait for PVC %s/%s to be bound: %w", namespace, name, err)
+	}
+
+	return nil
+}
+
+func deleteSourcePVClaim(ctx context.Context, cli kubernetes.Interface, claim *corev1.PersistentVolumeClaim,
+	logger *slog.Logger,
+) {
+	err := cli.CoreV1().PersistentVolumeClaims(claim.Namespace).Delete(ctx, claim.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.Warn("🔶 Cleanup of the temporary PVC of the source PV failed, you might want to clean up manually",
+			"pvc", claim.Namespace+"/"+claim.Name, "error", err)
+	}
+}
+
+// restorePVClaimRef restores the claim reference the PV had before it was bound to the temporary PVC,
+// i.e., the reference to its deleted PVC if it was released, or none if it was available.
+func restorePVClaimRef(ctx context.Context, cli kubernetes.Interface, pv *corev1.PersistentVolume,
+	logger *slog.Logger,
+) {
+	patch := []map[string]any{{"op": "remove", "path": "/spec/claimRef"}}
+	if pv.Spec.ClaimRef != nil {
+		patch = []map[string]any{{"op": "add", "path": "/spec/claimRef", "value": pv.Spec.ClaimRef}}
+	}
+
+	data, err := json.Marshal(patch)
+	if err == nil {
+		_, err = cli.CoreV1().PersistentVolumes().Patch(ctx, pv.Name, types.JSONPatchType, data,
+			metav1.PatchOptions{})
+	}
+
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.Warn("🔶 Restoring the claim reference of the source PV failed, you might want to restore it manually",
+			"pv", pv.Name, "error", err)
+	}
+}
+
+// validateSourcePV checks that the source PV of the request can be migrated from, without creating any resources.
+func (m *Migrator) validateSourcePV(ctx context.Context, request *migration.Request, logger *slog.Logger) error {
+	sourceClient, _, err := m.getClusterClients(request, logger)
+	if err != nil {
+		return err
+	}
+
+	pv, err := sourceClient.KubeClient.CoreV1().PersistentVolumes().Get(ctx, request.SourcePV, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get persistent volume %s: %w", request.SourcePV, err)
+	}
+
+	return checkSourcePV(pv)
+}
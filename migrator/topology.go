@@ -0,0 +1,107 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/strategy"
+)
+
+// maxLoggedNodes limits the number of the node names in the explanations of the topology check.
+const maxLoggedNodes = 5
+
+// checkTopology cross-checks the topology constraints of the PVCs, i.e., the node affinities of their volumes,
+// the allowed topologies of their storage classes and the nodes they are mounted to, with the nodes of the clusters,
+// for the migration pods not to hang in the Pending state until the timeout.
+//
+// It fails if no node can mount one of the PVCs. If the PVCs are in the same cluster and no node can mount both,
+// it returns true for them not to be mounted in a single pod, and fails if no other strategy is requested.
+func checkTopology(ctx context.Context, request *migration.Request, sourceInfo, destInfo *pvc.Info,
+	logger *slog.Logger,
+) (bool, error) {
+	sourceTerms, sourceNodes, err := nodesForPVC(ctx, sourceInfo, "source")
+	if err != nil {
+		return false, err
+	}
+
+	destTerms, destNodes, err := nodesForPVC(ctx, destInfo, "destination")
+	if err != nil {
+		return false, err
+	}
+
+	if sourceInfo.ClusterClient.RestConfig.Host != destInfo.ClusterClient.RestConfig.Host ||
+		slices.ContainsFunc(sourceNodes, func(node string) bool { return slices.Contains(destNodes, node) }) {
+		return false, nil
+	}
+
+	explanation := fmt.Sprintf("the source PVC requires %s, matched by %s, "+
+		"and the destination PVC requires %s, matched by %s",
+		describeTerms(sourceTerms), formatNodes(sourceNodes), describeTerms(destTerms), formatNodes(destNodes))
+
+	if !slices.ContainsFunc(request.Strategies, func(name string) bool { return name != strategy.Mnt2Strategy }) {
+		return false, fmt.Errorf("no node can mount both the source and the destination PVCs for the %s strategy: %s",
+			strategy.Mnt2Strategy, explanation)
+	}
+
+	logger.Warn("🔶 No node can mount both the source and the destination PVCs, "+
+		"the "+strategy.Mnt2Strategy+" strategy will be skipped", "explanation", explanation)
+
+	return true, nil
+}
+
+// nodesForPVC returns the topology constraints of the PVC and the names of the nodes matching them,
+// failing if there are none.
+func nodesForPVC(ctx context.Context, info *pvc.Info, side string) ([]corev1.NodeSelectorTerm, []string, error) {
+	name := info.Claim.Namespace + "/" + info.Claim.Name
+
+	terms, err := info.TopologyTerms(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to determine the topology of the %s PVC %s: %w", side, name, err)
+	}
+
+	nodes, err := info.ClusterClient.KubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list the nodes of the cluster of the %s PVC: %w", side, err)
+	}
+
+	var nodeNames []string
+
+	for _, node := range nodes.Items {
+		if pvc.MatchNodeSelectorTerms(&node, terms) {
+			nodeNames = append(nodeNames, node.Name)
+		}
+	}
+
+	if len(nodeNames) == 0 {
+		return nil, nil, fmt.Errorf("no node of the cluster can mount the %s PVC %s, it requires %s",
+			side, name, describeTerms(terms))
+	}
+
+	return terms, nodeNames, nil
+}
+
+func describeTerms(terms []corev1.NodeSelectorTerm) string {
+	if len(terms) == 0 {
+		return "no specific nodes"
+	}
+
+	return pvc.FormatNodeSelectorTerms(terms)
+}
+
+// formatNodes returns the names of the nodes, up to maxLoggedNodes of them.
+func formatNodes(nodes []string) string {
+	if len(nodes) > maxLoggedNodes {
+		return fmt.Sprintf("%s and %d more nodes", strings.Join(nodes[:maxLoggedNodes], ", "),
+			len(nodes)-maxLoggedNodes)
+	}
+
+	return strings.Join(nodes, ", ")
+}
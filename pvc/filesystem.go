@@ -0,0 +1,54 @@
+package pvc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// storageClassFSTypeParameters are the storage class parameters the filesystem type is commonly given with.
+var storageClassFSTypeParameters = []string{"csi.storage.k8s.io/fstype", "fsType", "fstype"}
+
+// FilesystemType returns the type of the filesystem of the volume of the PVC, e.g. ext4 or xfs,
+// as declared by its bound PersistentVolume or its StorageClass.
+//
+// It returns an empty string if the type is not declared, e.g., for the volumes using the default
+// filesystem of their CSI driver, or for the network filesystems.
+func (i *Info) FilesystemType(ctx context.Context) (string, error) {
+	if volumeName := i.Claim.Spec.VolumeName; volumeName != "" {
+		pv, err := i.ClusterClient.KubeClient.CoreV1().PersistentVolumes().Get(ctx, volumeName, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get persistent volume %s: %w", volumeName, err)
+		}
+
+		if csi := pv.Spec.CSI; csi != nil && csi.FSType != "" {
+			return strings.ToLower(csi.FSType), nil
+		}
+	}
+
+	storageClassName := i.Claim.Spec.StorageClassName
+	if storageClassName == nil || *storageClassName == "" {
+		return "", nil
+	}
+
+	storageClass, err := i.ClusterClient.KubeClient.StorageV1().StorageClasses().
+		Get(ctx, *storageClassName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to get storage class %s: %w", *storageClassName, err)
+	}
+
+	for _, parameter := range storageClassFSTypeParameters {
+		if fsType := storageClass.Parameters[parameter]; fsType != "" {
+			return strings.ToLower(fsType), nil
+		}
+	}
+
+	return "", nil
+}
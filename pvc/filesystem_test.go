@@ -0,0 +1,45 @@
+package pvc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+func TestFilesystemType(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	clusterClient := buildClusterClient("", corev1.ReadWriteOnce)
+
+	pvcInfo, err := pvc.New(ctx, clusterClient, "testns", "test")
+	require.NoError(t, err)
+
+	fsType, err := pvcInfo.FilesystemType(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, fsType)
+
+	_, err = clusterClient.KubeClient.CoreV1().PersistentVolumes().Create(ctx, &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pv"},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: "csi.example.com", FSType: "XFS"},
+			},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	pvcInfo.Claim.Spec.VolumeName = "test-pv"
+
+	fsType, err = pvcInfo.FilesystemType(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "xfs", fsType)
+}
@@ -19,6 +19,7 @@ type Info struct {
 	SupportsRWO        bool
 	SupportsROX        bool
 	SupportsRWX        bool
+	BlockMode          bool
 }
 
 //nolint:cyclop
@@ -62,6 +63,7 @@ func New(ctx context.Context, client *k8s.ClusterClient, namespace string, name
 	}
 
 	required := !supportsRWX && !supportsROX
+	blockMode := claim.Spec.VolumeMode != nil && *claim.Spec.VolumeMode == corev1.PersistentVolumeBlock
 
 	affinityHelmValues := buildAffinityHelmValues(mountedNode, required)
 
@@ -73,6 +75,7 @@ func New(ctx context.Context, client *k8s.ClusterClient, namespace string, name
 		SupportsRWO:        supportsRWO,
 		SupportsROX:        supportsROX,
 		SupportsRWX:        supportsRWX,
+		BlockMode:          blockMode,
 	}, nil
 }
 
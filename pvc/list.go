@@ -0,0 +1,91 @@
+package pvc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Summary is the summary of a PVC as a candidate for a migration.
+type Summary struct {
+	Namespace    string   `json:"namespace"`
+	Name         string   `json:"name"`
+	Phase        string   `json:"phase"`
+	Size         string   `json:"size"`
+	AccessModes  []string `json:"accessModes"`
+	StorageClass string   `json:"storageClass"`
+	// MountedBy are the names of the pods mounting the PVC which are not terminated.
+	MountedBy []string `json:"mountedBy"`
+}
+
+// List returns the summaries of the PVCs in the namespace, or in all namespaces if it is empty,
+// sorted by their namespaces and names.
+func List(ctx context.Context, kubeClient kubernetes.Interface, namespace string) ([]Summary, error) {
+	claims, err := kubeClient.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVCs: %w", err)
+	}
+
+	pods, err := kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	mountedBy := make(map[string][]string)
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				key := pod.Namespace + "/" + volume.PersistentVolumeClaim.ClaimName
+				mountedBy[key] = append(mountedBy[key], pod.Name)
+			}
+		}
+	}
+
+	summaries := make([]Summary, 0, len(claims.Items))
+
+	for _, claim := range claims.Items {
+		size, ok := claim.Status.Capacity[corev1.ResourceStorage]
+		if !ok {
+			size = claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		}
+
+		accessModes := make([]string, 0, len(claim.Spec.AccessModes))
+		for _, accessMode := range claim.Spec.AccessModes {
+			accessModes = append(accessModes, string(accessMode))
+		}
+
+		var storageClass string
+		if claim.Spec.StorageClassName != nil {
+			storageClass = *claim.Spec.StorageClassName
+		}
+
+		summaries = append(summaries, Summary{
+			Namespace:    claim.Namespace,
+			Name:         claim.Name,
+			Phase:        string(claim.Status.Phase),
+			Size:         size.String(),
+			AccessModes:  accessModes,
+			StorageClass: storageClass,
+			MountedBy:    mountedBy[claim.Namespace+"/"+claim.Name],
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Namespace != summaries[j].Namespace {
+			return summaries[i].Namespace < summaries[j].Namespace
+		}
+
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	return summaries, nil
+}
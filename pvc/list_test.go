@@ -0,0 +1,39 @@
+package pvc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	clusterClient := buildClusterClient("node-2", corev1.ReadWriteOnce)
+
+	summaries, err := pvc.List(ctx, clusterClient.KubeClient, "testns")
+	require.NoError(t, err)
+
+	require.Len(t, summaries, 1)
+	assert.Equal(t, pvc.Summary{
+		Namespace:   "testns",
+		Name:        "test",
+		Size:        "0",
+		AccessModes: []string{"ReadWriteOnce"},
+		MountedBy:   []string{"pod2"},
+	}, summaries[0])
+
+	summaries, err = pvc.List(ctx, buildClusterClient("").KubeClient, "")
+	require.NoError(t, err)
+
+	require.Len(t, summaries, 1)
+	assert.Empty(t, summaries[0].MountedBy)
+}
@@ -0,0 +1,231 @@
+package pvc
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// nodeNameField is the only field of the nodes the node selector terms can match, with the In and NotIn operators.
+const nodeNameField = "metadata.name"
+
+// nodeSelectorOperators maps the operators of the node selector requirements to those of the label selectors.
+var nodeSelectorOperators = map[corev1.NodeSelectorOperator]selection.Operator{
+	corev1.NodeSelectorOpIn:           selection.In,
+	corev1.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1.NodeSelectorOpExists:       selection.Exists,
+	corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+// RespectVolumeTopology requires the migration pods of the PVC to be scheduled on the nodes matching
+// the node affinity of its bound PersistentVolume, e.g., the zone of a zonal volume.
+//
+// If the PVC is mounted to a node and can only be mounted on a single node, the existing affinity to that node
+// already satisfies the topology of the volume, so it is kept as is.
+//
+// It returns the node selector terms of the volume, which are empty if it has no topology constraints.
+func (i *Info) RespectVolumeTopology(ctx context.Context) ([]corev1.NodeSelectorTerm, error) {
+	volumeName := i.Claim.Spec.VolumeName
+	if volumeName == "" {
+		return nil, nil
+	}
+
+	pv, err := i.ClusterClient.KubeClient.CoreV1().PersistentVolumes().Get(ctx, volumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get persistent volume %s: %w", volumeName, err)
+	}
+
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil ||
+		len(pv.Spec.NodeAffinity.Required.NodeSelectorTerms) == 0 {
+		return nil, nil
+	}
+
+	terms := pv.Spec.NodeAffinity.Required.NodeSelectorTerms
+
+	nodeAffinity, _ := i.AffinityHelmValues["nodeAffinity"].(map[string]any)
+	if _, ok := nodeAffinity["requiredDuringSchedulingIgnoredDuringExecution"]; ok {
+		return terms, nil
+	}
+
+	required, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pv.Spec.NodeAffinity.Required)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the node affinity of persistent volume %s: %w", volumeName, err)
+	}
+
+	newNodeAffinity := map[string]any{
+		"requiredDuringSchedulingIgnoredDuringExecution": required,
+	}
+
+	if preferred, ok := nodeAffinity["preferredDuringSchedulingIgnoredDuringExecution"]; ok {
+		newNodeAffinity["preferredDuringSchedulingIgnoredDuringExecution"] = preferred
+	}
+
+	i.AffinityHelmValues = map[string]any{
+		"nodeAffinity": newNodeAffinity,
+	}
+
+	return terms, nil
+}
+
+// FormatNodeSelectorTerms returns a human-readable representation of the node selector terms.
+func FormatNodeSelectorTerms(terms []corev1.NodeSelectorTerm) string {
+	formattedTerms := make([]string, 0, len(terms))
+
+	for _, term := range terms {
+		requirements := make([]string, 0, len(term.MatchExpressions)+len(term.MatchFields))
+
+		for _, req := range slices.Concat(term.MatchExpressions, term.MatchFields) {
+			requirements = append(requirements,
+				fmt.Sprintf("%s %s (%s)", req.Key, strings.ToLower(string(req.Operator)), strings.Join(req.Values, ",")))
+		}
+
+		formattedTerms = append(formattedTerms, strings.Join(requirements, " and "))
+	}
+
+	return strings.Join(formattedTerms, " or ")
+}
+
+// TopologyTerms returns the node selector terms of the nodes the PVC can be mounted on, i.e., the node affinity
+// of its bound PersistentVolume, or the allowed topologies of its StorageClass if it is not bound yet.
+// If the PVC is mounted to a node and can only be mounted on a single node, the terms are limited to that node.
+//
+// It returns nil if the PVC can be mounted on any node.
+func (i *Info) TopologyTerms(ctx context.Context) ([]corev1.NodeSelectorTerm, error) {
+	terms, err := i.volumeTopologyTerms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.MountedNode == "" || i.SupportsRWX || i.SupportsROX {
+		return terms, nil
+	}
+
+	mountedNode := corev1.NodeSelectorRequirement{
+		Key:      nodeNameField,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{i.MountedNode},
+	}
+
+	if len(terms) == 0 {
+		return []corev1.NodeSelectorTerm{{MatchFields: []corev1.NodeSelectorRequirement{mountedNode}}}, nil
+	}
+
+	for index := range terms {
+		terms[index].MatchFields = append(slices.Clone(terms[index].MatchFields), mountedNode)
+	}
+
+	return terms, nil
+}
+
+func (i *Info) volumeTopologyTerms(ctx context.Context) ([]corev1.NodeSelectorTerm, error) {
+	kubeClient := i.ClusterClient.KubeClient
+
+	if volumeName := i.Claim.Spec.VolumeName; volumeName != "" {
+		pv, err := kubeClient.CoreV1().PersistentVolumes().Get(ctx, volumeName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get persistent volume %s: %w", volumeName, err)
+		}
+
+		if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+			return nil, nil
+		}
+
+		return slices.Clone(pv.Spec.NodeAffinity.Required.NodeSelectorTerms), nil
+	}
+
+	storageClassName := i.Claim.Spec.StorageClassName
+	if storageClassName == nil || *storageClassName == "" {
+		return nil, nil
+	}
+
+	storageClass, err := kubeClient.StorageV1().StorageClasses().Get(ctx, *storageClassName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to get storage class %s: %w", *storageClassName, err)
+	}
+
+	terms := make([]corev1.NodeSelectorTerm, 0, len(storageClass.AllowedTopologies))
+
+	for _, topology := range storageClass.AllowedTopologies {
+		var term corev1.NodeSelectorTerm
+
+		for _, expression := range topology.MatchLabelExpressions {
+			term.MatchExpressions = append(term.MatchExpressions, corev1.NodeSelectorRequirement{
+				Key:      expression.Key,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   expression.Values,
+			})
+		}
+
+		terms = append(terms, term)
+	}
+
+	return terms, nil
+}
+
+// MatchNodeSelectorTerms returns whether the node matches any of the node selector terms, i.e., all the requirements
+// of one of them, as the scheduler does. A node matches the empty list of terms.
+func MatchNodeSelectorTerms(node *corev1.Node, terms []corev1.NodeSelectorTerm) bool {
+	if len(terms) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(terms, func(term corev1.NodeSelectorTerm) bool {
+		return matchNodeSelectorTerm(node, term)
+	})
+}
+
+func matchNodeSelectorTerm(node *corev1.Node, term corev1.NodeSelectorTerm) bool {
+	// a term without any requirements matches no nodes
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+
+	for _, req := range term.MatchExpressions {
+		operator, ok := nodeSelectorOperators[req.Operator]
+		if !ok {
+			return false
+		}
+
+		requirement, err := labels.NewRequirement(req.Key, operator, req.Values)
+		if err != nil || !requirement.Matches(labels.Set(node.Labels)) {
+			return false
+		}
+	}
+
+	for _, req := range term.MatchFields {
+		if req.Key != nodeNameField {
+			return false
+		}
+
+		matches := slices.Contains(req.Values, node.Name)
+
+		switch req.Operator { //nolint:exhaustive
+		case corev1.NodeSelectorOpIn:
+			if !matches {
+				return false
+			}
+		case corev1.NodeSelectorOpNotIn:
+			if matches {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
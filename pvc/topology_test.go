@@ -0,0 +1,152 @@
+package pvc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+func TestRespectVolumeTopology(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	clusterClient := buildClusterClient("", corev1.ReadWriteOnce)
+	pvcClient := clusterClient.KubeClient.CoreV1().PersistentVolumeClaims("testns")
+
+	claim, err := pvcClient.Get(ctx, "test", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	claim.Spec.VolumeName = "test-pv"
+
+	_, err = pvcClient.Update(ctx, claim, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	_, err = clusterClient.KubeClient.CoreV1().PersistentVolumes().Create(ctx, &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pv"},
+		Spec: corev1.PersistentVolumeSpec{
+			NodeAffinity: &corev1.VolumeNodeAffinity{
+				Required: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: []corev1.NodeSelectorRequirement{
+							{
+								Key:      corev1.LabelTopologyZone,
+								Operator: corev1.NodeSelectorOpIn,
+								Values:   []string{"zone-a", "zone-b"},
+							},
+						}},
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	pvcInfo, err := pvc.New(ctx, clusterClient, "testns", "test")
+	require.NoError(t, err)
+	assert.Nil(t, pvcInfo.AffinityHelmValues)
+
+	terms, err := pvcInfo.RespectVolumeTopology(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "topology.kubernetes.io/zone in (zone-a,zone-b)", pvc.FormatNodeSelectorTerms(terms))
+	assert.Equal(t, map[string]any{
+		"nodeAffinity": map[string]any{
+			"requiredDuringSchedulingIgnoredDuringExecution": map[string]any{
+				"nodeSelectorTerms": []any{
+					map[string]any{
+						"matchExpressions": []any{
+							map[string]any{
+								"key":      "topology.kubernetes.io/zone",
+								"operator": "In",
+								"values":   []any{"zone-a", "zone-b"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, pvcInfo.AffinityHelmValues)
+}
+
+func TestTopologyTerms(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	clusterClient := buildClusterClient("node-2", corev1.ReadWriteOnce)
+	pvcClient := clusterClient.KubeClient.CoreV1().PersistentVolumeClaims("testns")
+
+	claim, err := pvcClient.Get(ctx, "test", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	claim.Spec.StorageClassName = ptr.To("zonal")
+
+	_, err = pvcClient.Update(ctx, claim, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	_, err = clusterClient.KubeClient.StorageV1().StorageClasses().Create(ctx, &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "zonal"},
+		AllowedTopologies: []corev1.TopologySelectorTerm{
+			{MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{
+				{Key: corev1.LabelTopologyZone, Values: []string{"zone-a"}},
+			}},
+		},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	pvcInfo, err := pvc.New(ctx, clusterClient, "testns", "test")
+	require.NoError(t, err)
+
+	terms, err := pvcInfo.TopologyTerms(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "topology.kubernetes.io/zone in (zone-a) and metadata.name in (node-2)",
+		pvc.FormatNodeSelectorTerms(terms))
+}
+
+func TestMatchNodeSelectorTerms(t *testing.T) {
+	t.Parallel()
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "node-1",
+		Labels: map[string]string{corev1.LabelTopologyZone: "zone-a"},
+	}}
+
+	inZone := func(zone string) corev1.NodeSelectorRequirement {
+		return corev1.NodeSelectorRequirement{
+			Key:      corev1.LabelTopologyZone,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{zone},
+		}
+	}
+
+	notOnNode := corev1.NodeSelectorRequirement{
+		Key:      "metadata.name",
+		Operator: corev1.NodeSelectorOpNotIn,
+		Values:   []string{"node-1"},
+	}
+
+	assert.True(t, pvc.MatchNodeSelectorTerms(node, nil))
+	assert.True(t, pvc.MatchNodeSelectorTerms(node, []corev1.NodeSelectorTerm{
+		{MatchExpressions: []corev1.NodeSelectorRequirement{inZone("zone-b")}},
+		{MatchExpressions: []corev1.NodeSelectorRequirement{inZone("zone-a")}},
+	}))
+	assert.False(t, pvc.MatchNodeSelectorTerms(node, []corev1.NodeSelectorTerm{
+		{
+			MatchExpressions: []corev1.NodeSelectorRequirement{inZone("zone-a")},
+			MatchFields:      []corev1.NodeSelectorRequirement{notOnNode},
+		},
+	}))
+	assert.False(t, pvc.MatchNodeSelectorTerms(node, []corev1.NodeSelectorTerm{{}}), "an empty term matches no nodes")
+}
@@ -0,0 +1,205 @@
+package result
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+type Status string
+
+const (
+	StatusSucceeded  Status = "succeeded"
+	StatusFailed     Status = "failed"
+	StatusUnaccepted Status = "unaccepted"
+
+	// fileMode allows the result to be read by the other containers, e.g., a controller sharing the volume.
+	fileMode = 0o644
+)
+
+// Result is the JSON document written to the result file at the end of a migration.
+type Result struct {
+	Status          Status    `json:"status"`
+	Error           string    `json:"error,omitempty"`
+	Source          string    `json:"source"`
+	Dest            string    `json:"dest"`
+	StartTime       time.Time `json:"startTime"`
+	EndTime         time.Time `json:"endTime"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	Attempts        []Attempt `json:"attempts"`
+}
+
+// Attempt is the result of an attempt of the migration with a strategy.
+type Attempt struct {
+	Strategy  string `json:"strategy"`
+	AttemptID string `json:"attemptId"`
+	Status    Status `json:"status"`
+	Error     string `json:"error,omitempty"`
+	// RsyncExitCode is the exit code of rsync, if the attempt failed with rsync exiting with an error.
+	RsyncExitCode *int   `json:"rsyncExitCode,omitempty"`
+	Stats         *Stats `json:"stats,omitempty"`
+}
+
+// Stats is the last reported progress of the transfer, i.e., partial if the attempt failed.
+type Stats struct {
+	TransferredBytes int64 `json:"transferredBytes"`
+	TotalBytes       int64 `json:"totalBytes"`
+	Percentage       int   `json:"percentage"`
+	FilesDone        int64 `json:"filesDone,omitempty"`
+	FilesTotal       int64 `json:"filesTotal,omitempty"`
+}
+
+// Recorder records the result of a migration and writes it to a file as JSON.
+//
+// A nil Recorder is valid and does nothing.
+type Recorder struct {
+	path string
+
+	lock   sync.Mutex
+	result Result
+}
+
+// New creates a Recorder for the migration from the source to the destination PVC, both in the form of "ns/name".
+func New(path, source, dest string) *Recorder {
+	return &Recorder{
+		path:   path,
+		result: Result{Source: source, Dest: dest},
+	}
+}
+
+func (r *Recorder) Started() {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.result = Result{
+		Source:    r.result.Source,
+		Dest:      r.result.Dest,
+		StartTime: time.Now(),
+		Attempts:  []Attempt{},
+	}
+}
+
+func (r *Recorder) AttemptStarted(strategy, attemptID string) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.result.Attempts = append(r.result.Attempts, Attempt{Strategy: strategy, AttemptID: attemptID})
+}
+
+// AttemptFinished records the outcome of the last started attempt.
+func (r *Recorder) AttemptFinished(status Status, err error) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	attempt := r.currentAttempt()
+	if attempt == nil {
+		return
+	}
+
+	attempt.Status = status
+
+	if err != nil && status == StatusFailed {
+		attempt.Error = err.Error()
+		attempt.RsyncExitCode = rsyncExitCode(err)
+	}
+}
+
+// ReportProgress implements progress.Reporter.
+func (r *Recorder) ReportProgress(_ context.Context, prg progress.Progress) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	attempt := r.currentAttempt()
+	if attempt == nil {
+		return
+	}
+
+	attempt.Stats = &Stats{
+		TransferredBytes: prg.Transferred,
+		TotalBytes:       prg.Total,
+		Percentage:       prg.Percentage,
+		FilesDone:        prg.FilesDone,
+		FilesTotal:       prg.FilesTotal,
+	}
+}
+
+// Finished writes the result of the migration, failed if err is not nil, to the file.
+func (r *Recorder) Finished(err error) error {
+	if r == nil {
+		return nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.result.EndTime = time.Now()
+	r.result.DurationSeconds = r.result.EndTime.Sub(r.result.StartTime).Seconds()
+	r.result.Status = StatusSucceeded
+	r.result.Error = ""
+
+	if err != nil {
+		r.result.Status = StatusFailed
+		r.result.Error = err.Error()
+	}
+
+	data, err := json.MarshalIndent(r.result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	if err = os.WriteFile(r.path, append(data, '\n'), fileMode); err != nil {
+		return fmt.Errorf("failed to write result file: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Recorder) currentAttempt() *Attempt {
+	if len(r.result.Attempts) == 0 {
+		return nil
+	}
+
+	return &r.result.Attempts[len(r.result.Attempts)-1]
+}
+
+// rsyncExitCode returns the exit code of rsync if the error is caused by rsync exiting with an error,
+// either in the rsync pod or locally over ssh.
+func rsyncExitCode(err error) *int {
+	var jobErr *k8s.JobFailedError
+	if errors.As(err, &jobErr) && jobErr.ExitCode >= 0 {
+		return &jobErr.ExitCode
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+
+		return &code
+	}
+
+	return nil
+}
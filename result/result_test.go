@@ -0,0 +1,79 @@
+package result_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/result"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "result.json")
+	recorder := result.New(path, "ns1/pvc1", "ns2/pvc2")
+
+	recorder.Started()
+
+	recorder.AttemptStarted("mnt2", "abcde")
+	recorder.AttemptFinished(result.StatusUnaccepted, errors.New("unaccepted"))
+
+	recorder.AttemptStarted("svc", "fghij")
+	recorder.ReportProgress(context.Background(), progress.Progress{Percentage: 42, Transferred: 42, Total: 100})
+
+	jobErr := fmt.Errorf("failed to wait for job completion: %w",
+		&k8s.JobFailedError{Namespace: "ns2", Name: "pv-migrate-fghij-rsync", ExitCode: 23})
+	recorder.AttemptFinished(result.StatusFailed, jobErr)
+
+	require.NoError(t, recorder.Finished(errors.New("all strategies failed for this migration")))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var res result.Result
+	require.NoError(t, json.Unmarshal(data, &res))
+
+	assert.Equal(t, result.StatusFailed, res.Status)
+	assert.Equal(t, "all strategies failed for this migration", res.Error)
+	assert.Equal(t, "ns1/pvc1", res.Source)
+	assert.Equal(t, "ns2/pvc2", res.Dest)
+	require.Len(t, res.Attempts, 2)
+
+	assert.Equal(t, "mnt2", res.Attempts[0].Strategy)
+	assert.Equal(t, result.StatusUnaccepted, res.Attempts[0].Status)
+	assert.Empty(t, res.Attempts[0].Error)
+	assert.Nil(t, res.Attempts[0].RsyncExitCode)
+
+	assert.Equal(t, "svc", res.Attempts[1].Strategy)
+	assert.Equal(t, "fghij", res.Attempts[1].AttemptID)
+	assert.Equal(t, result.StatusFailed, res.Attempts[1].Status)
+	assert.Contains(t, res.Attempts[1].Error, "failed with exit code 23")
+	require.NotNil(t, res.Attempts[1].RsyncExitCode)
+	assert.Equal(t, 23, *res.Attempts[1].RsyncExitCode)
+	require.NotNil(t, res.Attempts[1].Stats)
+	assert.Equal(t, int64(42), res.Attempts[1].Stats.TransferredBytes)
+	assert.Equal(t, 42, res.Attempts[1].Stats.Percentage)
+}
+
+func TestRecorderNil(t *testing.T) {
+	t.Parallel()
+
+	var recorder *result.Recorder
+
+	recorder.Started()
+	recorder.AttemptStarted("mnt2", "abcde")
+	recorder.ReportProgress(context.Background(), progress.Progress{})
+	recorder.AttemptFinished(result.StatusSucceeded, nil)
+
+	assert.NoError(t, recorder.Finished(nil))
+}
@@ -3,13 +3,108 @@ package rsync
 import (
 	"errors"
 	"fmt"
+	"net"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
+const (
+	// jobCompletionIndexEnv is the environment variable set by Kubernetes on the pods of Indexed Jobs.
+	jobCompletionIndexEnv = "JOB_COMPLETION_INDEX"
+
+	// listedEntriesPath and listedFilesPath are the files on the side running rsync the listed top-level entries
+	// of the source path and the NUL-separated paths passed to rsync are written to, for a failed listing
+	// to fail the command instead of resulting in an empty transfer.
+	listedEntriesPath = "/tmp/pv-migrate-entries"
+	listedFilesPath   = "/tmp/pv-migrate-files"
+	// sinceReferencePath is the file on the side of the source whose modification time is set to Since,
+	// for the modified files to be listed with "find -newer", which unlike "-newermt" is supported by busybox.
+	sinceReferencePath = "/tmp/pv-migrate-since"
+	// nulFilterFilePath is the copy of the filter file with NUL-separated rules, as "--from0" applies to
+	// the merged filter files as well.
+	nulFilterFilePath = "/tmp/pv-migrate-filter"
+
+	sshConnectRetryPeriodSeconds = 2
+
+	// HostKeyAlias is the name the host key of the remote SSH server is looked up by in the KnownHostsFile,
+	// instead of its host name and port, which are not always known when the file is created.
+	HostKeyAlias = "pv-migrate-sshd"
+
+	// DefaultConnectTimeoutSeconds is the timeout of the SSH connections used when Cmd.ConnectTimeout is nil.
+	DefaultConnectTimeoutSeconds = 5
+
+	// minVersion is the oldest version of rsync supporting the flags the command is always built with,
+	// i.e., --info=progress2 and --no-inc-recursive.
+	minVersion = "3.1.0"
+
+	// featureCheckScript is the awk script checking the output of "rsync --version" against the minimum version
+	// and the required capabilities, which are listed by rsync with a "no " prefix when they are missing.
+	featureCheckScript = `function num(s, parts) { split(s, parts, "."); ` +
+		`return parts[1] * 1000000 + parts[2] * 1000 + parts[3] } ` +
+		`NR == 1 { version = $3; next } ` +
+		`{ gsub(/,/, " "); for (i = 1; i <= NF; i++) { if ($i == "no") { i++; continue }; have[$i] = 1 } } ` +
+		`END { if (version == "") { print "rsync feature check failed: cannot determine the rsync version"; exit 1 } ` +
+		`if (num(version) < num(min)) { print "rsync feature check failed: rsync " version ` +
+		`" is older than the required " min; exit 1 } ` +
+		`n = split(features, required, " "); for (i = 1; i <= n; i++) { if (!(required[i] in have)) { ` +
+		`print "rsync feature check failed: rsync " version " lacks the " required[i] " capability"; exit 1 } } }`
+
+	// bwLimitScheduleScript is the awk script printing the bandwidth limit of the window the current UTC time,
+	// given as "HH MM SS", falls in, and the number of seconds until the next boundary of a window.
+	// The windows are passed as a comma-separated list of START-END=LIMIT, in the minutes of the day.
+	bwLimitScheduleScript = `{ m = $1 * 60 + $2; limit = 0; next_b = 1440; n = split(windows, w, ","); ` +
+		`for (i = 1; i <= n; i++) { split(w[i], p, "[-=]"); ` +
+		`if ((p[1] < p[2] && m >= p[1] && m < p[2]) || (p[1] >= p[2] && (m >= p[1] || m < p[2]))) limit = p[3]; ` +
+		`for (j = 1; j <= 2; j++) { d = (p[j] - m + 1440) % 1440; if (d > 0 && d < next_b) next_b = d } } ` +
+		`print limit, next_b * 60 - $3 }`
+
+	// bwLimitScheduleFunc is the name of the shell function running rsync with the scheduled bandwidth limits.
+	bwLimitScheduleFunc = "rsync_bwlimit_schedule"
+
+	minutesPerDay = 24 * 60
+
+	// AutoCompressThreshold is the throughput of the link in bytes per second, i.e., 100 Mbit/s, below which
+	// AutoCompress enables the compression, as compressing the data is then faster than transferring it as is.
+	AutoCompressThreshold = 12_500_000
+
+	// autoCompressProbeBytes is the amount of random data transferred to measure the throughput of the link.
+	autoCompressProbeBytes = 8 << 20
+	// autoCompressProbeTimeoutSeconds limits the duration of the probe on slow links.
+	autoCompressProbeTimeoutSeconds = 30
+	// autoCompressVar is the shell variable the probe sets to the compression flag of rsync, if it is to be enabled.
+	autoCompressVar = "auto_compress"
+)
+
+// chmodItemRegex matches a single item of the comma-separated --chmod spec of rsync,
+// in either symbolic (e.g. "g+w", "Fu=rw,go=r") or octal (e.g. "D2775") form.
+var chmodItemRegex = regexp.MustCompile(`^[DF]?([ugoa]*[-+=][rwxXst]*|[0-7]{3,4})$`)
+
+// iconvRegex matches the --iconv spec of rsync, i.e., the local and the remote charsets separated by a comma,
+// or "." to use the charset of the locale on both sides.
+var iconvRegex = regexp.MustCompile(`^(\.|[A-Za-z0-9][A-Za-z0-9._:+-]*,[A-Za-z0-9][A-Za-z0-9._:+-]*)$`)
+
+// bwLimitRegex matches the bandwidth limit passed to the --bwlimit flag of rsync, in KiB per second by default,
+// or with a K, M or G suffix.
+var bwLimitRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[KMGkmg]?$`)
+
+// BwLimitWindow is a daily time window with the bandwidth limit of rsync in it.
+type BwLimitWindow struct {
+	// Start and End are the minutes of the day in UTC the window starts at and ends before.
+	// The window wraps around midnight if End is not after Start.
+	Start int
+	End   int
+	// Limit is the bandwidth limit in the format of the --bwlimit flag of rsync, "0" meaning no limit.
+	Limit string
+}
+
 type Cmd struct {
 	Port        int
 	NoChown     bool
+	NoOwner     bool
+	NoGroup     bool
 	Delete      bool
 	SrcUseSSH   bool
 	DestUseSSH  bool
@@ -21,6 +116,121 @@ type Cmd struct {
 	DestSSHHost string
 	DestPath    string
 	Compress    bool
+	Itemize     bool
+	// SrcUseDaemon connects to the source with the rsync protocol of an rsync daemon listening on SrcSSHHost
+	// instead of SSH, as the user SrcSSHUser. The source path is then relative to the module SrcDaemonModule,
+	// and Port is the port of the daemon. It cannot be combined with Parallel.
+	SrcUseDaemon    bool
+	SrcDaemonModule string
+	// Parallel is the number of rsync streams the transfer is split into, each running in a pod of an
+	// Indexed Job. When it is greater than 1, the top-level entries of the source path are distributed
+	// across the streams by the completion index of the pod.
+	Parallel int
+	// SSHConnectRetries is the number of times to retry establishing the SSH connection before running rsync.
+	// It is separate from the retries of the data transfer, which are done by the rsync job itself.
+	SSHConnectRetries int
+	// BlockSize is the block size used by the delta-transfer algorithm of rsync, in bytes.
+	// Zero leaves it to rsync to choose.
+	BlockSize int
+	// HardLinks preserves the hard links, at the cost of rsync keeping track of them in memory.
+	HardLinks bool
+	// OmitDirTimes does not preserve the modification times of the directories, which are then set by
+	// the changes to their entries on the destination, e.g., the creations and the deletions.
+	OmitDirTimes bool
+	// DirsOnly only transfers the directories with their metadata, excluding everything else with the filter rules
+	// "+ */" and "- *", which come after the other rules. As excluded files, the files on the destination
+	// are protected from the deletions of Delete.
+	DirsOnly bool
+	// NumericIDs transfers the numeric user and group IDs instead of mapping them by name.
+	NumericIDs bool
+	// IOTimeout is the number of seconds after which rsync aborts if no data is transferred. Zero disables it.
+	IOTimeout int
+	// ConnectTimeout is the number of seconds to wait for the connection to the remote side to be established,
+	// i.e., the ConnectTimeout option of ssh, or the --contimeout flag of rsync with SrcUseDaemon.
+	// Nil means DefaultConnectTimeoutSeconds for ssh and no timeout for the rsync daemon, zero disables it.
+	ConnectTimeout *int
+	// FilesFrom is the path of the file listing the paths to transfer, relative to the source path,
+	// or "-" to read them from the standard input. The directories in the list are not recursed into.
+	FilesFrom string
+	// Since limits the transfer to the files modified after it, when it is not zero. As rsync cannot filter
+	// the files by their modification time, they are listed by running "find -newer" on the side of the source
+	// against a file touched with the time, and the list is passed to rsync with "--files-from".
+	// It cannot be combined with FilesFrom, Parallel or SrcUseDaemon.
+	// The directories are only created as the parents of the listed files, and the deleted files are not detected.
+	Since time.Time
+	// FilterFile is the path of the rsync filter file to merge the filter rules from.
+	FilterFile string
+	// ExcludeLostFound excludes the lost+found directory at the root of the source path, created by mkfs
+	// on ext filesystems, from the transfer. As an excluded file, it is also protected from the deletions of Delete.
+	// The rules of FilterFile take precedence over it.
+	ExcludeLostFound bool
+	// Chmod is the spec of the permissions to apply to the transferred files on top of the preserved ones.
+	// See ValidateChmod for its syntax.
+	Chmod string
+	// Iconv is the spec of the charset conversion of the file names, in the form of "LOCAL,REMOTE".
+	// See ValidateIconv for its syntax.
+	Iconv string
+	// SELinuxContexts transfers the SELinux contexts of the files, i.e., their security.selinux extended attributes,
+	// and no other extended attributes. Setting them requires rsync running as root on the destination, and
+	// the SELinux policy to allow it to relabel the files.
+	SELinuxContexts bool
+	// Update skips the files which are newer on the destination than on the source.
+	Update bool
+	// IgnoreExisting skips the files which already exist on the destination.
+	IgnoreExisting bool
+	// WholeFile forces the files to be sent whole if true, or with the delta-transfer algorithm if false.
+	// When it is nil, rsync decides, i.e., the delta-transfer algorithm is used unless both sides are local.
+	WholeFile *bool
+	// MaxDelete is the maximum number of files to delete with Delete, when it is not nil. When it is hit,
+	// rsync skips the rest of the deletions and exits with the code 25.
+	MaxDelete *int
+	// PartialDir is the directory to keep the partially transferred files in, to be resumed by a later run,
+	// relative to the directory of each file. rsync excludes it from the transfer and the deletions.
+	PartialDir string
+	// DelayUpdates puts the updated files into place only at the end of the transfer,
+	// keeping them in temporary files on the destination until then.
+	DelayUpdates bool
+	// Protocol pins the version of the rsync protocol to use. Zero lets the two sides negotiate it.
+	Protocol int
+	// SockOpts are the TCP socket options to set on the connections rsync opens, passed to rsync as is.
+	// It must not contain single quotes.
+	SockOpts string
+	// CompareDest is the path of the reference directory on the destination side to compare the files with,
+	// skipping those identical to the files in it. Before the transfer, the command fails with a
+	// "compare-dest ... is not a directory" line if it does not exist. It must not contain whitespace or quotes.
+	CompareDest string
+	// Backup keeps the files overwritten or deleted on the destination, renamed with a "~" suffix in place,
+	// or moved to BackupDir if it is set. Without BackupDir, rsync does not preserve the directory times.
+	Backup bool
+	// BackupDir is the path of the directory on the destination side to move the files overwritten or deleted
+	// on the destination to, implying Backup. If it is inside the destination path, it is protected from
+	// the deletions. It must not contain whitespace or quotes.
+	BackupDir string
+	// DestMkdir creates the destination path with its parents before the transfer, as rsync only creates
+	// its last component. When DestUseSSH is set, it is created on the remote side using --rsync-path.
+	DestMkdir bool
+	// SSHCompression compresses the SSH connection, including the protocol messages of rsync, with the -C flag
+	// of ssh. Combined with Compress, the file data is compressed twice, which is usually slower.
+	SSHCompression bool
+	// AutoCompress measures the throughput of the link to the remote side before the transfer, and enables
+	// the compression only if it is below AutoCompressThreshold. The throughput can only be measured over SSH,
+	// so the compression is enabled as with Compress otherwise.
+	AutoCompress bool
+	// Verbosity is the number of -v flags to pass to rsync, from 1 to 3. Zero means 1.
+	Verbosity int
+	// KnownHostsFile is the path of the known_hosts file pinning the host key of the remote SSH server under
+	// HostKeyAlias. When it is set, ssh refuses to connect if the host key does not match. Otherwise,
+	// the host key is not verified.
+	KnownHostsFile string
+	// BwLimitSchedule are the daily windows with different bandwidth limits. When it is set, rsync is run with
+	// the limit of the current window, and restarted with the limit of the next one at its boundary, resuming
+	// the partially transferred files. There is no limit outside the windows. It requires the timeout command
+	// on the side running rsync, and cannot be combined with Parallel, Since or reading FilesFrom from stdin.
+	BwLimitSchedule []BwLimitWindow
+	// CheckFeatures checks the version of rsync and its capabilities required by the other options before
+	// the transfer, e.g., iconv for Iconv, to fail without retrying and with a "rsync feature check failed" line,
+	// instead of with a cryptic error of rsync. Only the rsync running the command is checked, not the remote one.
+	CheckFeatures bool
 }
 
 func (c *Cmd) Build() (string, error) {
@@ -28,14 +238,42 @@ func (c *Cmd) Build() (string, error) {
 		return "", errors.New("cannot use ssh on both source and destination")
 	}
 
+	if c.SrcUseDaemon && (c.SrcUseSSH || c.Parallel > 1) {
+		return "", errors.New("cannot use the rsync daemon on the source with ssh or in parallel")
+	}
+
+	if !c.Since.IsZero() && (c.SrcUseDaemon || c.Parallel > 1 || c.FilesFrom != "") {
+		return "", errors.New("cannot list the modified files with the rsync daemon, in parallel or with a files list")
+	}
+
+	if len(c.BwLimitSchedule) > 0 && (c.Parallel > 1 || !c.Since.IsZero() || c.FilesFrom == "-") {
+		return "", errors.New("cannot schedule the bandwidth limit in parallel, with the modified files " +
+			"or with a files list from stdin")
+	}
+
 	cmd := "rsync"
 	if c.Command != "" {
 		cmd = c.Command
 	}
 
-	sshArgs := []string{
-		"ssh", "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null",
-		"-o", "ConnectTimeout=5",
+	connectTimeout := DefaultConnectTimeoutSeconds
+	if c.ConnectTimeout != nil {
+		connectTimeout = *c.ConnectTimeout
+	}
+
+	sshArgs := []string{"ssh", "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null"}
+	if c.KnownHostsFile != "" {
+		sshArgs = []string{
+			"ssh", "-o", "StrictHostKeyChecking=yes", "-o", "UserKnownHostsFile=" + c.KnownHostsFile,
+			"-o", "HostKeyAlias=" + HostKeyAlias,
+		}
+	}
+
+	if connectTimeout > 0 {
+		sshArgs = append(sshArgs, "-o", "ConnectTimeout="+strconv.Itoa(connectTimeout))
+	}
+	if c.SSHCompression {
+		sshArgs = append(sshArgs, "-C")
 	}
 	if c.Port != 0 {
 		sshArgs = append(sshArgs, "-p", strconv.Itoa(c.Port))
@@ -43,34 +281,459 @@ func (c *Cmd) Build() (string, error) {
 
 	sshArgsStr := fmt.Sprintf("\"%s\"", strings.Join(sshArgs, " "))
 
-	rsyncArgs := []string{
-		"-av", "--info=progress2,misc0,flist0",
-		"--no-inc-recursive", "-e", sshArgsStr,
+	rsyncArgs := []string{"-a" + strings.Repeat("v", max(c.Verbosity, 1)), "--info=progress2,misc0,flist0",
+		"--no-inc-recursive"}
+
+	// with a remote shell, rsync would start a daemon over it instead of connecting to the running one
+	if !c.SrcUseDaemon {
+		rsyncArgs = append(rsyncArgs, "-e", sshArgsStr)
+	} else if c.ConnectTimeout != nil && *c.ConnectTimeout > 0 {
+		rsyncArgs = append(rsyncArgs, "--contimeout="+strconv.Itoa(connectTimeout))
 	}
 
-	if c.Compress {
+	autoCompress := c.AutoCompress && !c.Compress && (c.SrcUseSSH || c.DestUseSSH)
+
+	if c.Compress || (c.AutoCompress && !autoCompress) {
 		rsyncArgs = append(rsyncArgs, "-z")
+	} else if autoCompress {
+		rsyncArgs = append(rsyncArgs, "$"+autoCompressVar)
 	}
 
-	if c.NoChown {
-		rsyncArgs = append(rsyncArgs, "--no-o", "--no-g")
+	if c.NoChown || c.NoOwner {
+		rsyncArgs = append(rsyncArgs, "--no-o")
+	}
+
+	if c.NoChown || c.NoGroup {
+		rsyncArgs = append(rsyncArgs, "--no-g")
 	}
 
 	if c.Delete {
 		rsyncArgs = append(rsyncArgs, "--delete")
 	}
 
+	if c.MaxDelete != nil {
+		rsyncArgs = append(rsyncArgs, "--max-delete="+strconv.Itoa(*c.MaxDelete))
+	}
+
+	if c.Itemize {
+		rsyncArgs = append(rsyncArgs, "--itemize-changes")
+	}
+
+	if c.HardLinks {
+		rsyncArgs = append(rsyncArgs, "-H")
+	}
+
+	if c.OmitDirTimes {
+		rsyncArgs = append(rsyncArgs, "-O")
+	}
+
+	if c.NumericIDs {
+		rsyncArgs = append(rsyncArgs, "--numeric-ids")
+	}
+
+	if c.Update {
+		rsyncArgs = append(rsyncArgs, "-u")
+	}
+
+	if c.IgnoreExisting {
+		rsyncArgs = append(rsyncArgs, "--ignore-existing")
+	}
+
+	if c.WholeFile != nil {
+		if *c.WholeFile {
+			rsyncArgs = append(rsyncArgs, "-W")
+		} else {
+			rsyncArgs = append(rsyncArgs, "--no-W")
+		}
+	}
+
+	if c.DelayUpdates {
+		rsyncArgs = append(rsyncArgs, "--delay-updates")
+	}
+
+	if c.PartialDir != "" {
+		rsyncArgs = append(rsyncArgs, "--partial-dir="+c.PartialDir)
+	} else if len(c.BwLimitSchedule) > 0 {
+		// rsync is interrupted at the boundaries of the windows
+		rsyncArgs = append(rsyncArgs, "--partial")
+	}
+
+	// the listed paths are NUL-separated for the names containing newlines to be transferred intact
+	listsFiles := c.Parallel > 1 || !c.Since.IsZero()
+
+	if c.FilterFile != "" {
+		filterFile := c.FilterFile
+		if listsFiles {
+			filterFile = nulFilterFilePath
+		}
+
+		rsyncArgs = append(rsyncArgs, fmt.Sprintf("--filter='. %s'", filterFile))
+	}
+
+	if c.ExcludeLostFound {
+		rsyncArgs = append(rsyncArgs, "--exclude=/lost+found/")
+	}
+
+	if c.DirsOnly {
+		rsyncArgs = append(rsyncArgs, "--filter='+ */'", "--filter='- *'")
+	}
+
+	if c.Chmod != "" {
+		rsyncArgs = append(rsyncArgs, "--chmod="+c.Chmod)
+	}
+
+	if c.Iconv != "" {
+		rsyncArgs = append(rsyncArgs, "--iconv="+c.Iconv)
+	}
+
+	if c.SELinuxContexts {
+		// the first matching xattr rule applies, the others are neither transferred nor deleted
+		rsyncArgs = append(rsyncArgs, "-X", "--filter='+x security.selinux'", "--filter='-x *'")
+	}
+
+	if c.IOTimeout > 0 {
+		rsyncArgs = append(rsyncArgs, "--timeout="+strconv.Itoa(c.IOTimeout))
+	}
+
+	if c.Protocol > 0 {
+		rsyncArgs = append(rsyncArgs, "--protocol="+strconv.Itoa(c.Protocol))
+	}
+
+	if c.SockOpts != "" {
+		rsyncArgs = append(rsyncArgs, fmt.Sprintf("--sockopts='%s'", c.SockOpts))
+	}
+
+	if c.BlockSize > 0 {
+		rsyncArgs = append(rsyncArgs, "--block-size="+strconv.Itoa(c.BlockSize))
+	}
+
+	if c.CompareDest != "" {
+		rsyncArgs = append(rsyncArgs, "--compare-dest="+c.CompareDest)
+	}
+
+	if c.Backup || c.BackupDir != "" {
+		rsyncArgs = append(rsyncArgs, "-b")
+	}
+
+	if c.BackupDir != "" {
+		rsyncArgs = append(rsyncArgs, "--backup-dir="+c.BackupDir)
+
+		// otherwise, the backups of the previous runs would be deleted as extraneous files
+		if rel, found := strings.CutPrefix(path.Clean(c.BackupDir), path.Clean(c.DestPath)+"/"); found {
+			rsyncArgs = append(rsyncArgs, fmt.Sprintf("--filter='P /%s/'", rel))
+		}
+	}
+
+	destPrepare := c.buildDestPrepareCmd()
+	if destPrepare != "" && c.DestUseSSH {
+		rsyncArgs = append(rsyncArgs, fmt.Sprintf("--rsync-path='%s && rsync'", destPrepare))
+	}
+
+	if c.Parallel > 1 {
+		rsyncArgs = append(rsyncArgs, "-r", "--from0", "--files-from="+listedFilesPath)
+	} else if !c.Since.IsZero() {
+		rsyncArgs = append(rsyncArgs, "--from0", "--files-from="+listedFilesPath)
+	} else if c.FilesFrom != "" {
+		rsyncArgs = append(rsyncArgs, "--files-from="+c.FilesFrom)
+	}
+
 	rsyncArgsStr := strings.Join(rsyncArgs, " ")
 
 	src := c.buildSrc()
 	dest := c.buildDest()
 
-	return fmt.Sprintf("%s %s %s %s", cmd, rsyncArgsStr, src, dest), nil
+	invocation := cmd
+	// the extra args appended to the command by the chart are passed to rsync by the function as well
+	if len(c.BwLimitSchedule) > 0 {
+		invocation = bwLimitScheduleFunc + " " + cmd
+	}
+
+	result := fmt.Sprintf("%s %s %s %s", invocation, rsyncArgsStr, src, dest)
+	if c.Parallel > 1 {
+		result = fmt.Sprintf("%s > %s && xargs -0 sh -c '%s' sh < %s > %s && %s", c.buildListCmd(sshArgs),
+			listedEntriesPath, c.buildSplitScript(), listedEntriesPath, listedFilesPath, result)
+	}
+
+	if listsFiles && c.FilterFile != "" {
+		result = fmt.Sprintf("tr '\\n' '\\0' < %s > %s && %s", c.FilterFile, nulFilterFilePath, result)
+	}
+
+	if !c.Since.IsZero() {
+		result = fmt.Sprintf("%s > %s && %s", c.buildSinceListCmd(sshArgs), listedFilesPath, result)
+	}
+
+	if destPrepare != "" && !c.DestUseSSH {
+		result = destPrepare + " && " + result
+	}
+
+	if autoCompress {
+		result = c.buildAutoCompressProbe(sshArgs) + " && " + result
+	}
+
+	if c.SSHConnectRetries > 0 && (c.SrcUseSSH || c.DestUseSSH) {
+		result = c.buildSSHConnectCheck(sshArgs) + " && " + result
+	}
+
+	if c.CheckFeatures {
+		result = c.buildFeatureCheck(cmd) + " || exit 1; " + result
+	}
+
+	if len(c.BwLimitSchedule) > 0 {
+		result = c.buildBwLimitScheduleFunc() + "; " + result
+	}
+
+	return result, nil
+}
+
+// ValidateChmod validates the spec passed to the --chmod flag of rsync.
+//
+// It is a comma-separated list of items, each optionally prefixed by "D" or "F" to only apply to directories
+// or files, in either the symbolic form of chmod, e.g. "ug+rw", or the octal form, e.g. "644".
+func ValidateChmod(spec string) error {
+	for _, item := range strings.Split(spec, ",") {
+		if !chmodItemRegex.MatchString(item) {
+			return fmt.Errorf("invalid chmod item: %q", item)
+		}
+	}
+
+	return nil
+}
+
+// ValidateIconv validates the spec passed to the --iconv flag of rsync.
+//
+// It is either the charset of the side running rsync and the charset of the other side separated by a comma,
+// e.g. "UTF-8,ISO-8859-1", or "." to use the charset of the locale on both sides.
+func ValidateIconv(spec string) error {
+	if !iconvRegex.MatchString(spec) {
+		return fmt.Errorf("invalid iconv spec: %q, must be in the form of LOCAL,REMOTE", spec)
+	}
+
+	return nil
+}
+
+// ParseBwLimitSchedule parses the daily windows with different bandwidth limits of rsync.
+//
+// It is a comma-separated list of windows in the form of START-END=LIMIT, where START and END are times of the day
+// in UTC in the HH:MM format, and LIMIT is a bandwidth limit accepted by the --bwlimit flag of rsync,
+// e.g., "09:00-17:00=10M,17:00-19:00=50M". A window wraps around midnight if END is not after START.
+// The windows cannot overlap.
+func ParseBwLimitSchedule(spec string) ([]BwLimitWindow, error) {
+	var (
+		windows []BwLimitWindow
+		covered [minutesPerDay]bool
+	)
+
+	for _, item := range strings.Split(spec, ",") {
+		period, limit, found := strings.Cut(item, "=")
+		if !found || !bwLimitRegex.MatchString(limit) {
+			return nil, fmt.Errorf("invalid bandwidth limit window: %q, must be in the form of HH:MM-HH:MM=LIMIT", item)
+		}
+
+		startStr, endStr, found := strings.Cut(period, "-")
+		if !found {
+			return nil, fmt.Errorf("invalid bandwidth limit window: %q, must be in the form of HH:MM-HH:MM=LIMIT", item)
+		}
+
+		start, err := parseMinuteOfDay(startStr)
+		if err != nil {
+			return nil, err
+		}
+
+		end, err := parseMinuteOfDay(endStr)
+		if err != nil {
+			return nil, err
+		}
+
+		for minute := start; ; {
+			if covered[minute] {
+				return nil, fmt.Errorf("bandwidth limit window %q overlaps with another window", item)
+			}
+
+			covered[minute] = true
+
+			if minute = (minute + 1) % minutesPerDay; minute == end {
+				break
+			}
+		}
+
+		windows = append(windows, BwLimitWindow{Start: start, End: end, Limit: limit})
+	}
+
+	return windows, nil
+}
+
+func parseMinuteOfDay(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of the day: %q, must be in the HH:MM format", value)
+	}
+
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// buildBwLimitScheduleFunc builds the definition of the shell function which runs the command given as its arguments
+// with the bandwidth limit of the current window until the boundary of the window, and again with the limit
+// of the next window if it was interrupted by the boundary. It fails with the exit code of the command
+// if it fails before the boundary.
+func (c *Cmd) buildBwLimitScheduleFunc() string {
+	windows := make([]string, 0, len(c.BwLimitSchedule))
+	for _, window := range c.BwLimitSchedule {
+		windows = append(windows, fmt.Sprintf("%d-%d=%s", window.Start, window.End, window.Limit))
+	}
+
+	return fmt.Sprintf("%s() { cmd=$1; shift; while true; do "+
+		"schedule=$(date -u +'%%H %%M %%S' | awk -v windows=%s '%s'); limit=${schedule%% *}; secs=${schedule#* }; "+
+		"end=$(($(date +%%s) + secs)); echo \"bandwidth limit: $limit, for $secs seconds\"; "+
+		"timeout \"$secs\" \"$cmd\" --bwlimit=\"$limit\" \"$@\" && return 0; rc=$?; "+
+		"[ \"$(date +%%s)\" -lt \"$end\" ] && return $rc; "+
+		"echo \"bandwidth limit window ended, restarting rsync\"; done; }",
+		bwLimitScheduleFunc, strings.Join(windows, ","), bwLimitScheduleScript)
+}
+
+// buildDestPrepareCmd builds the command to run on the destination side before rsync, creating the destination
+// path and checking the existence of the reference directory. It returns an empty string if there is nothing to do.
+func (c *Cmd) buildDestPrepareCmd() string {
+	var cmds []string
+
+	if c.DestMkdir {
+		cmds = append(cmds, "mkdir -p "+c.DestPath)
+	}
+
+	if c.CompareDest != "" {
+		cmds = append(cmds, fmt.Sprintf(`{ test -d %s || { echo "compare-dest %s is not a directory" >&2; exit 1; }; }`,
+			c.CompareDest, c.CompareDest))
+	}
+
+	return strings.Join(cmds, " && ")
+}
+
+// buildSSHConnectCheck builds the command which waits until an SSH connection to the remote side
+// can be established, making up to SSHConnectRetries+1 attempts.
+func (c *Cmd) buildSSHConnectCheck(sshArgs []string) string {
+	attempts := c.SSHConnectRetries + 1
+
+	return fmt.Sprintf("( i=1; until %s %s true; do echo \"ssh connection attempt $i/%d failed\"; "+
+		"[ \"$i\" -ge %d ] && exit 1; i=$((i+1)); sleep %d; done )",
+		strings.Join(sshArgs, " "), c.sshTarget(), attempts, attempts, sshConnectRetryPeriodSeconds)
+}
+
+// buildAutoCompressProbe builds the command which measures the throughput of the link to the remote side
+// by reading random data from it over SSH, and sets the autoCompressVar variable to the compression flag
+// of rsync if the throughput is below AutoCompressThreshold. The time to establish an SSH connection
+// is measured separately and subtracted. The times are read from /proc/uptime, as the date command of
+// busybox cannot print fractions of seconds.
+func (c *Cmd) buildAutoCompressProbe(sshArgs []string) string {
+	ssh := strings.Join(sshArgs, " ") + " " + c.sshTarget()
+	uptime := `$(cut -d " " -f 1 /proc/uptime)`
+
+	return fmt.Sprintf("t0=%s; %s true; t1=%s; "+
+		"bytes=$(timeout %d %s 'head -c %d /dev/urandom' | wc -c); t2=%s; "+
+		`rate=$(awk -v t0="$t0" -v t1="$t1" -v t2="$t2" -v bytes="$bytes" `+
+		`'BEGIN { secs = (t2 - t1) - (t1 - t0); if (secs < 0.01) secs = 0.01; printf "%%d", bytes / secs }'); `+
+		`if [ "$rate" -lt %d ]; then %s=-z; echo "auto-compress: link throughput $rate bytes/s, compression enabled"; `+
+		`else %s=; echo "auto-compress: link throughput $rate bytes/s, compression disabled"; fi`,
+		uptime, ssh, uptime, autoCompressProbeTimeoutSeconds, ssh, autoCompressProbeBytes, uptime,
+		AutoCompressThreshold, autoCompressVar, autoCompressVar)
+}
+
+// sshTarget returns the user and the host of the remote side to connect to with SSH.
+func (c *Cmd) sshTarget() string {
+	if c.DestUseSSH {
+		if c.DestSSHUser != "" {
+			return fmt.Sprintf("%s@%s", c.DestSSHUser, c.DestSSHHost)
+		}
+
+		return fmt.Sprintf("root@%s", c.DestSSHHost)
+	}
+
+	if c.SrcSSHUser != "" {
+		return fmt.Sprintf("%s@%s", c.SrcSSHUser, c.SrcSSHHost)
+	}
+
+	return fmt.Sprintf("root@%s", c.SrcSSHHost)
+}
+
+// buildFeatureCheck builds the command which fails if rsync is older than minVersion
+// or lacks a capability required by the options.
+func (c *Cmd) buildFeatureCheck(cmd string) string {
+	var features []string
+
+	if c.HardLinks {
+		features = append(features, "hardlinks")
+	}
+
+	if c.Iconv != "" {
+		features = append(features, "iconv")
+	}
+
+	if c.SELinuxContexts {
+		features = append(features, "xattrs")
+	}
+
+	return fmt.Sprintf("%s --version | awk -v min=%s -v features=\"%s\" '%s'",
+		cmd, minVersion, strings.Join(features, " "), featureCheckScript)
+}
+
+// buildListCmd builds the command which lists the top-level entries of the source path, NUL-separated.
+func (c *Cmd) buildListCmd(sshArgs []string) string {
+	listCmd := "find " + c.SrcPath + " -mindepth 1 -maxdepth 1 -print0"
+
+	if !c.SrcUseSSH {
+		return listCmd
+	}
+
+	sshDestUser := "root"
+	if c.SrcSSHUser != "" {
+		sshDestUser = c.SrcSSHUser
+	}
+
+	return fmt.Sprintf("%s %s@%s '%s'", strings.Join(sshArgs, " "), sshDestUser, c.SrcSSHHost, listCmd)
+}
+
+// buildSplitScript builds the shell script which selects the listed entries of the pod among the Parallel pods,
+// in a round-robin fashion, and prints their names NUL-separated. It is run by xargs with the entries
+// as its arguments, so that the names containing newlines are not split.
+func (c *Cmd) buildSplitScript() string {
+	return fmt.Sprintf("i=0; for f; do [ $((i %% %d)) -eq \"$%s\" ] && printf \"%%s\\000\" \"${f##*/}\"; "+
+		"i=$((i + 1)); done", c.Parallel, jobCompletionIndexEnv)
+}
+
+// buildSinceListCmd builds the command which lists the files in the source path modified after Since,
+// relative to the source path and NUL-separated. The time is set on the reference file in UTC, not to depend
+// on the timezone of the side of the source.
+func (c *Cmd) buildSinceListCmd(sshArgs []string) string {
+	listCmd := fmt.Sprintf("TZ=UTC0 touch -t %s %s && cd %s && find . ! -type d -newer %s -print0",
+		c.Since.UTC().Format("200601021504.05"), sinceReferencePath, c.SrcPath, sinceReferencePath)
+
+	if !c.SrcUseSSH {
+		return "( " + listCmd + " )"
+	}
+
+	sshDestUser := "root"
+	if c.SrcSSHUser != "" {
+		sshDestUser = c.SrcSSHUser
+	}
+
+	return fmt.Sprintf("%s %s@%s '%s'", strings.Join(sshArgs, " "), sshDestUser, c.SrcSSHHost, listCmd)
 }
 
 func (c *Cmd) buildSrc() string {
 	var src strings.Builder
 
+	if c.SrcUseDaemon {
+		user := "root"
+		if c.SrcSSHUser != "" {
+			user = c.SrcSSHUser
+		}
+
+		host := c.SrcSSHHost
+		if c.Port != 0 {
+			host = net.JoinHostPort(host, strconv.Itoa(c.Port))
+		}
+
+		src.WriteString(fmt.Sprintf("rsync://%s@%s/%s/", user, host, c.SrcDaemonModule))
+	}
+
 	if c.SrcUseSSH {
 		sshDestUser := "root"
 		if c.SrcSSHUser != "" {
@@ -0,0 +1,1033 @@
+package rsync_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/rsync"
+)
+
+// listedFilesMu serializes the tests running the generated commands, as they write the listed files
+// to the same paths.
+var listedFilesMu sync.Mutex
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "rsync -av --info=progress2,misc0,flist0 --no-inc-recursive "+
+		"-e \"ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o ConnectTimeout=5\" "+
+		"/source/ /dest/", result)
+}
+
+func TestBuildSrcUseDaemon(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:         "data/",
+		DestPath:        "/dest/",
+		SrcUseDaemon:    true,
+		SrcSSHHost:      "example.com",
+		SrcSSHUser:      "user",
+		SrcDaemonModule: "module",
+		Port:            8873,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "rsync -av --info=progress2,misc0,flist0 --no-inc-recursive "+
+		"rsync://user@example.com:8873/module/data/ /dest/", result)
+
+	cmd.Parallel = 2
+
+	_, err = cmd.Build()
+	require.Error(t, err)
+}
+
+func TestBuildSSH(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:    "/source/",
+		DestPath:   "/dest/",
+		SrcUseSSH:  true,
+		SrcSSHHost: "example.com",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " root@example.com:/source/ /dest/")
+
+	cmd.DestUseSSH = true
+
+	_, err = cmd.Build()
+	require.Error(t, err)
+}
+
+func TestBuildItemize(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		Itemize:  true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --itemize-changes ")
+}
+
+func TestBuildBlockSize(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:   "/source/",
+		DestPath:  "/dest/",
+		BlockSize: 65536,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --block-size=65536 ")
+}
+
+func TestBuildHardLinks(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:   "/source/",
+		DestPath:  "/dest/",
+		HardLinks: true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " -H ")
+}
+
+func TestBuildOmitDirTimes(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.NotContains(t, result, " -O ")
+
+	cmd.OmitDirTimes = true
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " -O ")
+}
+
+func TestBuildBackup(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		Backup:   true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " -b ")
+	assert.NotContains(t, result, "--backup-dir")
+
+	cmd.Backup = false
+	cmd.BackupDir = "/dest/backups/2024"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " -b --backup-dir=/dest/backups/2024 --filter='P /backups/2024/' ")
+
+	cmd.DestPath = "/dest/data/"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " -b --backup-dir=/dest/backups/2024 ")
+	assert.NotContains(t, result, "--filter")
+}
+
+func TestBuildDelayUpdates(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:      "/source/",
+		DestPath:     "/dest/",
+		DelayUpdates: true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --delay-updates ")
+}
+
+func TestBuildWholeFile(t *testing.T) {
+	t.Parallel()
+
+	enabled, disabled := true, false
+
+	for _, tc := range []struct {
+		wholeFile   *bool
+		expected    string
+		notExpected []string
+	}{
+		{wholeFile: nil, notExpected: []string{" -W ", " --no-W "}},
+		{wholeFile: &enabled, expected: " -W ", notExpected: []string{" --no-W "}},
+		{wholeFile: &disabled, expected: " --no-W ", notExpected: []string{" -W "}},
+	} {
+		cmd := rsync.Cmd{
+			SrcPath:   "/source/",
+			DestPath:  "/dest/",
+			WholeFile: tc.wholeFile,
+		}
+
+		result, err := cmd.Build()
+		require.NoError(t, err)
+
+		if tc.expected != "" {
+			assert.Contains(t, result, tc.expected)
+		}
+
+		for _, notExpected := range tc.notExpected {
+			assert.NotContains(t, result, notExpected)
+		}
+	}
+}
+
+func TestBuildNumericIDs(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:    "/source/",
+		DestPath:   "/dest/",
+		NumericIDs: true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --numeric-ids ")
+}
+
+func TestBuildNoOwnerNoGroup(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		NoOwner:  true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --no-o ")
+	assert.NotContains(t, result, " --no-g ")
+
+	cmd.NoOwner = false
+	cmd.NoGroup = true
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --no-g ")
+	assert.NotContains(t, result, " --no-o ")
+}
+
+func TestBuildUpdate(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		Update:   true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " -u ")
+}
+
+func TestBuildFilterFile(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:    "/source/",
+		DestPath:   "/dest/",
+		FilterFile: "/etc/filter",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --filter='. /etc/filter' ")
+}
+
+func TestBuildChmod(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		Chmod:    "Dg+s,ug+w,Fo-w",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --chmod=Dg+s,ug+w,Fo-w ")
+}
+
+func TestValidateChmod(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{"g+w", "Dg+s,ug+w,Fo-w,+X", "D2775,F664", "a=r", "u+rwx,go="} {
+		require.NoError(t, rsync.ValidateChmod(spec), spec)
+	}
+
+	for _, spec := range []string{"", "g+w,", "x+w", "G+w", "99", "g+w; rm -rf /", "F12345"} {
+		require.Error(t, rsync.ValidateChmod(spec), spec)
+	}
+}
+
+func TestBuildBwLimitSchedule(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		BwLimitSchedule: []rsync.BwLimitWindow{
+			{Start: 540, End: 1020, Limit: "10M"},
+		},
+		CheckFeatures: true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "rsync_bwlimit_schedule() { "))
+	assert.Contains(t, result, "-v windows=540-1020=10M ")
+	assert.Contains(t, result, `timeout "$secs" "$cmd" --bwlimit="$limit" "$@"`)
+	assert.Contains(t, result, " --partial ")
+	assert.True(t, strings.HasSuffix(result, "; rsync_bwlimit_schedule rsync -av --info=progress2,misc0,flist0 "+
+		"--no-inc-recursive -e \"ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null "+
+		"-o ConnectTimeout=5\" --partial /source/ /dest/"))
+
+	cmd.PartialDir = ".partial"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.NotContains(t, result, " --partial ")
+
+	cmd.Parallel = 2
+
+	_, err = cmd.Build()
+	require.Error(t, err)
+}
+
+func TestParseBwLimitSchedule(t *testing.T) {
+	t.Parallel()
+
+	windows, err := rsync.ParseBwLimitSchedule("09:00-17:00=10M,22:30-06:00=1.5m,17:00-17:30=500")
+	require.NoError(t, err)
+
+	assert.Equal(t, []rsync.BwLimitWindow{
+		{Start: 540, End: 1020, Limit: "10M"},
+		{Start: 1350, End: 360, Limit: "1.5m"},
+		{Start: 1020, End: 1050, Limit: "500"},
+	}, windows)
+
+	windows, err = rsync.ParseBwLimitSchedule("00:00-00:00=0")
+	require.NoError(t, err)
+
+	assert.Equal(t, []rsync.BwLimitWindow{{Start: 0, End: 0, Limit: "0"}}, windows)
+
+	for _, spec := range []string{
+		"", "09:00-17:00", "09:00=10M", "9-17=10M", "09:00-24:00=10M", "09:00-17:00=10T", "09:00-17:00=-1",
+		"09:00-17:00=10M,16:00-18:00=1M", "22:00-06:00=10M,05:00-07:00=1M", "00:00-00:00=1M,12:00-13:00=2M",
+	} {
+		_, err = rsync.ParseBwLimitSchedule(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestBuildIconv(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		Iconv:    "UTF-8,ISO-8859-1",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --iconv=UTF-8,ISO-8859-1 ")
+}
+
+func TestBuildExcludeLostFound(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:          "/source/",
+		DestPath:         "/dest/",
+		FilterFile:       "/etc/filter",
+		ExcludeLostFound: true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --filter='. /etc/filter' --exclude=/lost+found/ ",
+		"the rules of the filter file must take precedence")
+}
+
+func TestBuildDirsOnly(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:          "/source/",
+		DestPath:         "/dest/",
+		ExcludeLostFound: true,
+		DirsOnly:         true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --exclude=/lost+found/ --filter='+ */' --filter='- *' ",
+		"the other rules must take precedence")
+}
+
+func TestBuildSELinuxContexts(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:         "/source/",
+		DestPath:        "/dest/",
+		SELinuxContexts: true,
+		CheckFeatures:   true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, ` -X --filter='+x security.selinux' --filter='-x *' `)
+	assert.Contains(t, result, `-v features="xattrs"`)
+}
+
+func TestValidateIconv(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{".", "UTF-8,ISO-8859-1", "utf8,latin1", "UTF-8,UTF-8-MAC", "EUC-JP,SHIFT_JIS"} {
+		require.NoError(t, rsync.ValidateIconv(spec), spec)
+	}
+
+	for _, spec := range []string{"", "UTF-8", "UTF-8,", ",UTF-8", "UTF-8,ISO-8859-1,ASCII", "UTF-8,$(id)"} {
+		require.Error(t, rsync.ValidateIconv(spec), spec)
+	}
+}
+
+func TestBuildIgnoreExisting(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:        "/source/",
+		DestPath:       "/dest/",
+		IgnoreExisting: true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --ignore-existing ")
+}
+
+func TestBuildIOTimeout(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:   "/source/",
+		DestPath:  "/dest/",
+		IOTimeout: 300,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --timeout=300 ")
+}
+
+func TestBuildProtocol(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		Protocol: 30,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --protocol=30 ")
+}
+
+func TestBuildSockOpts(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		SockOpts: "SO_SNDBUF=4194304,SO_RCVBUF=4194304",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --sockopts='SO_SNDBUF=4194304,SO_RCVBUF=4194304' ")
+}
+
+func TestBuildFilesFrom(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:   "/source/",
+		DestPath:  "/dest/",
+		FilesFrom: "/etc/files",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --files-from=/etc/files /source/ /dest/")
+}
+
+func TestBuildSSHCompression(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:        "/source/",
+		DestPath:       "/dest/",
+		SSHCompression: true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " -o ConnectTimeout=5 -C\" ")
+}
+
+func TestBuildVerbosity(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:   "/source/",
+		DestPath:  "/dest/",
+		Verbosity: 3,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "rsync -avvv --info=progress2"))
+}
+
+func TestBuildDestMkdir(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:   "/source/",
+		DestPath:  "/dest/a/b",
+		DestMkdir: true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "mkdir -p /dest/a/b && rsync "))
+
+	cmd.DestUseSSH = true
+	cmd.DestSSHHost = "example.com"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "rsync "))
+	assert.Contains(t, result, " --rsync-path='mkdir -p /dest/a/b && rsync' /source/ root@example.com:/dest/a/b")
+}
+
+func TestBuildMaxDelete(t *testing.T) {
+	t.Parallel()
+
+	maxDelete := 0
+	cmd := rsync.Cmd{
+		SrcPath:   "/source/",
+		DestPath:  "/dest/",
+		Delete:    true,
+		MaxDelete: &maxDelete,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --delete --max-delete=0 ")
+
+	cmd.MaxDelete = nil
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.NotContains(t, result, "--max-delete")
+}
+
+func TestBuildSSHUser(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:     "/source/",
+		DestPath:    "/dest/",
+		DestUseSSH:  true,
+		DestSSHHost: "example.com",
+		SrcSSHUser:  "rsync",
+		DestSSHUser: "rsync",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasSuffix(result, " /source/ rsync@example.com:/dest/"))
+
+	cmd.DestUseSSH = false
+	cmd.SrcUseSSH = true
+	cmd.SrcSSHHost = "example.com"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasSuffix(result, " rsync@example.com:/source/ /dest/"))
+}
+
+func TestBuildKnownHostsFile(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:           "/source/",
+		DestPath:          "/dest/",
+		SrcUseSSH:         true,
+		SrcSSHHost:        "example.com",
+		SSHConnectRetries: 1,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, "-o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null")
+	assert.NotContains(t, result, "HostKeyAlias")
+
+	cmd.KnownHostsFile = "/etc/pv-migrate/known-hosts"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	sshArgs := "ssh -o StrictHostKeyChecking=yes -o UserKnownHostsFile=/etc/pv-migrate/known-hosts " +
+		"-o HostKeyAlias=pv-migrate-sshd"
+	assert.Contains(t, result, "-e \""+sshArgs)
+	assert.Contains(t, result, "until "+sshArgs)
+	assert.NotContains(t, result, "StrictHostKeyChecking=no")
+}
+
+func TestBuildConnectTimeout(t *testing.T) {
+	t.Parallel()
+
+	connectTimeout := 30
+	cmd := rsync.Cmd{
+		SrcPath:        "/source/",
+		DestPath:       "/dest/",
+		DestUseSSH:     true,
+		DestSSHHost:    "example.com",
+		ConnectTimeout: &connectTimeout,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " -o UserKnownHostsFile=/dev/null -o ConnectTimeout=30\" ")
+
+	connectTimeout = 0
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.NotContains(t, result, "ConnectTimeout")
+
+	connectTimeout = 10
+	cmd = rsync.Cmd{
+		SrcPath:         "/",
+		DestPath:        "/dest/",
+		SrcUseDaemon:    true,
+		SrcDaemonModule: "pv-migrate",
+		SrcSSHHost:      "example.com",
+		ConnectTimeout:  &connectTimeout,
+	}
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --contimeout=10 ")
+	assert.NotContains(t, result, "ConnectTimeout")
+}
+
+func TestBuildPartialDir(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:    "/source/",
+		DestPath:   "/dest/",
+		PartialDir: ".partial",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, " --partial-dir=.partial ")
+}
+
+func TestBuildCompareDest(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:     "/source/",
+		DestPath:    "/dest/",
+		CompareDest: "/dest/previous",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, `{ test -d /dest/previous || `+
+		`{ echo "compare-dest /dest/previous is not a directory" >&2; exit 1; }; } && rsync `))
+	assert.Contains(t, result, " --compare-dest=/dest/previous ")
+
+	cmd.DestUseSSH = true
+	cmd.DestSSHHost = "example.com"
+	cmd.DestMkdir = true
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "rsync "))
+	assert.Contains(t, result, ` --rsync-path='mkdir -p /dest/ && { test -d /dest/previous || `+
+		`{ echo "compare-dest /dest/previous is not a directory" >&2; exit 1; }; } && rsync' `)
+}
+
+func TestBuildCheckFeatures(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:       "/source/",
+		DestPath:      "/dest/",
+		CheckFeatures: true,
+		Iconv:         ".",
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, `rsync --version | awk -v min=3.1.0 -v features="iconv" '`))
+	assert.Contains(t, result, "' || exit 1; rsync -av ")
+
+	cmd.Iconv = ""
+	cmd.HardLinks = true
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, `-v features="hardlinks"`)
+}
+
+func TestBuildSince(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		Since:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "( TZ=UTC0 touch -t 202405011200.00 /tmp/pv-migrate-since && "+
+		"cd /source/ && find . ! -type d -newer /tmp/pv-migrate-since -print0 ) > /tmp/pv-migrate-files && rsync "))
+	assert.Contains(t, result, " --from0 --files-from=/tmp/pv-migrate-files /source/ /dest/")
+
+	cmd.SrcUseSSH = true
+	cmd.SrcSSHHost = "example.com"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.Contains(t, result, "root@example.com 'TZ=UTC0 touch -t 202405011200.00 /tmp/pv-migrate-since && "+
+		"cd /source/ && find . ! -type d -newer /tmp/pv-migrate-since -print0' > /tmp/pv-migrate-files && rsync ")
+
+	cmd.Parallel = 2
+
+	_, err = cmd.Build()
+	require.Error(t, err)
+}
+
+// TestBuildSinceList runs the listing of the modified files with the sh of the system, and with busybox
+// if it is installed, as the images are based on alpine.
+func TestBuildSinceList(t *testing.T) {
+	t.Parallel()
+
+	listedFilesMu.Lock()
+	defer listedFilesMu.Unlock()
+
+	dir := t.TempDir()
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, modTime := range map[string]time.Time{
+		"old":           since.Add(-time.Hour),
+		"new":           since.Add(time.Hour),
+		"sub/new\nline": since.Add(time.Minute),
+	} {
+		filePath := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0o700))
+		require.NoError(t, os.WriteFile(filePath, nil, 0o600))
+		require.NoError(t, os.Chtimes(filePath, modTime, modTime))
+	}
+
+	cmd := rsync.Cmd{
+		// prints the paths passed to rsync instead of running it
+		Command:  "cat /tmp/pv-migrate-files && :",
+		SrcPath:  dir,
+		DestPath: "/dest/",
+		Since:    since,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	t.Run("sh", func(t *testing.T) {
+		output, err := exec.Command("sh", "-c", result).Output()
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{"./new", "./sub/new\nline"},
+			strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00"))
+	})
+
+	t.Run("busybox", func(t *testing.T) {
+		busybox, err := exec.LookPath("busybox")
+		if err != nil {
+			t.Skip("busybox is not installed")
+		}
+
+		// the applets are run instead of the commands of the system
+		binDir := t.TempDir()
+		for _, applet := range []string{"sh", "touch", "find", "cat"} {
+			require.NoError(t, os.Symlink(busybox, filepath.Join(binDir, applet)))
+		}
+
+		command := exec.Command(filepath.Join(binDir, "sh"), "-c", result)
+		command.Env = []string{"PATH=" + binDir}
+
+		output, err := command.Output()
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{"./new", "./sub/new\nline"},
+			strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00"))
+	})
+
+	cmd.SrcPath = filepath.Join(dir, "missing")
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	require.Error(t, exec.Command("sh", "-c", result).Run(), "a failed listing must fail the command")
+}
+
+func TestBuildParallel(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:  "/source/",
+		DestPath: "/dest/",
+		Parallel: 3,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "find /source/ -mindepth 1 -maxdepth 1 -print0 > /tmp/pv-migrate-entries && "+
+		"xargs -0 sh -c 'i=0; for f; do [ $((i % 3)) -eq \"$JOB_COMPLETION_INDEX\" ] && "+
+		"printf \"%s\\000\" \"${f##*/}\"; i=$((i + 1)); done' sh < /tmp/pv-migrate-entries > /tmp/pv-migrate-files && "+
+		"rsync "))
+	assert.Contains(t, result, " -r --from0 --files-from=/tmp/pv-migrate-files /source/ /dest/")
+
+	cmd.FilterFile = "/etc/filter"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "tr '\\n' '\\0' < /etc/filter > /tmp/pv-migrate-filter && find "))
+	assert.Contains(t, result, " --filter='. /tmp/pv-migrate-filter' ")
+
+	cmd.FilterFile = ""
+	cmd.SrcUseSSH = true
+	cmd.SrcSSHHost = "example.com"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null "+
+		"-o ConnectTimeout=5 root@example.com 'find /source/ -mindepth 1 -maxdepth 1 -print0' > "))
+	assert.Contains(t, result, " root@example.com:/source/ /dest/")
+}
+
+func TestBuildParallelSplit(t *testing.T) {
+	t.Parallel()
+
+	listedFilesMu.Lock()
+	defer listedFilesMu.Unlock()
+
+	dir := t.TempDir()
+	entries := []string{"a", "b c", "new\nline", ".hidden", "d"}
+
+	for _, entry := range entries {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, entry), nil, 0o600))
+	}
+
+	var listed []string
+
+	for index := range 3 {
+		cmd := rsync.Cmd{
+			// prints the paths passed to rsync instead of running it
+			Command:  "cat /tmp/pv-migrate-files && :",
+			SrcPath:  dir + "/",
+			DestPath: "/dest/",
+			Parallel: 3,
+		}
+
+		result, err := cmd.Build()
+		require.NoError(t, err)
+
+		command := exec.Command("sh", "-c", result)
+		command.Env = append(os.Environ(), "JOB_COMPLETION_INDEX="+strconv.Itoa(index))
+
+		output, err := command.Output()
+		require.NoError(t, err)
+
+		listed = append(listed, strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")...)
+	}
+
+	assert.ElementsMatch(t, entries, listed, "each entry must be transferred by exactly one pod")
+
+	cmd := rsync.Cmd{SrcPath: filepath.Join(dir, "missing") + "/", DestPath: "/dest/", Parallel: 3}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	require.Error(t, exec.Command("sh", "-c", result).Run(), "a failed listing must fail the command")
+}
+
+func TestBuildSSHConnectRetries(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:           "/source/",
+		DestPath:          "/dest/",
+		SSHConnectRetries: 2,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "rsync "), "connection check must be skipped without ssh")
+
+	cmd.SrcUseSSH = true
+	cmd.SrcSSHHost = "example.com"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "( i=1; until ssh -o StrictHostKeyChecking=no "+
+		"-o UserKnownHostsFile=/dev/null -o ConnectTimeout=5 root@example.com true; "+
+		"do echo \"ssh connection attempt $i/3 failed\"; [ \"$i\" -ge 3 ] && exit 1; "+
+		"i=$((i+1)); sleep 2; done ) && rsync "))
+}
+
+func TestBuildAutoCompress(t *testing.T) {
+	t.Parallel()
+
+	cmd := rsync.Cmd{
+		SrcPath:      "/source/",
+		DestPath:     "/dest/",
+		AutoCompress: true,
+	}
+
+	result, err := cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "rsync "), "throughput cannot be measured without ssh")
+	assert.Contains(t, result, " -z ")
+
+	cmd.DestUseSSH = true
+	cmd.DestSSHHost = "example.com"
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "t0=$(cut -d \" \" -f 1 /proc/uptime); ssh -o StrictHostKeyChecking=no "+
+		"-o UserKnownHostsFile=/dev/null -o ConnectTimeout=5 root@example.com true; "))
+	assert.Contains(t, result, "root@example.com 'head -c 8388608 /dev/urandom' | wc -c")
+	assert.Contains(t, result, `if [ "$rate" -lt 12500000 ]; then auto_compress=-z;`)
+	assert.Contains(t, result, " $auto_compress ")
+	assert.NotContains(t, result, " -z ")
+
+	cmd.SSHConnectRetries = 1
+
+	result, err = cmd.Build()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(result, "( i=1; until ssh "), "connection check must precede the probe")
+	assert.Contains(t, result, " done ) && t0=")
+}
@@ -0,0 +1,61 @@
+package progress
+
+import "time"
+
+// DefaultETAWindow is the period of the recent progress the throughput is averaged over by default.
+const DefaultETAWindow = time.Minute
+
+// ETAIntervalContextKey is a context key for the interval to log the estimated time remaining at,
+// when the progress bar is not displayed.
+type ETAIntervalContextKey struct{}
+
+type etaSample struct {
+	time        time.Time
+	transferred int64
+}
+
+// ETAEstimator estimates the time remaining for the transfer from the rolling average of its recent throughput,
+// which is more stable than the one rsync prints, as it is projected from the speed of the file being transferred.
+type ETAEstimator struct {
+	window  time.Duration
+	samples []etaSample
+}
+
+// NewETAEstimator creates an ETAEstimator averaging the throughput over the given period.
+func NewETAEstimator(window time.Duration) *ETAEstimator {
+	return &ETAEstimator{window: window}
+}
+
+// Observe records the progress at the given time, and returns the estimated time remaining and the average
+// throughput in bytes per second. The last return value is false if they cannot be estimated yet, i.e.,
+// until the progress is observed twice, or if nothing was transferred in the window.
+func (e *ETAEstimator) Observe(now time.Time, progress Progress) (time.Duration, float64, bool) {
+	// the transfer was restarted, e.g., by a retry of rsync, the previous samples do not apply anymore
+	if n := len(e.samples); n > 0 && progress.Transferred < e.samples[n-1].transferred {
+		e.samples = nil
+	}
+
+	e.samples = append(e.samples, etaSample{time: now, transferred: progress.Transferred})
+
+	// keep the last sample before the window, for the average to cover the whole window
+	for len(e.samples) > 2 && now.Sub(e.samples[1].time) >= e.window {
+		e.samples = e.samples[1:]
+	}
+
+	first := e.samples[0]
+	elapsed := now.Sub(first.time)
+	transferred := progress.Transferred - first.transferred
+
+	if elapsed <= 0 || transferred <= 0 {
+		return 0, 0, false
+	}
+
+	throughput := float64(transferred) / elapsed.Seconds()
+
+	remaining := progress.Total - progress.Transferred
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return time.Duration(float64(remaining) / throughput * float64(time.Second)), throughput, true
+}
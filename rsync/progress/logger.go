@@ -8,16 +8,27 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/schollz/progressbar/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 )
 
+// logTailRetryPeriod is the time to wait before following the logs again, e.g., while the container is starting.
+const logTailRetryPeriod = time.Second
+
 type LogStreamFunc func(ctx context.Context) (io.ReadCloser, error)
 
 type Logger struct {
 	options   LoggerOptions
 	successCh chan struct{}
+
+	lock        sync.Mutex
+	destEntries []string
 }
 
 type LoggerOptions struct {
@@ -40,6 +51,12 @@ func (l *Logger) Start(ctx context.Context, logger *slog.Logger) error {
 		}
 
 		logger.Debug("log tail failed, retrying", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logTailRetryPeriod):
+		}
 	}
 }
 
@@ -53,6 +70,22 @@ func (l *Logger) MarkAsComplete(ctx context.Context) error {
 	return nil
 }
 
+// DestNotEmptyEntries returns the entries found on the destination when it was required to be empty,
+// as printed by the rsync job before it failed.
+func (l *Logger) DestNotEmptyEntries() []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return slices.Clone(l.destEntries)
+}
+
+func (l *Logger) addDestNotEmptyEntry(entry string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.destEntries = append(l.destEntries, entry)
+}
+
 func (l *Logger) startSingle(ctx context.Context, logger *slog.Logger) error {
 	logCh := make(chan string)
 
@@ -81,7 +114,7 @@ func (l *Logger) startSingle(ctx context.Context, logger *slog.Logger) error {
 	eg.Go(func() error {
 		defer cancel()
 
-		return handleLogs(ctx, logCh, l.successCh, l.options.ShowProgressBar, logger)
+		return l.handleLogs(ctx, logCh, l.successCh, l.options.ShowProgressBar, logger)
 	})
 
 	if err = eg.Wait(); err != nil {
@@ -91,31 +124,47 @@ func (l *Logger) startSingle(ctx context.Context, logger *slog.Logger) error {
 	return nil
 }
 
+// tailLogs sends the lines of the stream to the channel until it ends, e.g., when the container terminates.
 func tailLogs(ctx context.Context, stream io.Reader, logCh chan<- string) error {
 	scanner := bufio.NewScanner(stream)
 
-	for {
+	for scanner.Scan() {
 		select {
 		case <-ctx.Done():
 			return ctx.Err() //nolint:wrapcheck
-		default:
-			if scanner.Scan() {
-				select {
-				case <-ctx.Done():
-					return ctx.Err() //nolint:wrapcheck
-				case logCh <- scanner.Text():
-				}
-			}
+		case logCh <- scanner.Text():
 		}
 	}
+
+	return scanner.Err() //nolint:wrapcheck
 }
 
-//nolint:cyclop
-func handleLogs(ctx context.Context, logCh <-chan string, successCh <-chan struct{},
+// handleLogs logs the lines of rsync until the context is done, which happens when the log stream ends.
+// The lines are still handled after the transfer is marked as complete, as the ones printed at the end of
+// the run of rsync, e.g., its summary, can be read after its completion is observed.
+//
+//nolint:cyclop,gocognit
+func (l *Logger) handleLogs(ctx context.Context, logCh <-chan string, successCh <-chan struct{},
 	showProgressBar bool, logger *slog.Logger,
 ) error {
 	var progressBar *progressbar.ProgressBar
 
+	reporter, _ := ctx.Value(ReporterContextKey{}).(Reporter)
+	verbose := ctx.Value(VerboseContextKey{}) != nil
+	etaInterval, _ := ctx.Value(ETAIntervalContextKey{}).(time.Duration)
+	etaEstimator := NewETAEstimator(DefaultETAWindow)
+	span := trace.SpanFromContext(ctx)
+
+	var (
+		lastETALog time.Time
+		completed  bool
+		finished   bool
+		summary    Summary
+		// changed is the size of the data of the files changed on the destination, which rsync reports
+		// as transferred in its progress, unlike the bytes sent over the network
+		changed int64
+	)
+
 	if showProgressBar {
 		progressBar = progressbar.NewOptions64(
 			1,
@@ -131,50 +180,152 @@ func handleLogs(ctx context.Context, logCh <-chan string, successCh <-chan struc
 		)
 	}
 
+	finishProgressBar := func() {
+		if !showProgressBar || finished {
+			return
+		}
+
+		finished = true
+
+		if err := progressBar.Finish(); err != nil {
+			logger.Debug("failed to finish progress bar", "error", err)
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
+			if completed {
+				return nil
+			}
+
 			return ctx.Err() //nolint:wrapcheck
 		case <-successCh:
-			if showProgressBar {
-				if err := progressBar.Finish(); err != nil {
-					logger.Debug("failed to finish progress bar", "error", err)
-				}
-			}
+			completed = true
+			successCh = nil
 
-			return nil
+			finishProgressBar()
 		case logLine := <-logCh:
+			if IsItemizedLine(logLine) {
+				logger.Debug(logLine, slog.String("source", "rsync"), slog.Bool("itemized", true))
+
+				continue
+			}
+
+			if attempt, attempts, ok := ParseSSHConnectAttemptLine(logLine); ok {
+				logger.Warn("🔶 SSH connection attempt failed", "attempt", attempt, "attempts", attempts)
+
+				continue
+			}
+
+			if throughput, compression, ok := ParseAutoCompressLine(logLine); ok {
+				logger.Info("🗜️ Measured the throughput of the link", "throughput_bytes_per_second", throughput,
+					"compression", compression)
+
+				continue
+			}
+
+			if reason, ok := ParseFeatureCheckLine(logLine); ok {
+				logger.Warn("🔶 The rsync image lacks a feature required by the requested options", "reason", reason)
+
+				continue
+			}
+
+			if skipped, ok := ParseMaxDeleteLine(logLine); ok {
+				logger.Warn("🔶 The --max-delete limit was hit, the rest of the extraneous files were not deleted",
+					"skipped", skipped)
+
+				continue
+			}
+
+			if entry, ok := ParseDestNotEmptyLine(logLine); ok {
+				l.addDestNotEmptyEntry(entry)
+				logger.Warn("🔶 The destination is not empty", "entry", entry)
+
+				continue
+			}
+
+			if lineSummary, ok := ParseSummaryLine(logLine); ok {
+				summary = lineSummary
+
+				continue
+			}
+
 			progress, err := ParseLine(logLine)
 			if err != nil {
+				if verbose {
+					logger.Info(logLine, slog.String("source", "rsync"))
+
+					continue
+				}
+
 				logger.Log(ctx, slog.LevelDebug-1, "failed to parse progress line", "error", err)
 
 				continue
 			}
 
+			now := time.Now()
+
+			eta, throughput, etaKnown := etaEstimator.Observe(now, progress)
+			if etaKnown {
+				progress.ETA, progress.Throughput = eta, throughput
+			}
+
+			if reporter != nil {
+				reporter.ReportProgress(ctx, progress)
+			}
+
+			if etaKnown && etaInterval > 0 && !showProgressBar && now.Sub(lastETALog) >= etaInterval {
+				lastETALog = now
+
+				logger.Info("⏱️ Estimated time remaining", "eta", eta.Round(time.Second).String(),
+					"eta_seconds", int64(eta.Seconds()), "throughput_bytes_per_second", int64(throughput),
+					"percentage", progress.Percentage)
+			}
+
+			span.SetAttributes(attribute.Int64("rsync.transferred_bytes", progress.Transferred),
+				attribute.Int64("rsync.total_bytes", progress.Total))
+
 			if !showProgressBar {
 				logger.Debug(logLine, slog.String("source", "rsync"), slog.Group("progress", "transferred",
-					progress.Transferred, "total", progress.Total, "percentage", progress.Percentage))
+					progress.Transferred, "total", progress.Total, "percentage", progress.Percentage,
+					"files_done", progress.FilesDone, "files_total", progress.FilesTotal))
 			} else {
-				if err = updateProgressBar(progressBar, progress.Transferred, progress.Total); err != nil {
+				if err = updateProgressBar(progressBar, progress); err != nil {
 					logger.Warn("failed to update progress bar", "error", err, "progress", progress)
 				}
 			}
 
-			if progress.Percentage >= 100 { //nolint:mnd
-				return nil
+			totalSize, isEnd := ParseTotalSizeLine(logLine)
+			if !isEnd {
+				changed = progress.Transferred
+
+				continue
 			}
+
+			finishProgressBar()
+
+			logger.Info("📊 Transfer summary", "changed_bytes", changed, "sent_bytes", summary.Sent,
+				"received_bytes", summary.Received, "total_size_bytes", totalSize)
+			span.SetAttributes(attribute.Int64("rsync.changed_bytes", changed),
+				attribute.Int64("rsync.sent_bytes", summary.Sent),
+				attribute.Int64("rsync.received_bytes", summary.Received))
 		}
 	}
 }
 
-func updateProgressBar(progressBar *progressbar.ProgressBar, transferred, total int64) error {
-	progressBar.ChangeMax64(total)
+func updateProgressBar(progressBar *progressbar.ProgressBar, progress Progress) error {
+	progressBar.ChangeMax64(progress.Total)
+
+	if progress.FilesTotal > 0 {
+		progressBar.Describe(fmt.Sprintf("📂 Copying data... (%d/%d files)", progress.FilesDone, progress.FilesTotal))
+	}
 
-	if total == 0 { // cannot update progress bar when its max is 0
+	if progress.Total == 0 { // cannot update progress bar when its max is 0
 		return nil
 	}
 
-	if err := progressBar.Set64(transferred); err != nil {
+	if err := progressBar.Set64(progress.Transferred); err != nil {
 		return fmt.Errorf("failed to set progress bar value: %w", err)
 	}
 
@@ -0,0 +1,91 @@
+package progress_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+// runLogger runs a progress logger on the given lines of rsync and returns what it logged.
+func runLogger(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	progressLogger := progress.NewLogger(progress.LoggerOptions{
+		LogStreamFunc: func(context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(strings.Join(lines, "\n") + "\n")), nil
+		},
+	})
+
+	require.NoError(t, progressLogger.Start(ctx, logger))
+
+	return buf.String()
+}
+
+func TestLoggerSummary(t *testing.T) {
+	t.Parallel()
+
+	output := runLogger(t,
+		"      1,048,576  50%   10.00MB/s    0:00:01 (xfr#1, to-chk=1/3)",
+		"      2,097,152 100%   10.00MB/s    0:00:02 (xfr#2, to-chk=0/3)",
+		"sent 1,234,567 bytes  received 2,345 bytes  823,941.33 bytes/sec",
+		"total size is 8,388,608  speedup is 6.78",
+	)
+
+	assert.Contains(t, output, "Transfer summary\" changed_bytes=2097152 sent_bytes=1234567 received_bytes=2345 "+
+		"total_size_bytes=8388608")
+}
+
+func TestLoggerMaxDeleteAfterCompletion(t *testing.T) {
+	t.Parallel()
+
+	// rsync prints the message after the last progress line, at the end of the transfer
+	output := runLogger(t,
+		"      2,097,152 100%   10.00MB/s    0:00:02 (xfr#2, to-chk=0/3)",
+		"Deletions stopped due to --max-delete limit (42 skipped)",
+		"rsync error: the --max-delete limit stopped deletions (code 25) at main.c(1865) [sender=3.2.7]",
+		"sent 1,234,567 bytes  received 2,345 bytes  823,941.33 bytes/sec",
+		"total size is 8,388,608  speedup is 6.78",
+	)
+
+	assert.Contains(t, output, "level=WARN msg=\"🔶 The --max-delete limit was hit")
+	assert.Contains(t, output, "skipped=42")
+}
+
+func TestLoggerDestNotEmpty(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	progressLogger := progress.NewLogger(progress.LoggerOptions{
+		LogStreamFunc: func(context.Context) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("/dest is not empty\n" +
+				"destination is not empty, found: data\n" +
+				"destination is not empty, found: file with spaces.txt\n")), nil
+		},
+	})
+
+	require.NoError(t, progressLogger.Start(ctx, logger))
+
+	assert.Equal(t, []string{"data", "file with spaces.txt"}, progressLogger.DestNotEmptyEntries())
+	assert.Contains(t, buf.String(), "The destination is not empty\" entry=data")
+}
@@ -1,16 +1,37 @@
 package progress
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
 	progressRegex = regexp.MustCompile(`\s*(?P<bytes>[0-9]+(,[0-9]+)*)\s+(?P<percentage>[0-9]{1,3})%`)
 	rsyncEndRegex = regexp.MustCompile(`\s*total size is (?P<bytes>[0-9]+(,[0-9]+)*)`)
+	filesRegex    = regexp.MustCompile(`to-chk=(?P<remaining>[0-9]+)/(?P<total>[0-9]+)`)
+
+	summaryRegex = regexp.MustCompile(
+		`^\s*sent (?P<sent>[0-9]+(,[0-9]+)*) bytes\s+received (?P<received>[0-9]+(,[0-9]+)*) bytes`)
+
+	sshConnectAttemptRegex = regexp.MustCompile(
+		`^ssh connection attempt (?P<attempt>[0-9]+)/(?P<attempts>[0-9]+) failed`)
+
+	autoCompressRegex = regexp.MustCompile(
+		`^auto-compress: link throughput (?P<rate>[0-9]+) bytes/s, compression (?P<state>enabled|disabled)$`)
+
+	featureCheckRegex = regexp.MustCompile(`^rsync feature check failed: (?P<reason>.+)$`)
+
+	maxDeleteRegex = regexp.MustCompile(`Deletions stopped due to --max-delete limit \((?P<skipped>[0-9]+) skipped\)`)
+
+	destNotEmptyRegex = regexp.MustCompile(`^destination is not empty, found: (?P<entry>.+)$`)
+
+	// itemizeRegex matches the lines printed by rsync's --itemize-changes flag, e.g. ">f+++++++++ file.txt".
+	itemizeRegex = regexp.MustCompile(`^(\*deleting|[<>ch.][fdLDS][.+?cstTpoguax]{9,10}) +\S`)
 )
 
 const (
@@ -23,21 +44,43 @@ const (
 // CanDisplayProgressBarContextKey is a context key for whether a progress bar can be displayed.
 type CanDisplayProgressBarContextKey struct{}
 
+// VerboseContextKey is a context key for whether the lines printed by rsync other than the progress,
+// e.g., with an increased verbosity, are logged at the info level instead of being skipped.
+type VerboseContextKey struct{}
+
+// ReporterContextKey is a context key for a Reporter to be notified about the progress of the transfer.
+type ReporterContextKey struct{}
+
+// Reporter is notified about the progress of the transfer, in addition to it being logged.
+type Reporter interface {
+	ReportProgress(ctx context.Context, progress Progress)
+}
+
+// Reporters is a Reporter which notifies all the reporters in it.
+type Reporters []Reporter
+
+func (r Reporters) ReportProgress(ctx context.Context, progress Progress) {
+	for _, reporter := range r {
+		reporter.ReportProgress(ctx, progress)
+	}
+}
+
 type Progress struct {
 	Line        string
 	Percentage  int
 	Transferred int64
 	Total       int64
+	// FilesDone and FilesTotal are the numbers of the files checked so far and in total. They are zero if unknown.
+	FilesDone  int64
+	FilesTotal int64
+	// ETA is the estimated time remaining, projected from the rolling average of the recent throughput,
+	// and Throughput is that average in bytes per second. They are zero if unknown.
+	ETA        time.Duration
+	Throughput float64
 }
 
 func ParseLine(line string) (Progress, error) {
-	endMatches := findNamedMatches(rsyncEndRegex, line)
-	if len(endMatches) > 0 {
-		total, err := parseNumBytes(endMatches["bytes"])
-		if err != nil {
-			return Progress{}, err
-		}
-
+	if total, ok := ParseTotalSizeLine(line); ok {
 		return Progress{
 			Line:        line,
 			Percentage:  percentHundred,
@@ -56,12 +99,16 @@ func ParseLine(line string) (Progress, error) {
 		return Progress{}, fmt.Errorf("cannot parse percentage: %w", err)
 	}
 
+	filesDone, filesTotal := parseFiles(line)
+
 	if percentage == 0 {
 		return Progress{
 			Line:        line,
 			Percentage:  0,
 			Transferred: 0,
 			Total:       0,
+			FilesDone:   filesDone,
+			FilesTotal:  filesTotal,
 		}, nil
 	}
 
@@ -82,9 +129,137 @@ func ParseLine(line string) (Progress, error) {
 		Percentage:  percentage,
 		Transferred: transferred,
 		Total:       total,
+		FilesDone:   filesDone,
+		FilesTotal:  filesTotal,
 	}, nil
 }
 
+// parseFiles parses the "to-chk=remaining/total" part of the progress line,
+// which is only printed after a file is transferred.
+func parseFiles(line string) (done, total int64) {
+	matches := findNamedMatches(filesRegex, line)
+	if len(matches) == 0 {
+		return 0, 0
+	}
+
+	remaining, err := strconv.ParseInt(matches["remaining"], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+
+	total, err = strconv.ParseInt(matches["total"], 10, 64)
+	if err != nil || remaining > total {
+		return 0, 0
+	}
+
+	return total - remaining, total
+}
+
+// Summary is the amount of data transferred by rsync over the network, as printed at the end of its run.
+type Summary struct {
+	Sent     int64
+	Received int64
+}
+
+// ParseTotalSizeLine parses the "total size is X" line printed by rsync as the last line of its run,
+// after the "sent X bytes  received Y bytes" line. X is the total size of the files in the transfer,
+// including the unchanged ones. The second return value is false if the line is not such a line.
+func ParseTotalSizeLine(line string) (int64, bool) {
+	matches := findNamedMatches(rsyncEndRegex, line)
+	if len(matches) == 0 {
+		return 0, false
+	}
+
+	total, err := parseNumBytes(matches["bytes"])
+	if err != nil {
+		return 0, false
+	}
+
+	return total, true
+}
+
+// ParseSummaryLine parses the "sent X bytes  received Y bytes" line printed by rsync at the end of its run.
+// The second return value is false if the line is not a summary line.
+func ParseSummaryLine(line string) (Summary, bool) {
+	matches := findNamedMatches(summaryRegex, line)
+	if len(matches) == 0 {
+		return Summary{}, false
+	}
+
+	sent, err := parseNumBytes(matches["sent"])
+	if err != nil {
+		return Summary{}, false
+	}
+
+	received, err := parseNumBytes(matches["received"])
+	if err != nil {
+		return Summary{}, false
+	}
+
+	return Summary{Sent: sent, Received: received}, true
+}
+
+// ParseSSHConnectAttemptLine parses the line printed when an attempt to establish the SSH connection fails.
+// The second return value is false if the line is not such a line.
+func ParseSSHConnectAttemptLine(line string) (attempt, attempts string, ok bool) {
+	matches := findNamedMatches(sshConnectAttemptRegex, line)
+	if len(matches) == 0 {
+		return "", "", false
+	}
+
+	return matches["attempt"], matches["attempts"], true
+}
+
+// ParseAutoCompressLine parses the line printed after measuring the throughput of the link for --auto-compress,
+// returning the throughput in bytes per second and whether the compression was enabled.
+// The last return value is false if the line is not such a line.
+func ParseAutoCompressLine(line string) (throughput string, compression bool, ok bool) {
+	matches := findNamedMatches(autoCompressRegex, line)
+	if len(matches) == 0 {
+		return "", false, false
+	}
+
+	return matches["rate"], matches["state"] == "enabled", true
+}
+
+// ParseFeatureCheckLine parses the line printed when rsync lacks a feature required by the options,
+// returning the reason. The second return value is false if the line is not such a line.
+func ParseFeatureCheckLine(line string) (string, bool) {
+	matches := findNamedMatches(featureCheckRegex, line)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	return matches["reason"], true
+}
+
+// ParseMaxDeleteLine parses the line printed by rsync when the --max-delete limit is hit,
+// returning the number of the skipped deletions. The second return value is false if the line is not such a line.
+func ParseMaxDeleteLine(line string) (string, bool) {
+	matches := findNamedMatches(maxDeleteRegex, line)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	return matches["skipped"], true
+}
+
+// ParseDestNotEmptyLine parses the line printed for each entry found on the destination when it is required
+// to be empty, returning the entry. The second return value is false if the line is not such a line.
+func ParseDestNotEmptyLine(line string) (string, bool) {
+	matches := findNamedMatches(destNotEmptyRegex, line)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	return matches["entry"], true
+}
+
+// IsItemizedLine returns whether the line is an itemized change line printed by rsync.
+func IsItemizedLine(line string) bool {
+	return itemizeRegex.MatchString(line)
+}
+
 func parseNumBytes(numBytes string) (int64, error) {
 	parsed, err := strconv.ParseInt(strings.ReplaceAll(numBytes, ",", ""),
 		bytesTransferredIntBase, bytesTransferredInt64Bits)
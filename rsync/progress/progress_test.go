@@ -2,6 +2,7 @@ package progress_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,3 +20,123 @@ func TestParseLogLineEndMatch(t *testing.T) {
 	assert.Equal(t, int64(1879048192), p.Transferred)
 	assert.Equal(t, int64(1879048192), p.Total)
 }
+
+func TestParseLogLineFiles(t *testing.T) {
+	t.Parallel()
+
+	p, err := progress.ParseLine("    524,288,000  50%  100.00MB/s    0:00:05 (xfr#3, to-chk=7/10)")
+	require.NoError(t, err)
+	assert.Equal(t, 50, p.Percentage)
+	assert.Equal(t, int64(3), p.FilesDone)
+	assert.Equal(t, int64(10), p.FilesTotal)
+
+	p, err = progress.ParseLine("    524,288,000  50%  100.00MB/s    0:00:05")
+	require.NoError(t, err)
+	assert.Zero(t, p.FilesTotal)
+}
+
+func TestETAEstimator(t *testing.T) {
+	t.Parallel()
+
+	estimator := progress.NewETAEstimator(time.Minute)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, _, ok := estimator.Observe(start, progress.Progress{Transferred: 0, Total: 1000})
+	assert.False(t, ok)
+
+	eta, throughput, ok := estimator.Observe(start.Add(10*time.Second), progress.Progress{Transferred: 100, Total: 1000})
+	require.True(t, ok)
+	assert.InDelta(t, 10.0, throughput, 0.001)
+	assert.Equal(t, 90*time.Second, eta)
+
+	// a burst is averaged over the window instead of being projected as is
+	eta, throughput, ok = estimator.Observe(start.Add(11*time.Second), progress.Progress{Transferred: 330, Total: 1000})
+	require.True(t, ok)
+	assert.InDelta(t, 30.0, throughput, 0.001)
+	assert.Equal(t, 22333*time.Millisecond, eta.Round(time.Millisecond))
+
+	// the samples older than the window are dropped
+	_, throughput, ok = estimator.Observe(start.Add(71*time.Second), progress.Progress{Transferred: 930, Total: 1000})
+	require.True(t, ok)
+	assert.InDelta(t, 10.0, throughput, 0.001)
+
+	// a restarted transfer resets the average
+	_, _, ok = estimator.Observe(start.Add(72*time.Second), progress.Progress{Transferred: 10, Total: 1000})
+	assert.False(t, ok)
+}
+
+func TestIsItemizedLine(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, progress.IsItemizedLine(">f+++++++++ file.txt"))
+	assert.True(t, progress.IsItemizedLine("cd+++++++++ some dir/"))
+	assert.True(t, progress.IsItemizedLine(">f.st...... data/file.bin"))
+	assert.True(t, progress.IsItemizedLine("*deleting   extra_file.txt"))
+	assert.False(t, progress.IsItemizedLine("      1,234,567  42%   12.34MB/s    0:00:01"))
+	assert.False(t, progress.IsItemizedLine("total size is 1,879,048,192  speedup is 31,548.30"))
+}
+
+func TestParseSummaryLine(t *testing.T) {
+	t.Parallel()
+
+	summary, ok := progress.ParseSummaryLine("sent 1,234,567 bytes  received 2,345 bytes  823,941.33 bytes/sec")
+	require.True(t, ok)
+	assert.Equal(t, progress.Summary{Sent: 1234567, Received: 2345}, summary)
+
+	_, ok = progress.ParseSummaryLine("total size is 1,879,048,192  speedup is 31,548.30")
+	assert.False(t, ok)
+}
+
+func TestParseFeatureCheckLine(t *testing.T) {
+	t.Parallel()
+
+	reason, ok := progress.ParseFeatureCheckLine(
+		"rsync feature check failed: rsync 3.0.9 is older than the required 3.1.0")
+	require.True(t, ok)
+	assert.Equal(t, "rsync 3.0.9 is older than the required 3.1.0", reason)
+
+	_, ok = progress.ParseFeatureCheckLine("rsync attempt 1/11 failed, waiting 5 seconds before trying again")
+	assert.False(t, ok)
+}
+
+func TestParseMaxDeleteLine(t *testing.T) {
+	t.Parallel()
+
+	skipped, ok := progress.ParseMaxDeleteLine("Deletions stopped due to --max-delete limit (42 skipped)")
+	require.True(t, ok)
+	assert.Equal(t, "42", skipped)
+
+	_, ok = progress.ParseMaxDeleteLine("deleting old/file.txt")
+	assert.False(t, ok)
+}
+
+func TestParseAutoCompressLine(t *testing.T) {
+	t.Parallel()
+
+	throughput, compression, ok := progress.ParseAutoCompressLine(
+		"auto-compress: link throughput 5242880 bytes/s, compression enabled")
+	require.True(t, ok)
+	assert.Equal(t, "5242880", throughput)
+	assert.True(t, compression)
+
+	throughput, compression, ok = progress.ParseAutoCompressLine(
+		"auto-compress: link throughput 104857600 bytes/s, compression disabled")
+	require.True(t, ok)
+	assert.Equal(t, "104857600", throughput)
+	assert.False(t, compression)
+
+	_, _, ok = progress.ParseAutoCompressLine("ssh connection attempt 2/6 failed")
+	assert.False(t, ok)
+}
+
+func TestParseSSHConnectAttemptLine(t *testing.T) {
+	t.Parallel()
+
+	attempt, attempts, ok := progress.ParseSSHConnectAttemptLine("ssh connection attempt 2/6 failed")
+	require.True(t, ok)
+	assert.Equal(t, "2", attempt)
+	assert.Equal(t, "6", attempts)
+
+	_, _, ok = progress.ParseSSHConnectAttemptLine("rsync attempt 1/11 failed, waiting 5 seconds before trying again")
+	assert.False(t, ok)
+}
@@ -37,6 +37,12 @@ func CreateSSHKeyPair(keyAlgorithm string) (string, string, error) {
 	}
 }
 
+// KnownHostsEntry returns the line of a known_hosts file pinning the given public key, in the authorized_keys format,
+// as the host key of the given host.
+func KnownHostsEntry(host, publicKey string) string {
+	return host + " " + strings.TrimSpace(publicKey) + "\n"
+}
+
 func createSSHRSAKeyPair() (string, string, error) {
 	privateKey, err := rsa.GenerateKey(rand.Reader, RSAKeyLengthBits)
 	if err != nil {
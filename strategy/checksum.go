@@ -0,0 +1,152 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/utkuozdemir/pv-migrate/checksum"
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+// checksumFilesScript prints the SHA-256 checksum and the path of each file passed to it, separated by NUL
+// instead of a newline, as the paths can contain newlines. sha256sum is run on each file separately, as the one
+// of busybox in the sshd image can neither separate its output with NUL nor escape the newlines in the paths.
+const checksumFilesScript = `for f; do s=$(sha256sum < "$f") && printf "%s  %s\000" "${s%% *}" "$f" || exit 1; done`
+
+// checksumScript prints the SHA-256 checksums of the regular files in the directory passed as its first argument,
+// with their paths relative to it. The lost+found directory at its root is skipped if the second argument is set.
+const checksumScript = `cd "$1" && if [ -n "$2" ]; then ` +
+	`find . -path ./lost+found -prune -o -type f -exec sh -c '` + checksumFilesScript + `' sh {} +; ` +
+	`else find . -type f -exec sh -c '` + checksumFilesScript + `' sh {} +; fi`
+
+// ChecksumManifests computes the SHA-256 checksums of the files in the source and the destination paths
+// of the migration, by running sha256sum in an sshd pod mounting each PVC read-only, which is removed afterwards.
+func ChecksumManifests(ctx context.Context, attempt *migration.Attempt,
+	logger *slog.Logger,
+) (checksum.Manifest, checksum.Manifest, error) {
+	mig := attempt.Migration
+	req := mig.Request
+
+	srcReleaseName := attempt.HelmReleaseNamePrefix + "-src"
+	destReleaseName := attempt.HelmReleaseNamePrefix + "-dest"
+	releaseNames := []string{srcReleaseName, destReleaseName}
+
+	defer cleanupReleases(ctx, attempt, releaseNames, logger)
+
+	if err := installChecksumRelease(ctx, attempt, mig.SourceInfo, srcReleaseName, srcMountPath, logger); err != nil {
+		return nil, nil, err
+	}
+
+	if err := installChecksumRelease(ctx, attempt, mig.DestInfo, destReleaseName, destMountPath, logger); err != nil {
+		return nil, nil, err
+	}
+
+	// lost+found is only skipped where it is excluded from the transfer, i.e., at the root of the volumes
+	skipLostFound := !req.IncludeLostFound && strings.Trim(req.Source.Path, "/") == ""
+
+	var (
+		source, dest checksum.Manifest
+		eg           errgroup.Group
+	)
+
+	eg.Go(func() error {
+		var err error
+
+		source, err = checksumPVC(ctx, mig.SourceInfo, srcReleaseName,
+			path.Join(srcMountPath, req.Source.Path), skipLostFound)
+
+		return err
+	})
+
+	eg.Go(func() error {
+		var err error
+
+		dest, err = checksumPVC(ctx, mig.DestInfo, destReleaseName,
+			path.Join(destMountPath, req.Dest.Path), skipLostFound && strings.Trim(req.Dest.Path, "/") == "")
+
+		return err
+	})
+
+	if err := eg.Wait(); err != nil {
+		return nil, nil, err
+	}
+
+	return source, dest, nil
+}
+
+func installChecksumRelease(ctx context.Context, attempt *migration.Attempt, pvcInfo *pvc.Info,
+	releaseName, mountPath string, logger *slog.Logger,
+) error {
+	vals := map[string]any{
+		"sshd": map[string]any{
+			"enabled":   true,
+			"namespace": pvcInfo.Claim.Namespace,
+			"pvcMounts": []map[string]any{
+				{
+					"name":      pvcInfo.Claim.Name,
+					"readOnly":  true,
+					"mountPath": mountPath,
+				},
+			},
+			"affinity": pvcInfo.AffinityHelmValues,
+			"service": map[string]any{
+				"enabled": false,
+			},
+		},
+	}
+
+	if err := installHelmChart(ctx, attempt, pvcInfo, releaseName, vals, logger); err != nil {
+		return fmt.Errorf("failed to install helm chart: %w", err)
+	}
+
+	return nil
+}
+
+// checksumPVC runs sha256sum on the files in the directory in the sshd pod of the release and parses its output.
+func checksumPVC(ctx context.Context, pvcInfo *pvc.Info, releaseName, dir string,
+	skipLostFound bool,
+) (checksum.Manifest, error) {
+	pod, err := getSshdPodForHelmRelease(ctx, pvcInfo, releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := ""
+	if skipLostFound {
+		skip = "1"
+	}
+
+	reader, writer := io.Pipe()
+
+	var stderr bytes.Buffer
+
+	go func() {
+		writer.CloseWithError(k8s.ExecInPod(ctx, pvcInfo.ClusterClient, pod.Namespace, pod.Name, "sshd",
+			[]string{"sh", "-c", checksumScript, "sh", dir, skip}, writer, &stderr))
+	}()
+
+	manifest, err := checksum.Parse(reader)
+
+	// drain the output for the command to complete, if the parsing failed
+	_, _ = io.Copy(io.Discard, reader)
+
+	if err != nil {
+		if trimmed := strings.TrimSpace(stderr.String()); trimmed != "" {
+			return nil, fmt.Errorf("failed to compute the checksums in pod %s/%s: %w: %s",
+				pod.Namespace, pod.Name, err, trimmed)
+		}
+
+		return nil, fmt.Errorf("failed to compute the checksums in pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+
+	return manifest, nil
+}
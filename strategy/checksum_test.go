@@ -0,0 +1,50 @@
+package strategy
+
+import (
+	"bytes"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/checksum"
+)
+
+func TestChecksumScript(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sha256sum"); err != nil {
+		t.Skip("sha256sum is not available")
+	}
+
+	dir := t.TempDir()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "lost+found"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "lost+found"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a"), []byte("a"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "lost+found", "b"), []byte("b"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "lost+found", "c"), []byte("c"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new\nline\\back"), []byte("a"), 0o600))
+
+	run := func(skipLostFound string) checksum.Manifest {
+		output, err := exec.Command("sh", "-c", checksumScript, "sh", dir, skipLostFound).Output()
+		require.NoError(t, err)
+
+		manifest, err := checksum.Parse(bytes.NewReader(output))
+		require.NoError(t, err)
+
+		return manifest
+	}
+
+	assert.ElementsMatch(t, []string{"a", "lost+found/b", "sub/lost+found/c", "new\nline\\back"},
+		slices.Collect(maps.Keys(run(""))))
+	assert.ElementsMatch(t, []string{"a", "sub/lost+found/c", "new\nline\\back"}, slices.Collect(maps.Keys(run("1"))),
+		"only lost+found at the root must be skipped")
+	assert.Equal(t, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", run("")["a"])
+	assert.Equal(t, run("")["a"], run("")["new\nline\\back"])
+}
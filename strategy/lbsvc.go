@@ -7,7 +7,6 @@ import (
 
 	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
-	"github.com/utkuozdemir/pv-migrate/rsync"
 	"github.com/utkuozdemir/pv-migrate/ssh"
 	"github.com/utkuozdemir/pv-migrate/util"
 )
@@ -16,6 +15,11 @@ type LbSvc struct{}
 
 func (r *LbSvc) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
 	mig := attempt.Migration
+	if mig.SourceInfo.BlockMode {
+		return ErrUnaccepted
+	}
+
+	attempt.Accept()
 
 	sourceInfo := mig.SourceInfo
 	destInfo := mig.DestInfo
@@ -32,50 +36,81 @@ func (r *LbSvc) Run(ctx context.Context, attempt *migration.Attempt, logger *slo
 
 	privateKeyMountPath := "/tmp/id_" + keyAlgorithm
 
+	hostKey, knownHosts, err := createHostKey(mig.Request, logger)
+	if err != nil {
+		return err
+	}
+
 	srcReleaseName := attempt.HelmReleaseNamePrefix + "-src"
 	destReleaseName := attempt.HelmReleaseNamePrefix + "-dest"
 	releaseNames := []string{srcReleaseName, destReleaseName}
 
-	doneCh := registerCleanupHook(attempt, releaseNames, logger)
-	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+	defer cleanupReleases(ctx, attempt, releaseNames, logger)
 
-	err = installOnSource(attempt, srcReleaseName, publicKey, srcMountPath, logger)
+	err = installOnSource(ctx, attempt, srcReleaseName, publicKey, hostKey, srcMountPath, logger)
 	if err != nil {
 		return fmt.Errorf("failed to install on source: %w", err)
 	}
 
-	sourceKubeClient := attempt.Migration.SourceInfo.ClusterClient.KubeClient
-	svcName := srcReleaseName + "-sshd"
-
-	lbSvcAddress, err := k8s.GetServiceAddress(ctx, sourceKubeClient, sourceNs, svcName, mig.Request.LBSvcTimeout)
+	sshTargetHost, err := lbSvcSSHTargetHost(ctx, mig, sourceNs, sshServiceName(mig.Request, srcReleaseName), logger)
 	if err != nil {
-		return fmt.Errorf("failed to get service address: %w", err)
+		return err
 	}
 
-	sshTargetHost := formatSSHTargetHost(lbSvcAddress)
-	if mig.Request.DestHostOverride != "" {
-		sshTargetHost = mig.Request.DestHostOverride
-	}
-
-	err = installOnDest(attempt, destReleaseName, privateKey, privateKeyMountPath,
-		sshTargetHost, srcMountPath, destMountPath, logger)
+	parallelism, err := installOnDest(ctx, attempt, destReleaseName, privateKey, privateKeyMountPath,
+		knownHosts, sshTargetHost, destMountPath, logger)
 	if err != nil {
 		return fmt.Errorf("failed to install on dest: %w", err)
 	}
 
-	showProgressBar := !attempt.Migration.Request.NoProgressBar
 	kubeClient := destInfo.ClusterClient.KubeClient
 	jobName := destReleaseName + "-rsync"
 
-	if err = k8s.WaitForJobCompletion(ctx, kubeClient, destNs, jobName, showProgressBar, logger); err != nil {
+	if err = waitForRsyncJob(ctx, mig, kubeClient, destNs, jobName, parallelism, logger); err != nil {
 		return fmt.Errorf("failed to wait for job completion: %w", err)
 	}
 
 	return nil
 }
 
-func installOnSource(attempt *migration.Attempt, releaseName,
-	publicKey, srcMountPath string, logger *slog.Logger,
+// lbSvcSSHTargetHost returns the host the rsync job connects to the SSH server with. Unless an endpoint
+// is given with DestSSHHost, e.g., an ingress or a bastion, it is the address of the load balancer service.
+func lbSvcSSHTargetHost(ctx context.Context, mig *migration.Migration, namespace, svcName string,
+	logger *slog.Logger,
+) (string, error) {
+	if host := mig.Request.DestSSHHost; host != "" {
+		logger.Info("🔗 Connecting to the SSH server through the given endpoint",
+			"host", host, "port", mig.Request.DestSSHPort)
+
+		return formatSSHTargetHost(host), nil
+	}
+
+	sourceKubeClient := mig.SourceInfo.ClusterClient.KubeClient
+
+	lbSvcAddress, err := k8s.GetServiceAddress(ctx, sourceKubeClient, namespace, svcName, mig.Request.LBSvcTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to get service address: %w", err)
+	}
+
+	if mig.Request.DestHostOverride != "" {
+		return mig.Request.DestHostOverride, nil
+	}
+
+	return formatSSHTargetHost(lbSvcAddress), nil
+}
+
+// lbSvcServiceType returns the type of the service of the SSH server. No load balancer is needed
+// when the SSH server is reached through a given endpoint, which is expected to route to the service.
+func lbSvcServiceType(request *migration.Request) string {
+	if request.DestSSHHost != "" {
+		return "ClusterIP"
+	}
+
+	return "LoadBalancer"
+}
+
+func installOnSource(ctx context.Context, attempt *migration.Attempt, releaseName,
+	publicKey, hostKey, srcMountPath string, logger *slog.Logger,
 ) error {
 	mig := attempt.Migration
 	sourceInfo := mig.SourceInfo
@@ -83,11 +118,14 @@ func installOnSource(attempt *migration.Attempt, releaseName,
 
 	vals := map[string]any{
 		"sshd": map[string]any{
-			"enabled":   true,
-			"namespace": namespace,
-			"publicKey": publicKey,
+			"enabled":          true,
+			"namespace":        namespace,
+			"publicKey":        publicKey,
+			"hostKey":          hostKey,
+			"hostKeyMountPath": hostKeyMountPath,
 			"service": map[string]any{
-				"type": "LoadBalancer",
+				"type": lbSvcServiceType(mig.Request),
+				"name": mig.Request.SSHServiceName,
 			},
 			"pvcMounts": []map[string]any{
 				{
@@ -100,53 +138,64 @@ func installOnSource(attempt *migration.Attempt, releaseName,
 		},
 	}
 
-	return installHelmChart(attempt, sourceInfo, releaseName, vals, logger)
+	return installHelmChart(ctx, attempt, sourceInfo, releaseName, vals, logger)
 }
 
-func installOnDest(attempt *migration.Attempt, releaseName, privateKey,
-	privateKeyMountPath, sshHost, srcMountPath, destMountPath string, logger *slog.Logger,
-) error {
+// installOnDest installs the rsync job on the destination and returns the number of pods it runs with.
+// If the known_hosts file is given, the host key of the SSH server is verified against it.
+func installOnDest(ctx context.Context, attempt *migration.Attempt, releaseName, privateKey,
+	privateKeyMountPath, knownHosts, sshHost, destMountPath string, logger *slog.Logger,
+) (int, error) {
 	mig := attempt.Migration
 	destInfo := mig.DestInfo
 	namespace := destInfo.Claim.Namespace
 
-	srcPath := srcMountPath + "/" + mig.Request.Source.Path
-	destPath := destMountPath + "/" + mig.Request.Dest.Path
-	rsyncCmd := rsync.Cmd{
-		NoChown:    mig.Request.NoChown,
-		Delete:     mig.Request.DeleteExtraneousFiles,
-		SrcPath:    srcPath,
-		DestPath:   destPath,
-		SrcUseSSH:  true,
-		SrcSSHHost: sshHost,
-		Compress:   mig.Request.Compress,
+	rsyncCmd := newRsyncCmd(mig.Request)
+	rsyncCmd.SrcUseSSH = true
+	rsyncCmd.SrcSSHHost = sshHost
+	rsyncCmd.Port = mig.Request.DestSSHPort
+
+	if knownHosts != "" {
+		rsyncCmd.KnownHostsFile = knownHostsMountPath
 	}
 
 	rsyncCmdStr, err := rsyncCmd.Build()
 	if err != nil {
-		return fmt.Errorf("failed to build rsync command: %w", err)
+		return 0, fmt.Errorf("failed to build rsync command: %w", err)
 	}
 
-	vals := map[string]any{
-		"rsync": map[string]any{
-			"enabled":             true,
-			"namespace":           namespace,
-			"privateKeyMount":     true,
-			"privateKey":          privateKey,
-			"privateKeyMountPath": privateKeyMountPath,
-			"sshRemoteHost":       sshHost,
-			"pvcMounts": []map[string]any{
-				{
-					"name":      destInfo.Claim.Name,
-					"mountPath": destMountPath,
-				},
+	rsyncVals := map[string]any{
+		"enabled":             true,
+		"namespace":           namespace,
+		"privateKeyMount":     true,
+		"privateKey":          privateKey,
+		"privateKeyMountPath": privateKeyMountPath,
+		"knownHosts":          knownHosts,
+		"knownHostsMountPath": knownHostsMountPath,
+		"sshRemoteHost":       sshHost,
+		"pvcMounts": []map[string]any{
+			{
+				"name":      destInfo.Claim.Name,
+				"mountPath": destMountPath,
 			},
-			"command":  rsyncCmdStr,
-			"affinity": destInfo.AffinityHelmValues,
 		},
+		"command":  rsyncCmdStr,
+		"affinity": destInfo.AffinityHelmValues,
+	}
+
+	applyRsyncMounts(rsyncVals, mig.Request)
+	applyDestEmptyCheck(rsyncVals, mig.Request)
+	parallelism := applyParallelism(rsyncVals, mig, releaseName, false)
+
+	vals := map[string]any{
+		"rsync": rsyncVals,
+	}
+
+	if err = installHelmChart(ctx, attempt, destInfo, releaseName, vals, logger); err != nil {
+		return 0, err
 	}
 
-	return installHelmChart(attempt, destInfo, releaseName, vals, logger)
+	return parallelism, nil
 }
 
 func formatSSHTargetHost(host string) string {
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
 )
 
 func TestFormatSSHTargetHost(t *testing.T) {
@@ -15,3 +17,10 @@ func TestFormatSSHTargetHost(t *testing.T) {
 		formatSSHTargetHost("2001:0db8:85a3:0000:0000:8a2e:0370:7334"))
 	assert.Equal(t, "[::1]", formatSSHTargetHost("::1"))
 }
+
+func TestLbSvcServiceType(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "LoadBalancer", lbSvcServiceType(&migration.Request{}))
+	assert.Equal(t, "ClusterIP", lbSvcServiceType(&migration.Request{DestSSHHost: "ssh.example.com"}))
+}
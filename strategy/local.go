@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -18,9 +19,9 @@ import (
 	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
 	"github.com/utkuozdemir/pv-migrate/pvc"
-	"github.com/utkuozdemir/pv-migrate/rsync"
 	"github.com/utkuozdemir/pv-migrate/rsync/progress"
 	"github.com/utkuozdemir/pv-migrate/ssh"
+	"github.com/utkuozdemir/pv-migrate/tracing"
 )
 
 const (
@@ -31,27 +32,65 @@ const (
 	privateKeyFileMode = 0o600
 )
 
+// Local reaches the sshd pods on both sides through port-forwards from the client device,
+// so it does not need any Service to be created or to be reachable.
 type Local struct{}
 
-func (r *Local) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
-	_, err := exec.LookPath("ssh")
-	if err != nil {
-		return errors.New("ssh binary not found")
+func (r *Local) canDo(t *migration.Migration, logger *slog.Logger) bool {
+	if t.SourceInfo.BlockMode {
+		return false
+	}
+
+	if t.Request.FilterFile != "" {
+		logger.Debug("filter file is not supported by the local strategy, as it does not run an rsync pod")
+
+		return false
+	}
+
+	if t.Request.StrictHostKeyChecking {
+		logger.Debug("strict host key checking is not supported by the local strategy, " +
+			"as the SSH servers are reached through port-forwards from the client device")
+
+		return false
+	}
+
+	if t.Request.FailIfDestNotEmpty {
+		logger.Debug("checking the destination to be empty is not supported by the local strategy, " +
+			"as it does not run an rsync pod")
+
+		return false
 	}
 
+	if _, err := exec.LookPath("ssh"); err != nil {
+		logger.Debug("ssh binary not found on the client device", "error", err)
+
+		return false
+	}
+
+	return true
+}
+
+func (r *Local) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
 	mig := attempt.Migration
+	if !r.canDo(mig, logger) {
+		return ErrUnaccepted
+	}
+
+	attempt.Accept()
+
 	sourceInfo := mig.SourceInfo
 	destInfo := mig.DestInfo
 
-	srcReleaseName, destReleaseName, privateKey, err := r.installLocalReleases(attempt, logger)
+	warnUnverifiedHostKey(logger)
+
+	srcReleaseName, destReleaseName, privateKey, err := r.installLocalReleases(ctx, attempt, logger)
 	if err != nil {
 		return fmt.Errorf("failed to install local releases: %w", err)
 	}
 
 	releaseNames := []string{srcReleaseName, destReleaseName}
 
-	doneCh := registerCleanupHook(attempt, releaseNames, logger)
-	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+	defer cleanupReleases(ctx, attempt, releaseNames, logger)
 
 	srcFwdPort, srcStopChan, err := portForwardToSshd(ctx, sourceInfo, srcReleaseName, logger)
 	if err != nil {
@@ -76,19 +115,47 @@ func (r *Local) Run(ctx context.Context, attempt *migration.Attempt, logger *slo
 		os.Remove(privateKeyFile)
 	}()
 
+	if mig.Request.Parallel > 1 {
+		logger.Warn("🔶 Parallel transfer is not supported by the local strategy, ignoring it")
+	}
+
+	if mig.Request.EvictionRetries > 0 {
+		logger.Warn("🔶 Eviction retries are not supported by the local strategy, as it does not run an rsync pod, " +
+			"ignoring them")
+	}
+
+	if len(mig.Request.ExtraVolumes) > 0 {
+		logger.Warn("🔶 Extra volumes are not supported by the local strategy, as it does not run an rsync pod, " +
+			"ignoring them")
+	}
+
 	rsyncCmd, err := buildRsyncCmdLocal(mig)
 	if err != nil {
 		return fmt.Errorf("failed to build rsync command: %w", err)
 	}
 
-	cmd := exec.Command("ssh", "-i", privateKeyFile,
+	if mig.Request.PrintCommand {
+		logger.Info("📋 Rsync command", "command", rsyncCmd)
+	}
+
+	sshUser := "root"
+	if mig.Request.RsyncUser != "" {
+		sshUser = mig.Request.RsyncUser
+	}
+
+	cmd := exec.CommandContext(ctx, "ssh", "-i", privateKeyFile,
 		"-p", strconv.Itoa(srcFwdPort),
 		"-R", fmt.Sprintf("%d:localhost:%d", sshReverseTunnelPort, destFwdPort),
-		"-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null", "root@localhost",
+		"-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null", sshUser+"@localhost",
 		rsyncCmd,
 	)
+	cmd.Stdin = strings.NewReader(mig.Request.FilesFrom)
 
-	if err = runCmdLocal(ctx, attempt, cmd, logger); err != nil {
+	transferCtx, span := tracing.Start(ctx, "transfer")
+	err = runCmdLocal(transferCtx, attempt, cmd, logger)
+	tracing.End(span, err)
+
+	if err != nil {
 		return fmt.Errorf("failed to run rsync command: %w", err)
 	}
 
@@ -103,7 +170,14 @@ func runCmdLocal(ctx context.Context, attempt *migration.Attempt, cmd *exec.Cmd,
 	errorCh := make(chan error)
 
 	//nolint:godox
-	go func() { errorCh <- cmd.Run() }() // todo: this is a mess, refactor
+	go func() { // todo: this is a mess, refactor
+		err := cmd.Run()
+
+		// for the progress logger to read the output to its end
+		writer.Close()
+
+		errorCh <- err
+	}()
 
 	canDisplayProgressBar := ctx.Value(progress.CanDisplayProgressBarContextKey{}) != nil
 	progressBarRequested := !attempt.Migration.Request.NoProgressBar
@@ -119,6 +193,10 @@ func runCmdLocal(ctx context.Context, attempt *migration.Attempt, cmd *exec.Cmd,
 	tailCtx, tailCancel := context.WithCancel(ctx)
 	defer tailCancel()
 
+	if attempt.Migration.Request.RsyncVerbose > 1 {
+		tailCtx = context.WithValue(tailCtx, progress.VerboseContextKey{}, true)
+	}
+
 	var eg errgroup.Group //nolint:varnamelen
 
 	eg.Go(func() error {
@@ -144,18 +222,15 @@ func runCmdLocal(ctx context.Context, attempt *migration.Attempt, cmd *exec.Cmd,
 }
 
 func buildRsyncCmdLocal(mig *migration.Migration) (string, error) {
-	srcPath := srcMountPath + "/" + mig.Request.Source.Path
-	destPath := destMountPath + "/" + mig.Request.Dest.Path
-
-	rsyncCmd := rsync.Cmd{
-		Port:        sshReverseTunnelPort,
-		NoChown:     mig.Request.NoChown,
-		Delete:      mig.Request.DeleteExtraneousFiles,
-		SrcPath:     srcPath,
-		DestPath:    destPath,
-		DestUseSSH:  true,
-		DestSSHHost: "localhost",
-		Compress:    mig.Request.Compress,
+	rsyncCmd := newRsyncCmd(mig.Request)
+	rsyncCmd.Port = sshReverseTunnelPort
+	rsyncCmd.DestUseSSH = true
+	rsyncCmd.DestSSHHost = "localhost"
+	rsyncCmd.Parallel = 0
+
+	if mig.Request.FilesFrom != "" {
+		// the list is passed through the standard input of ssh, as nothing is mounted from the client device
+		rsyncCmd.FilesFrom = "-"
 	}
 
 	cmd, err := rsyncCmd.Build()
@@ -166,7 +241,9 @@ func buildRsyncCmdLocal(mig *migration.Migration) (string, error) {
 	return cmd, nil
 }
 
-func (r *Local) installLocalReleases(attempt *migration.Attempt, logger *slog.Logger) (string, string, string, error) {
+func (r *Local) installLocalReleases(ctx context.Context, attempt *migration.Attempt,
+	logger *slog.Logger,
+) (string, string, string, error) {
 	keyAlgorithm := attempt.Migration.Request.KeyAlgorithm
 
 	logger.Info("🔑 Generating SSH key pair", "algorithm", keyAlgorithm)
@@ -181,13 +258,13 @@ func (r *Local) installLocalReleases(attempt *migration.Attempt, logger *slog.Lo
 	srcReleaseName := attempt.HelmReleaseNamePrefix + "-src"
 	destReleaseName := attempt.HelmReleaseNamePrefix + "-dest"
 
-	err = installLocalOnSource(attempt, srcReleaseName, publicKey,
+	err = installLocalOnSource(ctx, attempt, srcReleaseName, publicKey,
 		privateKey, privateKeyMountPath, srcMountPath, logger)
 	if err != nil {
 		return "", "", "", err
 	}
 
-	err = installLocalOnDest(attempt, destReleaseName, publicKey, destMountPath, logger)
+	err = installLocalOnDest(ctx, attempt, destReleaseName, publicKey, destMountPath, logger)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -206,7 +283,7 @@ func getSshdPodForHelmRelease(ctx context.Context, pvcInfo *pvc.Info, name strin
 	return pod, nil
 }
 
-func installLocalOnSource(attempt *migration.Attempt, releaseName,
+func installLocalOnSource(ctx context.Context, attempt *migration.Attempt, releaseName,
 	publicKey, privateKey, privateKeyMountPath, srcMountPath string, logger *slog.Logger,
 ) error {
 	mig := attempt.Migration
@@ -229,13 +306,16 @@ func installLocalOnSource(attempt *migration.Attempt, releaseName,
 				},
 			},
 			"affinity": sourceInfo.AffinityHelmValues,
+			"service": map[string]any{
+				"enabled": false,
+			},
 		},
 	}
 
-	return installHelmChart(attempt, sourceInfo, releaseName, vals, logger)
+	return installHelmChart(ctx, attempt, sourceInfo, releaseName, vals, logger)
 }
 
-func installLocalOnDest(attempt *migration.Attempt, releaseName,
+func installLocalOnDest(ctx context.Context, attempt *migration.Attempt, releaseName,
 	publicKey, destMountPath string, logger *slog.Logger,
 ) error {
 	mig := attempt.Migration
@@ -254,6 +334,9 @@ func installLocalOnDest(attempt *migration.Attempt, releaseName,
 				},
 			},
 			"affinity": destInfo.AffinityHelmValues,
+			"service": map[string]any{
+				"enabled": false,
+			},
 		},
 	}
 
@@ -264,7 +347,7 @@ func installLocalOnDest(attempt *migration.Attempt, releaseName,
 
 	defer func() { _ = os.Remove(valsFile) }()
 
-	return installHelmChart(attempt, destInfo, releaseName, vals, logger)
+	return installHelmChart(ctx, attempt, destInfo, releaseName, vals, logger)
 }
 
 func writePrivateKeyToTempFile(privateKey string) (string, error) {
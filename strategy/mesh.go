@@ -0,0 +1,95 @@
+package strategy
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+const (
+	sshPort = 22
+
+	istioInjectionLabel     = "istio-injection"
+	istioRevisionLabel      = "istio.io/rev"
+	istioInjectAnnotation   = "sidecar.istio.io/inject"
+	istioExcludeInboundKey  = "traffic.sidecar.istio.io/excludeInboundPorts"
+	istioExcludeOutboundKey = "traffic.sidecar.istio.io/excludeOutboundPorts"
+)
+
+// applyMeshAnnotations annotates the pods of the release if the Istio sidecar injection is enabled
+// for its namespace, as the sidecars intercept the SSH connections and keep the rsync pods from completing.
+//
+// The injection is disabled for the pods, unless the sidecars are allowed by the request. In that case,
+// only the ports of the connections of the migration are excluded from the traffic redirection to the sidecars.
+func applyMeshAnnotations(ctx context.Context, values map[string]any, pvcInfo *pvc.Info,
+	req *migration.Request, logger *slog.Logger,
+) {
+	namespace := pvcInfo.Claim.Namespace
+
+	ns, err := pvcInfo.ClusterClient.KubeClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		logger.Debug("skipping the service mesh detection", "namespace", namespace, "error", err)
+
+		return
+	}
+
+	_, revisioned := ns.Labels[istioRevisionLabel]
+	if ns.Labels[istioInjectionLabel] != "enabled" && !revisioned {
+		return
+	}
+
+	if req.AllowSidecars {
+		logger.Info("🕸️ Istio sidecar injection is enabled in the namespace, "+
+			"excluding the SSH connections from the sidecars", "namespace", namespace)
+	} else {
+		logger.Info("🕸️ Istio sidecar injection is enabled in the namespace, "+
+			"disabling it for the migration pods", "namespace", namespace)
+	}
+
+	ports := meshExcludedPorts(req)
+
+	for component, excluded := range map[string]map[string]string{
+		"sshd":  {istioExcludeInboundKey: ports},
+		"rsync": {istioExcludeOutboundKey: ports},
+	} {
+		componentVals, ok := values[component].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		annotations, _ := componentVals["podAnnotations"].(map[string]any)
+		if annotations == nil {
+			annotations = map[string]any{}
+		}
+
+		if req.AllowSidecars {
+			for key, value := range excluded {
+				annotations[key] = value
+			}
+		} else {
+			annotations[istioInjectAnnotation] = "false"
+		}
+
+		componentVals["podAnnotations"] = annotations
+	}
+}
+
+// meshExcludedPorts returns the comma-separated ports the connections of the migration can be made to,
+// i.e., those of SSH, of the rsync daemon and of the SSH endpoint given by the request.
+func meshExcludedPorts(req *migration.Request) string {
+	ports := []string{strconv.Itoa(sshPort)}
+
+	for _, port := range []int{req.RsyncdPort, req.DestSSHPort} {
+		if port != 0 && port != sshPort {
+			ports = append(ports, strconv.Itoa(port))
+		}
+	}
+
+	return strings.Join(ports, ",")
+}
@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
-	"github.com/utkuozdemir/pv-migrate/rsync"
 )
 
 type Mnt2 struct{}
@@ -22,7 +20,7 @@ func (r *Mnt2) canDo(t *migration.Migration) bool {
 	}
 
 	sameNamespace := sourceInfo.Claim.Namespace == destInfo.Claim.Namespace
-	if !sameNamespace {
+	if !sameNamespace || t.NoCommonNode {
 		return false
 	}
 
@@ -38,54 +36,91 @@ func (r *Mnt2) Run(ctx context.Context, attempt *migration.Attempt, logger *slog
 		return ErrUnaccepted
 	}
 
+	attempt.Accept()
+
 	sourceInfo := attempt.Migration.SourceInfo
 	destInfo := attempt.Migration.DestInfo
 	namespace := sourceInfo.Claim.Namespace
 
 	node := determineTargetNode(mig)
 
-	rsyncCmd, err := buildRsyncCmdMnt2(mig)
-	if err != nil {
-		return fmt.Errorf("failed to build rsync command: %w", err)
+	rsyncVals := map[string]any{
+		"enabled":   true,
+		"namespace": namespace,
+		"nodeName":  node,
+		"affinity":  sourceInfo.AffinityHelmValues,
 	}
 
-	vals := map[string]any{
-		"rsync": map[string]any{
-			"enabled":   true,
-			"namespace": namespace,
-			"nodeName":  node,
-			"pvcMounts": []map[string]any{
-				{
-					"name":      sourceInfo.Claim.Name,
-					"mountPath": srcMountPath,
-					"readOnly":  mig.Request.SourceMountReadOnly,
-				},
-				{
-					"name":      destInfo.Claim.Name,
-					"mountPath": destMountPath,
-				},
+	releaseName := attempt.HelmReleaseNamePrefix
+	parallelism := 1
+
+	if sourceInfo.BlockMode {
+		logger.Info("💽 Source and destination PVCs are block devices, they will be copied using dd")
+
+		if mig.Request.Parallel > 1 {
+			logger.Warn("🔶 Parallel transfer is not supported for block devices, ignoring it")
+		}
+
+		if len(mig.Request.BwLimitSchedule) > 0 {
+			logger.Warn("🔶 Bandwidth limit schedule is not supported for block devices, ignoring it")
+		}
+
+		if mig.Request.FailIfDestNotEmpty {
+			logger.Warn("🔶 Checking the destination to be empty is not supported for block devices, ignoring it")
+		}
+
+		rsyncVals["pvcDevices"] = []map[string]any{
+			{
+				"name":       sourceInfo.Claim.Name,
+				"devicePath": srcDevicePath,
+				"readOnly":   mig.Request.SourceMountReadOnly,
+			},
+			{
+				"name":       destInfo.Claim.Name,
+				"devicePath": destDevicePath,
 			},
-			"command":  rsyncCmd,
-			"affinity": sourceInfo.AffinityHelmValues,
-		},
+		}
+		rsyncVals["command"] = buildBlockCopyCmd()
+	} else {
+		rsyncCmd, err := buildRsyncCmdMnt2(mig)
+		if err != nil {
+			return fmt.Errorf("failed to build rsync command: %w", err)
+		}
+
+		rsyncVals["pvcMounts"] = []map[string]any{
+			{
+				"name":      sourceInfo.Claim.Name,
+				"mountPath": srcMountPath,
+				"readOnly":  mig.Request.SourceMountReadOnly,
+			},
+			{
+				"name":      destInfo.Claim.Name,
+				"mountPath": destMountPath,
+			},
+		}
+		rsyncVals["command"] = rsyncCmd
+		applyRsyncMounts(rsyncVals, mig.Request)
+		applyDestEmptyCheck(rsyncVals, mig.Request)
+		parallelism = applyParallelism(rsyncVals, mig, releaseName, true)
+	}
+
+	vals := map[string]any{
+		"rsync": rsyncVals,
 	}
 
-	releaseName := attempt.HelmReleaseNamePrefix
 	releaseNames := []string{releaseName}
 
-	doneCh := registerCleanupHook(attempt, releaseNames, logger)
-	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+	defer cleanupReleases(ctx, attempt, releaseNames, logger)
 
-	err = installHelmChart(attempt, sourceInfo, releaseName, vals, logger)
+	err := installHelmChart(ctx, attempt, sourceInfo, releaseName, vals, logger)
 	if err != nil {
 		return fmt.Errorf("failed to install helm chart: %w", err)
 	}
 
-	showProgressBar := !mig.Request.NoProgressBar
 	kubeClient := mig.SourceInfo.ClusterClient.KubeClient
-	jobName := attempt.HelmReleaseNamePrefix + "-rsync"
+	jobName := releaseName + "-rsync"
 
-	if err = k8s.WaitForJobCompletion(ctx, kubeClient, namespace, jobName, showProgressBar, logger); err != nil {
+	if err = waitForRsyncJob(ctx, mig, kubeClient, namespace, jobName, parallelism, logger); err != nil {
 		return fmt.Errorf("failed to wait for job completion: %w", err)
 	}
 
@@ -93,16 +128,7 @@ func (r *Mnt2) Run(ctx context.Context, attempt *migration.Attempt, logger *slog
 }
 
 func buildRsyncCmdMnt2(mig *migration.Migration) (string, error) {
-	srcPath := srcMountPath + "/" + mig.Request.Source.Path
-	destPath := destMountPath + "/" + mig.Request.Dest.Path
-
-	rsyncCmd := rsync.Cmd{
-		NoChown:  mig.Request.NoChown,
-		Delete:   mig.Request.DeleteExtraneousFiles,
-		SrcPath:  srcPath,
-		DestPath: destPath,
-		Compress: mig.Request.Compress,
-	}
+	rsyncCmd := newRsyncCmd(mig.Request)
 
 	cmd, err := rsyncCmd.Build()
 	if err != nil {
@@ -112,6 +138,11 @@ func buildRsyncCmdMnt2(mig *migration.Migration) (string, error) {
 	return cmd, nil
 }
 
+// buildBlockCopyCmd builds the command to copy the raw source block device into the destination block device.
+func buildBlockCopyCmd() string {
+	return fmt.Sprintf("dd if=%s of=%s bs=%s conv=fsync", srcDevicePath, destDevicePath, blockCopyBlockSize)
+}
+
 func determineTargetNode(t *migration.Migration) string {
 	sourceInfo := t.SourceInfo
 	destInfo := t.DestInfo
@@ -0,0 +1,243 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/tracing"
+	"github.com/utkuozdemir/pv-migrate/util"
+)
+
+const (
+	// ObjStorePhaseAll, ObjStorePhaseUpload and ObjStorePhaseDownload are the phases of the objstore strategy
+	// to run, i.e., both of them in order, or only one of them, e.g., to run them at different times.
+	ObjStorePhaseAll      = migration.IntermediatePhaseAll
+	ObjStorePhaseUpload   = migration.IntermediatePhaseUpload
+	ObjStorePhaseDownload = migration.IntermediatePhaseDownload
+
+	// DefaultIntermediateImage is the image running rclone in the jobs of the objstore strategy.
+	DefaultIntermediateImage = "docker.io/rclone/rclone:1.68.1"
+
+	// IntermediateRemote is the name of the rclone remote of the object store, which the rclone.conf
+	// in the credentials secret is expected to define.
+	IntermediateRemote = "intermediate"
+	// IntermediateCredentialsKey is the key of the rclone.conf in the credentials secret.
+	IntermediateCredentialsKey = "rclone.conf"
+
+	// intermediateCredentialsMountPath is where the credentials secret is mounted into the rclone pods.
+	intermediateCredentialsMountPath = "/etc/pv-migrate/rclone"
+)
+
+// ObjStorePhases are the phases of the objstore strategy which can be run.
+var ObjStorePhases = migration.IntermediatePhases
+
+// ObjStore migrates the data in two phases through an intermediate bucket of an object store, e.g., S3,
+// for the clusters which cannot reach each other reliably. A job next to the source PVC uploads the data
+// to the bucket with rclone, then a job next to the destination PVC downloads it from there.
+//
+// The data is staged under a prefix derived from the source PVC, and rclone only transfers the files
+// which differ from those already there. This way, each phase resumes where it left off, at the granularity
+// of the files, when it is retried or run again after a failure, e.g., a complete network drop.
+//
+// As the files are transferred with rclone, it does not accept the migrations with the options of rsync
+// selecting or transforming the files, limiting the deletions or preserving the hard links.
+type ObjStore struct{}
+
+func (o *ObjStore) canDo(t *migration.Migration) bool {
+	s := t.SourceInfo
+	d := t.DestInfo
+	sameCluster := s.ClusterClient.RestConfig.Host == d.ClusterClient.RestConfig.Host
+
+	if t.Request.IntermediateBucket == "" || sameCluster || s.BlockMode || d.BlockMode {
+		return false
+	}
+
+	return len(objStoreUnsupportedOptions(t.Request)) == 0
+}
+
+// objStoreUnsupportedOptions returns the names of the options of the request which rclone cannot honor.
+func objStoreUnsupportedOptions(req *migration.Request) []string {
+	options := rsyncSelectionOptions(req)
+
+	if req.MaxDelete != nil {
+		options = append(options, "max-delete")
+	}
+
+	if req.HardLinks {
+		options = append(options, "hard-links")
+	}
+
+	return options
+}
+
+func (o *ObjStore) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
+	mig := attempt.Migration
+	if !o.canDo(mig) {
+		if options := objStoreUnsupportedOptions(mig.Request); len(options) > 0 {
+			logger.Debug("the objstore strategy does not support the options of rsync",
+				"options", strings.Join(options, ","))
+		}
+
+		return ErrUnaccepted
+	}
+
+	attempt.Accept()
+
+	req := mig.Request
+
+	if req.Parallel > 1 {
+		logger.Warn("🔶 Parallel transfer is not supported by the objstore strategy, ignoring it")
+	}
+
+	remotePath := intermediatePath(mig)
+
+	if req.IntermediatePhase != ObjStorePhaseDownload {
+		logger.Info("☁️ Uploading the source to the object store", "path", remotePath)
+
+		vals, err := buildObjStoreHelmVals(mig, ObjStorePhaseUpload)
+		if err != nil {
+			return fmt.Errorf("failed to build helm values: %w", err)
+		}
+
+		if err = runObjStorePhase(ctx, attempt, mig.SourceInfo, attempt.HelmReleaseNamePrefix+"-upload",
+			vals, logger); err != nil {
+			return fmt.Errorf("failed to upload the source to the object store: %w", err)
+		}
+	}
+
+	if req.IntermediatePhase != ObjStorePhaseUpload {
+		logger.Info("☁️ Downloading the destination from the object store", "path", remotePath)
+
+		vals, err := buildObjStoreHelmVals(mig, ObjStorePhaseDownload)
+		if err != nil {
+			return fmt.Errorf("failed to build helm values: %w", err)
+		}
+
+		if err = runObjStorePhase(ctx, attempt, mig.DestInfo, attempt.HelmReleaseNamePrefix+"-download",
+			vals, logger); err != nil {
+			return fmt.Errorf("failed to download the destination from the object store: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// runObjStorePhase installs the release of a phase and waits for its job, removing the release afterwards
+// for the next phase not to keep the PVC of this one mounted.
+func runObjStorePhase(ctx context.Context, attempt *migration.Attempt, pvcInfo *pvc.Info, releaseName string,
+	vals map[string]any, logger *slog.Logger,
+) (retErr error) {
+	releaseNames := []string{releaseName}
+
+	defer cleanupReleases(ctx, attempt, releaseNames, logger)
+
+	if err := installHelmChart(ctx, attempt, pvcInfo, releaseName, vals, logger); err != nil {
+		return fmt.Errorf("failed to install helm chart: %w", err)
+	}
+
+	ctx, span := tracing.Start(ctx, "transfer", attribute.String("pv_migrate.release", releaseName))
+	defer func() { tracing.End(span, retErr) }()
+
+	// the progress bar is not displayed, as it is parsed from the output of rsync
+	if err := k8s.WaitForJobCompletion(ctx, pvcInfo.ClusterClient.KubeClient, pvcInfo.Claim.Namespace,
+		releaseName+"-rsync", false, attempt.Migration.Request.EvictionRetries, logger); err != nil {
+		return fmt.Errorf("failed to wait for job completion: %w", err)
+	}
+
+	return nil
+}
+
+// intermediatePath returns the rclone path of the data in the object store, i.e., under the bucket
+// in a prefix derived from the source PVC, for the phases to find the data of each other across the attempts.
+func intermediatePath(mig *migration.Migration) string {
+	claim := mig.SourceInfo.Claim
+
+	return fmt.Sprintf("%s:%s/%s/%s", IntermediateRemote, strings.Trim(mig.Request.IntermediateBucket, "/"),
+		claim.Namespace, claim.Name)
+}
+
+// buildRcloneCmd builds the rclone command of the phase. The upload mirrors the source in the object store,
+// while the download only deletes the extraneous files on the destination if requested.
+func buildRcloneCmd(mig *migration.Migration, phase string) string {
+	req := mig.Request
+	remotePath := intermediatePath(mig)
+	srcPath := dirContents(path.Join(srcMountPath, req.Source.Path))
+	destPath := dirContents(path.Join(destMountPath, req.Dest.Path))
+
+	subcommand := "copy"
+	if phase == ObjStorePhaseUpload || req.DeleteExtraneousFiles {
+		subcommand = "sync"
+	}
+
+	args := []string{
+		"RCLONE_CONFIG=" + path.Join(intermediateCredentialsMountPath, IntermediateCredentialsKey),
+		"rclone", subcommand,
+		// the owners, the permissions and the times of the files are kept in the metadata of the objects
+		"--metadata", "--links", "--create-empty-src-dirs",
+		"--stats=1m", "--stats-one-line", "--stats-log-level=NOTICE",
+	}
+
+	if !req.IncludeLostFound && strings.Trim(req.Source.Path, "/") == "" {
+		args = append(args, "--exclude='/lost+found/**'")
+	}
+
+	if phase == ObjStorePhaseUpload {
+		return strings.Join(append(args, fmt.Sprintf("'%s'", srcPath), fmt.Sprintf("'%s'", remotePath)), " ")
+	}
+
+	return strings.Join(append(args, fmt.Sprintf("'%s'", remotePath), fmt.Sprintf("'%s'", destPath)), " ")
+}
+
+func buildObjStoreHelmVals(mig *migration.Migration, phase string) (map[string]any, error) {
+	req := mig.Request
+
+	image := req.IntermediateImage
+	if image == "" {
+		image = DefaultIntermediateImage
+	}
+
+	repository, tag, err := util.ParseImage(image)
+	if err != nil {
+		return nil, fmt.Errorf("invalid intermediate image: %w", err)
+	}
+
+	pvcInfo := mig.DestInfo
+	mount := map[string]any{"name": pvcInfo.Claim.Name, "mountPath": destMountPath}
+
+	if phase == ObjStorePhaseUpload {
+		pvcInfo = mig.SourceInfo
+		mount = map[string]any{"name": pvcInfo.Claim.Name, "mountPath": srcMountPath, "readOnly": true}
+	}
+
+	rsyncVals := map[string]any{
+		"enabled":   true,
+		"namespace": pvcInfo.Claim.Namespace,
+		"image": map[string]any{
+			"repository": repository,
+			"tag":        tag,
+		},
+		"pvcMounts": []map[string]any{mount},
+		"extraVolumes": []map[string]any{
+			{
+				"secret":    req.IntermediateSecret,
+				"mountPath": intermediateCredentialsMountPath,
+			},
+		},
+		"command":  buildRcloneCmd(mig, phase),
+		"affinity": pvcInfo.AffinityHelmValues,
+	}
+
+	if phase == ObjStorePhaseDownload {
+		applyDestEmptyCheck(rsyncVals, req)
+	}
+
+	return map[string]any{"rsync": rsyncVals}, nil
+}
@@ -0,0 +1,121 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+func TestObjStoreCanDo(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pvcA := buildTestPVC("namespace1", "pvc1", corev1.ReadWriteOnce)
+	pvcB := buildTestPVC("namespace2", "pvc2", corev1.ReadWriteOnce)
+	c1 := buildTestClient(pvcA, pvcB)
+	c2 := buildTestClientWithAPIServerHost("https://10.0.0.1:6443", pvcB)
+	src, _ := pvc.New(ctx, c1, "namespace1", "pvc1")
+	sameClusterDst, _ := pvc.New(ctx, c1, "namespace2", "pvc2")
+	otherClusterDst, _ := pvc.New(ctx, c2, "namespace2", "pvc2")
+
+	s := ObjStore{}
+
+	mig := migration.Migration{
+		Request:    &migration.Request{IntermediateBucket: "my-bucket"},
+		SourceInfo: src,
+		DestInfo:   otherClusterDst,
+	}
+	assert.True(t, s.canDo(&mig))
+
+	mig.DestInfo = sameClusterDst
+	assert.False(t, s.canDo(&mig), "the strategy must not be used within a cluster")
+
+	mig.DestInfo = otherClusterDst
+	mig.Request.IntermediateBucket = ""
+	assert.False(t, s.canDo(&mig), "the strategy must not be used without a bucket")
+
+	maxDelete := 0
+
+	for name, modify := range map[string]func(req *migration.Request){
+		"files from":      func(req *migration.Request) { req.FilesFrom = "a\n" },
+		"filter file":     func(req *migration.Request) { req.FilterFile = "- *.tmp\n" },
+		"since":           func(req *migration.Request) { req.Since = time.Now() },
+		"dirs only":       func(req *migration.Request) { req.DirsOnly = true },
+		"chmod":           func(req *migration.Request) { req.Chmod = "g+w" },
+		"iconv":           func(req *migration.Request) { req.Iconv = "utf8,latin1" },
+		"update":          func(req *migration.Request) { req.Update = true },
+		"ignore existing": func(req *migration.Request) { req.IgnoreExisting = true },
+		"compare dest":    func(req *migration.Request) { req.CompareDest = "/previous" },
+		"backup":          func(req *migration.Request) { req.Backup = true },
+		"max delete":      func(req *migration.Request) { req.MaxDelete = &maxDelete },
+		"hard links":      func(req *migration.Request) { req.HardLinks = true },
+	} {
+		request := migration.Request{IntermediateBucket: "my-bucket"}
+		modify(&request)
+
+		assert.False(t, s.canDo(&migration.Migration{Request: &request, SourceInfo: src, DestInfo: otherClusterDst}),
+			"the strategy must not be used with %s", name)
+	}
+}
+
+func TestBuildObjStoreHelmVals(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pvcA := buildTestPVC("namespace1", "pvc1", corev1.ReadWriteOnce)
+	pvcB := buildTestPVC("namespace2", "pvc2", corev1.ReadWriteOnce)
+	src, _ := pvc.New(ctx, buildTestClient(pvcA), "namespace1", "pvc1")
+	dst, _ := pvc.New(ctx, buildTestClientWithAPIServerHost("https://10.0.0.1:6443", pvcB), "namespace2", "pvc2")
+
+	mig := migration.Migration{
+		Request: &migration.Request{
+			Source:             &migration.PVCInfo{Namespace: "namespace1", Name: "pvc1", Path: "/"},
+			Dest:               &migration.PVCInfo{Namespace: "namespace2", Name: "pvc2", Path: "/data"},
+			IntermediateBucket: "my-bucket/migrations/",
+			IntermediateSecret: "rclone-config",
+			IntermediateImage:  DefaultIntermediateImage,
+		},
+		SourceInfo: src,
+		DestInfo:   dst,
+	}
+
+	rsyncVals := func(phase string) map[string]any {
+		vals, err := buildObjStoreHelmVals(&mig, phase)
+		require.NoError(t, err)
+
+		rsyncVals, _ := vals["rsync"].(map[string]any)
+
+		return rsyncVals
+	}
+
+	upload := rsyncVals(ObjStorePhaseUpload)
+	assert.Equal(t, "namespace1", upload["namespace"])
+	assert.Equal(t, []map[string]any{{"name": "pvc1", "mountPath": srcMountPath, "readOnly": true}},
+		upload["pvcMounts"])
+	assert.Equal(t, []map[string]any{{"secret": "rclone-config", "mountPath": intermediateCredentialsMountPath}},
+		upload["extraVolumes"])
+	assert.Equal(t, map[string]any{"repository": "docker.io/rclone/rclone", "tag": "1.68.1"}, upload["image"])
+	assert.Contains(t, upload["command"], "RCLONE_CONFIG=/etc/pv-migrate/rclone/rclone.conf rclone sync ")
+	assert.Contains(t, upload["command"], " --exclude='/lost+found/**' ")
+	assert.Contains(t, upload["command"], " '/source/' 'intermediate:my-bucket/migrations/namespace1/pvc1'")
+
+	download := rsyncVals(ObjStorePhaseDownload)
+	assert.Equal(t, "namespace2", download["namespace"])
+	assert.Equal(t, []map[string]any{{"name": "pvc2", "mountPath": destMountPath}}, download["pvcMounts"])
+	assert.Contains(t, download["command"], " rclone copy ")
+	assert.Contains(t, download["command"], " 'intermediate:my-bucket/migrations/namespace1/pvc1' '/dest/data/'")
+
+	mig.Request.DeleteExtraneousFiles = true
+	assert.Contains(t, rsyncVals(ObjStorePhaseDownload)["command"], " rclone sync ")
+}
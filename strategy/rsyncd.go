@@ -0,0 +1,143 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/util"
+)
+
+const (
+	rsyncdModule         = "pv-migrate"
+	rsyncdUser           = "pv-migrate"
+	rsyncdPasswordLength = 32
+)
+
+// Rsyncd runs an rsync daemon next to the source PVC and an rsync client next to the destination PVC,
+// which connects to the daemon with the rsync protocol over a Kubernetes Service (ClusterIP).
+//
+// It is an alternative to the svc strategy for the clusters where SSH is not allowed, and needs no SSH keys.
+// The client authenticates with a password generated for each attempt.
+type Rsyncd struct{}
+
+func (r *Rsyncd) canDo(t *migration.Migration) bool {
+	s := t.SourceInfo
+	d := t.DestInfo
+	sameCluster := s.ClusterClient.RestConfig.Host == d.ClusterClient.RestConfig.Host
+
+	return sameCluster && !s.BlockMode
+}
+
+func (r *Rsyncd) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
+	mig := attempt.Migration
+	if !r.canDo(mig) {
+		return ErrUnaccepted
+	}
+
+	attempt.Accept()
+
+	if mig.Request.Parallel > 1 {
+		logger.Warn("🔶 Parallel transfer is not supported by the rsyncd strategy, ignoring it")
+	}
+
+	releaseName := attempt.HelmReleaseNamePrefix
+	releaseNames := []string{releaseName}
+
+	helmVals, err := buildRsyncdHelmVals(mig, releaseName)
+	if err != nil {
+		return fmt.Errorf("failed to build helm values: %w", err)
+	}
+
+	rsyncVals := helmVals["rsync"].(map[string]any) //nolint:forcetypeassert
+	applyRsyncMounts(rsyncVals, mig.Request)
+	applyDestEmptyCheck(rsyncVals, mig.Request)
+
+	defer cleanupReleases(ctx, attempt, releaseNames, logger)
+
+	err = installHelmChart(ctx, attempt, mig.DestInfo, releaseName, helmVals, logger)
+	if err != nil {
+		return fmt.Errorf("failed to install helm chart: %w", err)
+	}
+
+	kubeClient := mig.SourceInfo.ClusterClient.KubeClient
+	jobName := releaseName + "-rsync"
+
+	if err = waitForRsyncJob(ctx, mig, kubeClient,
+		mig.DestInfo.Claim.Namespace, jobName, 1, logger); err != nil {
+		return fmt.Errorf("failed to wait for job completion: %w", err)
+	}
+
+	return nil
+}
+
+func buildRsyncdHelmVals(mig *migration.Migration, helmReleaseName string) (map[string]any, error) {
+	sourceInfo := mig.SourceInfo
+	destInfo := mig.DestInfo
+	sourceNs := sourceInfo.Claim.Namespace
+	port := mig.Request.RsyncdPort
+	password := util.RandomHexadecimalString(rsyncdPasswordLength)
+
+	rsyncCmd := newRsyncCmd(mig.Request)
+	rsyncCmd.SrcUseDaemon = true
+	rsyncCmd.SrcSSHHost = helmReleaseName + "-sshd." + sourceNs
+	rsyncCmd.SrcSSHUser = rsyncdUser
+	rsyncCmd.SrcDaemonModule = rsyncdModule
+	rsyncCmd.SrcPath = dirContents(strings.TrimPrefix(path.Join("/", mig.Request.Source.Path), "/"))
+	rsyncCmd.Port = port
+	rsyncCmd.Parallel = 0
+
+	rsyncCmdStr, err := rsyncCmd.Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build rsync command: %w", err)
+	}
+
+	probe := map[string]any{
+		"tcpSocket": map[string]any{"port": "rsyncd"},
+	}
+
+	return map[string]any{
+		"rsync": map[string]any{
+			"enabled":        true,
+			"namespace":      destInfo.Claim.Namespace,
+			"rsyncdPassword": password,
+			"pvcMounts": []map[string]any{
+				{
+					"name":      destInfo.Claim.Name,
+					"mountPath": destMountPath,
+				},
+			},
+			"command":  rsyncCmdStr,
+			"affinity": destInfo.AffinityHelmValues,
+		},
+		"sshd": map[string]any{
+			"enabled":        true,
+			"namespace":      sourceNs,
+			"publicKeyMount": false,
+			"rsyncd": map[string]any{
+				"enabled":  true,
+				"port":     port,
+				"module":   rsyncdModule,
+				"path":     srcMountPath,
+				"user":     rsyncdUser,
+				"password": password,
+			},
+			"service": map[string]any{
+				"port": port,
+			},
+			"readinessProbe": probe,
+			"livenessProbe":  probe,
+			"pvcMounts": []map[string]any{
+				{
+					"name":      sourceInfo.Claim.Name,
+					"mountPath": srcMountPath,
+					"readOnly":  mig.Request.SourceMountReadOnly,
+				},
+			},
+			"affinity": sourceInfo.AffinityHelmValues,
+		},
+	}, nil
+}
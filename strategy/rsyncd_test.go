@@ -0,0 +1,55 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+func TestBuildRsyncdHelmVals(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pvcA := buildTestPVC("namespace1", "pvc1", corev1.ReadWriteOnce)
+	pvcB := buildTestPVC("namespace2", "pvc2", corev1.ReadWriteOnce)
+	c := buildTestClient(pvcA, pvcB)
+	src, _ := pvc.New(ctx, c, "namespace1", "pvc1")
+	dst, _ := pvc.New(ctx, c, "namespace2", "pvc2")
+
+	mig := migration.Migration{
+		Request: &migration.Request{
+			Source:     &migration.PVCInfo{Namespace: "namespace1", Name: "pvc1", Path: "/data"},
+			Dest:       &migration.PVCInfo{Namespace: "namespace2", Name: "pvc2", Path: "/"},
+			RsyncdPort: 873,
+			SockOpts:   "SO_SNDBUF=4194304,SO_RCVBUF=4194304",
+		},
+		SourceInfo: src,
+		DestInfo:   dst,
+	}
+
+	s := Rsyncd{}
+	assert.True(t, s.canDo(&mig))
+
+	vals, err := buildRsyncdHelmVals(&mig, "pv-migrate-abcde")
+	require.NoError(t, err)
+
+	rsyncVals, _ := vals["rsync"].(map[string]any)
+	sshdVals, _ := vals["sshd"].(map[string]any)
+	rsyncdVals, _ := sshdVals["rsyncd"].(map[string]any)
+
+	assert.Contains(t, rsyncVals["command"],
+		" rsync://pv-migrate@pv-migrate-abcde-sshd.namespace1:873/pv-migrate/data/ /dest/")
+	assert.NotContains(t, rsyncVals["command"], " -e ")
+	assert.Contains(t, rsyncVals["command"], " --sockopts='SO_SNDBUF=4194304,SO_RCVBUF=4194304' ")
+	assert.NotEmpty(t, rsyncVals["rsyncdPassword"])
+	assert.Equal(t, rsyncVals["rsyncdPassword"], rsyncdVals["password"])
+	assert.Equal(t, true, rsyncdVals["enabled"])
+}
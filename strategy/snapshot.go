@@ -0,0 +1,255 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+const (
+	snapshotReadyTimeout    = 10 * time.Minute
+	pvcDeletionTimeout      = 2 * time.Minute
+	pvcDeletionPollInterval = 2 * time.Second
+)
+
+// pvcAnnotationsToDrop are the annotations set by the controllers on a PVC which
+// must not be carried over when the destination PVC is recreated.
+var pvcAnnotationsToDrop = []string{
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.kubernetes.io/selected-node",
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.beta.kubernetes.io/storage-provisioner",
+}
+
+// Snapshot takes a CSI VolumeSnapshot of the source PVC and recreates the destination PVC
+// with the snapshot as its data source, so that the data is restored by the storage backend
+// instead of being copied file by file.
+//
+// As the destination PVC needs to be recreated, this strategy only accepts destination PVCs
+// which are not yet bound to a volume, i.e., which do not contain any data.
+//
+// As the whole volume is restored, including its lost+found directory, it only accepts the migrations
+// of the whole volume, i.e., with the root as the source and the destination paths and with lost+found included,
+// without any of the options selecting or transforming the files.
+type Snapshot struct{}
+
+func (r *Snapshot) canDo(t *migration.Migration) bool {
+	sourceInfo := t.SourceInfo
+	destInfo := t.DestInfo
+
+	sameCluster := sourceInfo.ClusterClient.RestConfig.Host == destInfo.ClusterClient.RestConfig.Host
+	if !sameCluster {
+		return false
+	}
+
+	sameNamespace := sourceInfo.Claim.Namespace == destInfo.Claim.Namespace
+	if !sameNamespace {
+		return false
+	}
+
+	hasStorageClass := sourceInfo.Claim.Spec.StorageClassName != nil && *sourceInfo.Claim.Spec.StorageClassName != ""
+	if !hasStorageClass {
+		return false
+	}
+
+	destUnbound := destInfo.Claim.Status.Phase == corev1.ClaimPending && destInfo.Claim.Spec.VolumeName == ""
+	if !destUnbound || destInfo.MountedNode != "" {
+		return false
+	}
+
+	return wholeVolume(t.Request)
+}
+
+// wholeVolume returns whether the request migrates the whole source volume as is to the root of the destination.
+func wholeVolume(req *migration.Request) bool {
+	if strings.Trim(req.Source.Path, "/") != "" || strings.Trim(req.Dest.Path, "/") != "" {
+		return false
+	}
+
+	return req.IncludeLostFound && !req.DeleteExtraneousFiles && len(rsyncSelectionOptions(req)) == 0
+}
+
+func (r *Snapshot) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
+	mig := attempt.Migration
+	if !r.canDo(mig) {
+		return ErrUnaccepted
+	}
+
+	sourceInfo := mig.SourceInfo
+	destInfo := mig.DestInfo
+	namespace := sourceInfo.Claim.Namespace
+	dynamicClient := sourceInfo.ClusterClient.DynamicClient
+
+	snapshotClass, err := ResolveSnapshotClass(ctx, mig.Request.SnapshotClass, sourceInfo)
+	if err != nil {
+		if errors.Is(err, k8s.ErrNoSnapshotClass) {
+			return ErrUnaccepted
+		}
+
+		return err
+	}
+
+	attempt.Accept()
+
+	snapshotName := attempt.HelmReleaseNamePrefix
+
+	logger.Info("📸 Creating volume snapshot of the source PVC", "snapshot", snapshotName,
+		"snapshot_class", snapshotClass)
+
+	if err = k8s.CreateVolumeSnapshot(ctx, dynamicClient, namespace, snapshotName, sourceInfo.Claim.Name,
+		snapshotClass, map[string]string{
+			"app.kubernetes.io/name":     "pv-migrate",
+			"app.kubernetes.io/instance": snapshotName,
+		}); err != nil {
+		return err
+	}
+
+	if err = k8s.WaitForVolumeSnapshotReady(ctx, dynamicClient, namespace,
+		snapshotName, snapshotReadyTimeout); err != nil {
+		r.deleteSnapshot(ctx, attempt, snapshotName, logger)
+
+		return err
+	}
+
+	logger.Info("♻️ Recreating the destination PVC from the volume snapshot", "snapshot", snapshotName)
+
+	if err = recreatePVCFromSnapshot(ctx, destInfo, snapshotName); err != nil {
+		r.deleteSnapshot(ctx, attempt, snapshotName, logger)
+
+		return err
+	}
+
+	logger.Info("💡 The volume snapshot is kept as the data source of the destination PVC, "+
+		"you can delete it once the destination PVC is bound", "snapshot", namespace+"/"+snapshotName)
+
+	return nil
+}
+
+func (r *Snapshot) deleteSnapshot(ctx context.Context, attempt *migration.Attempt,
+	name string, logger *slog.Logger,
+) {
+	if attempt.Migration.Request.SkipCleanup {
+		logger.Info("🧹 Cleanup skipped")
+
+		return
+	}
+
+	sourceInfo := attempt.Migration.SourceInfo
+
+	if err := k8s.DeleteVolumeSnapshot(ctx, sourceInfo.ClusterClient.DynamicClient,
+		sourceInfo.Claim.Namespace, name); err != nil {
+		logger.Warn("🔶 Cleanup failed, you might want to clean up manually", "error", err)
+	}
+}
+
+// ResolveSnapshotClass returns the VolumeSnapshotClass to take the snapshots of the PVC with.
+//
+// Unless a class is given explicitly, the one matching the CSI driver of the storage class of the PVC is looked up.
+// If there is none, k8s.ErrNoSnapshotClass is returned.
+func ResolveSnapshotClass(ctx context.Context, snapshotClass string, info *pvc.Info) (string, error) {
+	if snapshotClass != "" {
+		return snapshotClass, nil
+	}
+
+	if info.Claim.Spec.StorageClassName == nil || *info.Claim.Spec.StorageClassName == "" {
+		return "", k8s.ErrNoSnapshotClass
+	}
+
+	storageClassName := *info.Claim.Spec.StorageClassName
+
+	storageClass, err := info.ClusterClient.KubeClient.StorageV1().StorageClasses().
+		Get(ctx, storageClassName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get storage class %s: %w", storageClassName, err)
+	}
+
+	return k8s.FindVolumeSnapshotClass(ctx, info.ClusterClient.DynamicClient, storageClass.Provisioner)
+}
+
+// recreatePVCFromSnapshot deletes the destination PVC and creates it again with the snapshot as its data source.
+// If the new PVC cannot be created, the original one is created again, not to leave the destination missing.
+func recreatePVCFromSnapshot(ctx context.Context, info *pvc.Info, snapshotName string) error {
+	claim := info.Claim
+	pvcClient := info.ClusterClient.KubeClient.CoreV1().PersistentVolumeClaims(claim.Namespace)
+
+	if err := pvcClient.Delete(ctx, claim.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete destination PVC %s/%s: %w", claim.Namespace, claim.Name, err)
+	}
+
+	if err := wait.PollUntilContextTimeout(ctx, pvcDeletionPollInterval, pvcDeletionTimeout, true,
+		func(ctx context.Context) (bool, error) {
+			_, err := pvcClient.Get(ctx, claim.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+
+			return false, err
+		}); err != nil {
+		return fmt.Errorf("failed to wait for destination PVC %s/%s to be deleted: %w",
+			claim.Namespace, claim.Name, err)
+	}
+
+	apiGroup := k8s.SnapshotAPIGroup
+	spec := claim.Spec.DeepCopy()
+	spec.VolumeName = ""
+	spec.DataSourceRef = nil
+	spec.DataSource = &corev1.TypedLocalObjectReference{
+		APIGroup: &apiGroup,
+		Kind:     k8s.SnapshotKind,
+		Name:     snapshotName,
+	}
+
+	newClaim := recreatedPVC(claim, spec)
+
+	if _, err := pvcClient.Create(ctx, newClaim, metav1.CreateOptions{}); err != nil {
+		createErr := fmt.Errorf("failed to create destination PVC %s/%s: %w", claim.Namespace, claim.Name, err)
+
+		// the original PVC is restored even if the migration is being interrupted
+		if _, restoreErr := pvcClient.Create(context.WithoutCancel(ctx), recreatedPVC(claim, &claim.Spec),
+			metav1.CreateOptions{}); restoreErr != nil {
+			return errors.Join(createErr, fmt.Errorf("failed to restore the original destination PVC %s/%s, "+
+				"it needs to be created again manually: %w", claim.Namespace, claim.Name, restoreErr))
+		}
+
+		return createErr
+	}
+
+	return nil
+}
+
+// recreatedPVC returns a PVC with the metadata of the given one, without the annotations set by the controllers,
+// and with the given spec.
+func recreatedPVC(claim *corev1.PersistentVolumeClaim, spec *corev1.PersistentVolumeClaimSpec,
+) *corev1.PersistentVolumeClaim {
+	annotations := make(map[string]string, len(claim.Annotations))
+	for key, value := range claim.Annotations {
+		annotations[key] = value
+	}
+
+	for _, key := range pvcAnnotationsToDrop {
+		delete(annotations, key)
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        claim.Name,
+			Namespace:   claim.Namespace,
+			Labels:      claim.Labels,
+			Annotations: annotations,
+		},
+		Spec: *spec.DeepCopy(),
+	}
+}
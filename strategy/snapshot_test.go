@@ -0,0 +1,172 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+func TestSnapshotCanDo(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	storageClass := "csi-storage"
+
+	tests := []struct {
+		name      string
+		destNS    string
+		destPhase corev1.PersistentVolumeClaimPhase
+		expected  bool
+	}{
+		{name: "unbound dest in same namespace", destNS: "namespace1", destPhase: corev1.ClaimPending, expected: true},
+		{name: "bound dest", destNS: "namespace1", destPhase: corev1.ClaimBound, expected: false},
+		{name: "different namespace", destNS: "namespace2", destPhase: corev1.ClaimPending, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pvcA := buildTestPVC("namespace1", "pvc1", corev1.ReadWriteOnce)
+			pvcA.Spec.StorageClassName = &storageClass
+			pvcA.Status.Phase = corev1.ClaimBound
+
+			pvcB := buildTestPVC(tt.destNS, "pvc2", corev1.ReadWriteOnce)
+			pvcB.Spec.StorageClassName = &storageClass
+			pvcB.Status.Phase = tt.destPhase
+
+			c := buildTestClient(pvcA, pvcB)
+
+			src, err := pvc.New(ctx, c, "namespace1", "pvc1")
+			require.NoError(t, err)
+
+			dst, err := pvc.New(ctx, c, tt.destNS, "pvc2")
+			require.NoError(t, err)
+
+			mig := migration.Migration{
+				Request:    buildWholeVolumeRequest(),
+				SourceInfo: src,
+				DestInfo:   dst,
+			}
+
+			s := Snapshot{}
+			assert.Equal(t, tt.expected, s.canDo(&mig))
+		})
+	}
+}
+
+func TestSnapshotCanDoWholeVolume(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	storageClass := "csi-storage"
+
+	pvcA := buildTestPVC("namespace1", "pvc1", corev1.ReadWriteOnce)
+	pvcA.Spec.StorageClassName = &storageClass
+	pvcA.Status.Phase = corev1.ClaimBound
+
+	pvcB := buildTestPVC("namespace1", "pvc2", corev1.ReadWriteOnce)
+	pvcB.Spec.StorageClassName = &storageClass
+	pvcB.Status.Phase = corev1.ClaimPending
+
+	c := buildTestClient(pvcA, pvcB)
+
+	src, err := pvc.New(ctx, c, "namespace1", "pvc1")
+	require.NoError(t, err)
+
+	dst, err := pvc.New(ctx, c, "namespace1", "pvc2")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		modify func(req *migration.Request)
+	}{
+		{name: "source path", modify: func(req *migration.Request) { req.Source.Path = "/data" }},
+		{name: "dest path", modify: func(req *migration.Request) { req.Dest.Path = "/data" }},
+		{name: "lost+found excluded", modify: func(req *migration.Request) { req.IncludeLostFound = false }},
+		{name: "delete extraneous files", modify: func(req *migration.Request) { req.DeleteExtraneousFiles = true }},
+		{name: "files from", modify: func(req *migration.Request) { req.FilesFrom = "a\n" }},
+		{name: "filter file", modify: func(req *migration.Request) { req.FilterFile = "- *.tmp\n" }},
+		{name: "since", modify: func(req *migration.Request) { req.Since = time.Now() }},
+		{name: "dirs only", modify: func(req *migration.Request) { req.DirsOnly = true }},
+		{name: "chmod", modify: func(req *migration.Request) { req.Chmod = "g+w" }},
+		{name: "iconv", modify: func(req *migration.Request) { req.Iconv = "utf8,latin1" }},
+		{name: "update", modify: func(req *migration.Request) { req.Update = true }},
+		{name: "ignore existing", modify: func(req *migration.Request) { req.IgnoreExisting = true }},
+		{name: "compare dest", modify: func(req *migration.Request) { req.CompareDest = "/previous" }},
+		{name: "backup", modify: func(req *migration.Request) { req.Backup = true }},
+		{name: "backup dir", modify: func(req *migration.Request) { req.BackupDir = "/backup" }},
+	}
+
+	s := Snapshot{}
+
+	assert.True(t, s.canDo(&migration.Migration{Request: buildWholeVolumeRequest(), SourceInfo: src, DestInfo: dst}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := buildWholeVolumeRequest()
+			tt.modify(request)
+
+			assert.False(t, s.canDo(&migration.Migration{Request: request, SourceInfo: src, DestInfo: dst}))
+		})
+	}
+}
+
+func buildWholeVolumeRequest() *migration.Request {
+	return &migration.Request{
+		Source:           &migration.PVCInfo{Namespace: "namespace1", Name: "pvc1", Path: "/"},
+		Dest:             &migration.PVCInfo{Namespace: "namespace1", Name: "pvc2", Path: "/"},
+		IncludeLostFound: true,
+	}
+}
+
+func TestRecreatePVCFromSnapshotRestoresOnFailure(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	dest := buildTestPVC("namespace1", "pvc2", corev1.ReadWriteOnce)
+	dest.Labels = map[string]string{"app": "test"}
+	c := buildTestClient(dest)
+
+	cli, _ := c.KubeClient.(*fake.Clientset)
+	cli.PrependReactor("create", "persistentvolumeclaims",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			created, _ := action.(k8stesting.CreateAction).GetObject().(*corev1.PersistentVolumeClaim)
+			if created.Spec.DataSource != nil {
+				return true, nil, errors.New("test error")
+			}
+
+			return false, nil, nil
+		})
+
+	info, err := pvc.New(ctx, c, "namespace1", "pvc2")
+	require.NoError(t, err)
+
+	require.Error(t, recreatePVCFromSnapshot(ctx, info, "snapshot"))
+
+	restored, err := cli.CoreV1().PersistentVolumeClaims("namespace1").Get(ctx, "pvc2", metav1.GetOptions{})
+	require.NoError(t, err, "the original PVC must be created again")
+	assert.Nil(t, restored.Spec.DataSource)
+	assert.Equal(t, dest.Spec.AccessModes, restored.Spec.AccessModes)
+	assert.Equal(t, map[string]string{"app": "test"}, restored.Labels)
+}
@@ -5,22 +5,34 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
-	"os/signal"
-	"syscall"
+	"path"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/storage/driver"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
 
+	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
 	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/rsync"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+	"github.com/utkuozdemir/pv-migrate/ssh"
+	"github.com/utkuozdemir/pv-migrate/tracing"
+	"github.com/utkuozdemir/pv-migrate/util"
 )
 
 const (
@@ -29,21 +41,70 @@ const (
 	LbSvcStrategy = "lbsvc"
 	LocalStrategy = "local"
 
+	SnapshotStrategy = "snapshot"
+	RsyncdStrategy   = "rsyncd"
+	ObjStoreStrategy = "objstore"
+
 	helmValuesYAMLIndent = 2
 
 	srcMountPath  = "/source"
 	destMountPath = "/dest"
+
+	// filesFromMountPath is where the list of the paths to transfer is mounted into the rsync pods.
+	filesFromMountPath = "/etc/pv-migrate/files-from"
+	// filterFileMountPath is where the rsync filter file is mounted into the rsync pods.
+	filterFileMountPath = "/etc/pv-migrate/filter"
+	// hostKeyMountPath is where the host key of the SSH server is mounted into the sshd pod.
+	hostKeyMountPath = "/etc/pv-migrate/ssh-host-key"
+	// knownHostsMountPath is where the known_hosts file pinning the host key of the SSH server
+	// is mounted into the rsync pods.
+	knownHostsMountPath = "/etc/pv-migrate/known-hosts"
+	// partialDirName is the directory on the destination to keep the partially transferred files in,
+	// for a new rsync pod to resume them when the previous one is disrupted.
+	partialDirName = ".pv-migrate-partial"
+
+	// selinuxRelabelType is the SELinux type of the migration pods preserving the SELinux contexts of the files,
+	// i.e., that of the super privileged containers, which are allowed to relabel the files.
+	selinuxRelabelType = "spc_t"
+
+	srcDevicePath      = "/dev/source"
+	destDevicePath     = "/dev/dest"
+	blockCopyBlockSize = "4M"
+
+	// ownerCapability is the capability rsync running as root needs to chown the files, needed to preserve
+	// either their owners or their groups.
+	ownerCapability = "CHOWN"
+	// groupCapability is the capability rsync running as root needs to keep the setgid bits of the files
+	// whose groups it does not belong to, only needed to preserve their groups.
+	groupCapability = "FSETID"
 )
 
 var (
-	DefaultStrategies = []string{Mnt2Strategy, SvcStrategy, LbSvcStrategy}
-	AllStrategies     = []string{Mnt2Strategy, SvcStrategy, LbSvcStrategy, LocalStrategy}
+	DefaultStrategies = []string{Mnt2Strategy, SvcStrategy, ObjStoreStrategy, LbSvcStrategy}
+	AllStrategies     = []string{
+		Mnt2Strategy, SvcStrategy, ObjStoreStrategy, LbSvcStrategy, LocalStrategy, SnapshotStrategy, RsyncdStrategy,
+	}
+
+	// ParallelStrategies are the strategies which can split the transfer across multiple rsync pods,
+	// the others ignore --parallel.
+	ParallelStrategies = []string{Mnt2Strategy, SvcStrategy, LbSvcStrategy}
+
+	// KeepableResourceKinds are the kinds of the resources created by the strategies
+	// which can be kept on cleanup, while the rest of the resources are deleted.
+	KeepableResourceKinds = []string{"configmap", "networkpolicy", "secret", "service", "serviceaccount"}
+
+	// fileCapabilities are the capabilities rsync running as root needs regardless of the ownership,
+	// i.e., to write and modify the files of the other users, and to create the device files, as -a implies -D.
+	fileCapabilities = []string{"DAC_OVERRIDE", "FOWNER", "MKNOD"}
 
 	nameToStrategy = map[string]Strategy{
-		Mnt2Strategy:  &Mnt2{},
-		SvcStrategy:   &Svc{},
-		LbSvcStrategy: &LbSvc{},
-		LocalStrategy: &Local{},
+		Mnt2Strategy:     &Mnt2{},
+		SvcStrategy:      &Svc{},
+		LbSvcStrategy:    &LbSvc{},
+		LocalStrategy:    &Local{},
+		SnapshotStrategy: &Snapshot{},
+		RsyncdStrategy:   &Rsyncd{},
+		ObjStoreStrategy: &ObjStore{},
 	}
 
 	helmProviders = getter.All(cli.New())
@@ -73,38 +134,335 @@ func GetStrategiesMapForNames(names []string) (map[string]Strategy, error) {
 	return sts, nil
 }
 
-func registerCleanupHook(attempt *migration.Attempt, releaseNames []string, logger *slog.Logger) chan<- bool {
-	doneCh := make(chan bool)
-	signalCh := make(chan os.Signal, 1)
+// dirContents returns the path of the directory with a trailing slash, for rsync to copy the contents of it
+// rather than the directory itself. This way, the source path is mapped onto the destination path however
+// they are given, e.g., with "/old/prefix" and "/new/prefix", "/old/prefix/a" is copied to "/new/prefix/a",
+// not to "/new/prefix/prefix/a".
+//
+// As a result, the source path must be a directory, rsync fails on a path to a single file.
+// Whether it is a directory cannot be known beforehand, as the path might be on a remote host.
+func dirContents(dir string) string {
+	if dir == "" || strings.HasSuffix(dir, "/") {
+		return dir
+	}
+
+	return dir + "/"
+}
+
+// rsyncSelectionOptions returns the names of the options of the request which select or transform the files
+// transferred by rsync, which the strategies not transferring the files with rsync cannot honor.
+func rsyncSelectionOptions(req *migration.Request) []string {
+	var options []string
+
+	for _, option := range []struct {
+		name string
+		set  bool
+	}{
+		{"files-from", req.FilesFrom != ""},
+		{"filter-file", req.FilterFile != ""},
+		{"since", !req.Since.IsZero()},
+		{"dirs-only", req.DirsOnly},
+		{"chmod", req.Chmod != ""},
+		{"iconv", req.Iconv != ""},
+		{"update", req.Update},
+		{"ignore-existing", req.IgnoreExisting},
+		{"compare-dest", req.CompareDest != ""},
+		{"backup", req.Backup || req.BackupDir != ""},
+	} {
+		if option.set {
+			options = append(options, option.name)
+		}
+	}
+
+	return options
+}
+
+// newRsyncCmd builds an rsync command with the options which are common across all strategies.
+//
+// The strategies are expected to set the transport related fields (SSH, port etc.) themselves.
+func newRsyncCmd(req *migration.Request) rsync.Cmd {
+	cmd := rsync.Cmd{
+		NoChown:           req.NoChown,
+		NoOwner:           req.NoOwner,
+		NoGroup:           req.NoGroup,
+		Delete:            req.DeleteExtraneousFiles,
+		SrcSSHUser:        req.RsyncUser,
+		DestSSHUser:       req.RsyncUser,
+		SrcPath:           dirContents(path.Join(srcMountPath, req.Source.Path)),
+		DestPath:          dirContents(path.Join(destMountPath, req.Dest.Path)),
+		Compress:          req.Compress,
+		SSHCompression:    req.SSHCompression,
+		AutoCompress:      req.AutoCompress,
+		Itemize:           req.Itemize,
+		Verbosity:         req.RsyncVerbose,
+		Parallel:          req.Parallel,
+		SSHConnectRetries: req.SSHConnectRetries,
+		BlockSize:         req.BlockSize,
+		HardLinks:         req.HardLinks,
+		OmitDirTimes:      req.OmitDirTimes,
+		DirsOnly:          req.DirsOnly,
+		NumericIDs:        req.NumericIDs,
+		Chmod:             req.Chmod,
+		Iconv:             req.Iconv,
+		IOTimeout:         req.IOTimeout,
+		ConnectTimeout:    req.ConnectTimeout,
+		Update:            req.Update,
+		IgnoreExisting:    req.IgnoreExisting,
+		DelayUpdates:      req.DelayUpdates,
+		WholeFile:         req.WholeFile,
+		MaxDelete:         req.MaxDelete,
+		Protocol:          req.Protocol,
+		SockOpts:          req.SockOpts,
+		BwLimitSchedule:   req.BwLimitSchedule,
+		Since:             req.Since,
+		SELinuxContexts:   req.PreserveSELinux,
+		CheckFeatures:     true,
+		// lost+found only exists at the root of the volumes
+		ExcludeLostFound: !req.IncludeLostFound && strings.Trim(req.Source.Path, "/") == "",
+		// rsync only creates the last component of the destination path
+		DestMkdir: !req.NoDestMkdir && strings.Trim(req.Dest.Path, "/") != "",
+	}
 
-	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
+	if req.FilesFrom != "" {
+		cmd.FilesFrom = filesFromMountPath
+	}
 
-	go func() {
-		select {
-		case <-signalCh:
-			logger.Warn("🔶 Received termination signal")
+	if req.FilterFile != "" {
+		cmd.FilterFile = filterFileMountPath
+	}
+
+	if req.EvictionRetries > 0 {
+		cmd.PartialDir = partialDirName
+	}
+
+	if req.CompareDest != "" {
+		cmd.CompareDest = path.Join(destMountPath, req.CompareDest)
+	}
+
+	cmd.Backup = req.Backup
+	if req.BackupDir != "" {
+		cmd.BackupDir = path.Join(destMountPath, req.BackupDir)
+	}
+
+	return cmd
+}
 
-			cleanup(attempt, releaseNames, logger)
+// createHostKey generates the host key of the SSH server if the strict host key checking is requested,
+// returning its private key to provision the sshd pod with and the known_hosts file pinning its public key
+// for the rsync pods. Otherwise, it returns empty strings, and the host keys of the sshd image are not verified.
+func createHostKey(req *migration.Request, logger *slog.Logger) (string, string, error) {
+	if !req.StrictHostKeyChecking {
+		warnUnverifiedHostKey(logger)
 
-			os.Exit(1)
-		case <-doneCh:
-			return
+		return "", "", nil
+	}
+
+	logger.Info("🔑 Generating SSH host key pair", "algorithm", req.KeyAlgorithm)
+
+	publicKey, privateKey, err := ssh.CreateSSHKeyPair(req.KeyAlgorithm)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create ssh host key pair: %w", err)
+	}
+
+	return privateKey, ssh.KnownHostsEntry(rsync.HostKeyAlias, publicKey), nil
+}
+
+func warnUnverifiedHostKey(logger *slog.Logger) {
+	logger.Warn("🔶 The host key of the SSH server is not verified, " +
+		"the connection might be subject to man-in-the-middle attacks")
+}
+
+// applyDestEmptyCheck configures the rsync job values to check that the destination path is empty before
+// the transfer, if requested. The check is done once per pod, before the retries of the command.
+func applyDestEmptyCheck(rsyncVals map[string]any, req *migration.Request) {
+	if req.FailIfDestNotEmpty {
+		rsyncVals["failIfNotEmpty"] = path.Join(destMountPath, req.Dest.Path)
+	}
+}
+
+// applyRsyncMounts configures the rsync job values to mount the list of the paths to transfer,
+// the filter file and the extra volumes, if requested.
+func applyRsyncMounts(rsyncVals map[string]any, req *migration.Request) {
+	if req.FilesFrom != "" {
+		rsyncVals["filesFrom"] = req.FilesFrom
+		rsyncVals["filesFromMountPath"] = filesFromMountPath
+	}
+
+	if req.FilterFile != "" {
+		rsyncVals["filterFile"] = req.FilterFile
+		rsyncVals["filterFileMountPath"] = filterFileMountPath
+	}
+
+	if len(req.ExtraVolumes) == 0 {
+		return
+	}
+
+	extraVolumes := make([]map[string]any, 0, len(req.ExtraVolumes))
+
+	for _, volume := range req.ExtraVolumes {
+		key := "configMap"
+		if volume.Kind == migration.ExtraVolumeKindSecret {
+			key = "secret"
 		}
-	}()
 
-	return doneCh
+		extraVolumes = append(extraVolumes, map[string]any{
+			key:         volume.Name,
+			"mountPath": volume.MountPath,
+		})
+	}
+
+	rsyncVals["extraVolumes"] = extraVolumes
 }
 
-func cleanupAndReleaseHook(ctx context.Context, a *migration.Attempt,
-	releaseNames []string, doneCh chan<- bool, logger *slog.Logger,
-) {
-	cleanup(a, releaseNames, logger)
+// applyParallelism configures the rsync job values to run the transfer in multiple pods, if requested.
+// It returns the number of pods the rsync job will run with.
+//
+// If the number of the pods of the migration running at once is limited, the rsync pods are run in batches,
+// counting the SSH server pod as well unless the source is mounted into the rsync pods.
+//
+// Unless all the PVCs mounted into the rsync pods can be mounted on multiple nodes at once,
+// the pods are required to be scheduled on the same node. Otherwise, they are preferred to be spread
+// across the nodes, to maximize the aggregate throughput.
+func applyParallelism(rsyncVals map[string]any, mig *migration.Migration,
+	releaseName string, mountsSource bool,
+) int {
+	parallelism := mig.Request.Parallel
+	if parallelism <= 1 {
+		return 1
+	}
+
+	rsyncVals["parallelism"] = parallelism
+
+	if maxPods := mig.Request.MaxConcurrentPods; maxPods > 0 {
+		serverPods := 1
+		if mountsSource {
+			serverPods = 0
+		}
+
+		rsyncVals["maxConcurrentPods"] = max(maxPods-serverPods, 1)
+	}
+
+	sourceInfo := mig.SourceInfo
+	multiNodeDest := mig.DestInfo.SupportsRWX
+	multiNodeSource := !mountsSource || sourceInfo.SupportsRWX || sourceInfo.SupportsROX
+
+	if !multiNodeDest || !multiNodeSource {
+		rsyncVals["affinity"] = withPodCoLocation(rsyncVals["affinity"], releaseName)
+	} else {
+		rsyncVals["affinity"] = withPodSpreading(rsyncVals["affinity"], releaseName)
+
+		if key := mig.Request.TopologySpread; key != "" {
+			rsyncVals["topologySpreadConstraints"] = topologySpreadConstraints(key, releaseName)
+		}
+	}
+
+	return parallelism
+}
+
+// topologySpreadConstraints returns the helm values of the constraints preferring the rsync pods
+// of the given release to be spread evenly across the values of the given node label.
+func topologySpreadConstraints(topologyKey, releaseName string) []map[string]any {
+	return []map[string]any{
+		{
+			"maxSkew":           1,
+			"topologyKey":       topologyKey,
+			"whenUnsatisfiable": string(corev1.ScheduleAnyway),
+			"labelSelector": map[string]any{
+				"matchLabels": map[string]any{
+					"app.kubernetes.io/component": "rsync",
+					"app.kubernetes.io/instance":  releaseName,
+				},
+			},
+		},
+	}
+}
+
+// withPodCoLocation returns a copy of the given affinity helm values,
+// requiring the rsync pods of the given release to be scheduled on the same node.
+func withPodCoLocation(affinity any, releaseName string) map[string]any {
+	result := map[string]any{}
+
+	if affinityMap, ok := affinity.(map[string]any); ok {
+		maps.Copy(result, affinityMap)
+	}
+
+	result["podAffinity"] = map[string]any{
+		"requiredDuringSchedulingIgnoredDuringExecution": []map[string]any{
+			{
+				"labelSelector": map[string]any{
+					"matchLabels": map[string]any{
+						"app.kubernetes.io/component": "rsync",
+						"app.kubernetes.io/instance":  releaseName,
+					},
+				},
+				"topologyKey": corev1.LabelHostname,
+			},
+		},
+	}
+
+	return result
+}
 
-	select {
-	case <-ctx.Done():
-		logger.Warn("🔶 Context cancelled")
-	case doneCh <- true:
+// withPodSpreading returns a copy of the given affinity helm values,
+// preferring the rsync pods of the given release to be scheduled on different nodes.
+func withPodSpreading(affinity any, releaseName string) map[string]any {
+	result := map[string]any{}
+
+	if affinityMap, ok := affinity.(map[string]any); ok {
+		maps.Copy(result, affinityMap)
+	}
+
+	result["podAntiAffinity"] = map[string]any{
+		"preferredDuringSchedulingIgnoredDuringExecution": []map[string]any{
+			{
+				"weight": 100, //nolint:mnd
+				"podAffinityTerm": map[string]any{
+					"labelSelector": map[string]any{
+						"matchLabels": map[string]any{
+							"app.kubernetes.io/component": "rsync",
+							"app.kubernetes.io/instance":  releaseName,
+						},
+					},
+					"topologyKey": corev1.LabelHostname,
+				},
+			},
+		},
 	}
+
+	return result
+}
+
+// waitForRsyncJob waits for the rsync job to complete, taking the number of pods it runs with into account.
+func waitForRsyncJob(ctx context.Context, mig *migration.Migration, cli kubernetes.Interface,
+	namespace, jobName string, parallelism int, logger *slog.Logger,
+) error {
+	ctx, span := tracing.Start(ctx, "transfer", attribute.Int("pv_migrate.parallelism", parallelism))
+
+	if mig.Request.RsyncVerbose > 1 {
+		ctx = context.WithValue(ctx, progress.VerboseContextKey{}, true)
+	}
+
+	var err error
+
+	if parallelism > 1 {
+		err = k8s.WaitForParallelJobCompletion(ctx, cli, namespace, jobName, parallelism, logger)
+	} else {
+		showProgressBar := !mig.Request.NoProgressBar
+		err = k8s.WaitForJobCompletion(ctx, cli, namespace, jobName, showProgressBar,
+			mig.Request.EvictionRetries, logger)
+	}
+
+	tracing.End(span, err)
+
+	return err //nolint:wrapcheck
+}
+
+// cleanupReleases uninstalls the releases of the attempt. It is deferred by the strategies, so that it also runs
+// when the context is cancelled by a termination signal.
+func cleanupReleases(ctx context.Context, a *migration.Attempt, releaseNames []string, logger *slog.Logger) {
+	_, span := tracing.Start(context.WithoutCancel(ctx), "cleanup")
+	cleanup(a, releaseNames, logger)
+	span.End()
 }
 
 func cleanup(attempt *migration.Attempt, releaseNames []string, logger *slog.Logger) {
@@ -123,7 +481,7 @@ func cleanup(attempt *migration.Attempt, releaseNames []string, logger *slog.Log
 
 	for _, info := range []*pvc.Info{mig.SourceInfo, mig.DestInfo} {
 		for _, name := range releaseNames {
-			err := cleanupForPVC(name, req.HelmTimeout, info, logger)
+			err := cleanupForPVC(name, req.HelmTimeout, info, releaseNamespace(req, info), logger)
 			if err != nil {
 				errs = multierror.Append(errs, err)
 			}
@@ -136,13 +494,20 @@ func cleanup(attempt *migration.Attempt, releaseNames []string, logger *slog.Log
 		return
 	}
 
+	if len(req.KeepResources) > 0 {
+		logger.Info("✨ Cleanup done, kept the requested resources, you might want to clean them up manually",
+			"kinds", strings.Join(req.KeepResources, ","), "releases", strings.Join(releaseNames, ","))
+
+		return
+	}
+
 	logger.Info("✨ Cleanup done")
 }
 
 func cleanupForPVC(helmReleaseName string, helmUninstallTimeout time.Duration,
-	pvcInfo *pvc.Info, logger *slog.Logger,
+	pvcInfo *pvc.Info, namespace string, logger *slog.Logger,
 ) error {
-	ac, err := initHelmActionConfig(pvcInfo, logger)
+	ac, err := initHelmActionConfig(pvcInfo, namespace, logger)
 	if err != nil {
 		return err
 	}
@@ -159,11 +524,22 @@ func cleanupForPVC(helmReleaseName string, helmUninstallTimeout time.Duration,
 	return nil
 }
 
-func initHelmActionConfig(pvcInfo *pvc.Info, logger *slog.Logger) (*action.Configuration, error) {
+// releaseNamespace returns the namespace the Helm releases of the PVC are stored in, i.e., the coordination
+// namespace if given, or the namespace of the PVC. The resources of the chart set their namespaces explicitly,
+// so they are created in the namespace of the PVC either way.
+func releaseNamespace(request *migration.Request, pvcInfo *pvc.Info) string {
+	if request.CoordinationNamespace != "" {
+		return request.CoordinationNamespace
+	}
+
+	return pvcInfo.Claim.Namespace
+}
+
+func initHelmActionConfig(pvcInfo *pvc.Info, namespace string, logger *slog.Logger) (*action.Configuration, error) {
 	actionConfig := new(action.Configuration)
 
 	err := actionConfig.Init(pvcInfo.ClusterClient.RESTClientGetter,
-		pvcInfo.Claim.Namespace, os.Getenv("HELM_DRIVER"), func(format string, v ...any) {
+		namespace, os.Getenv("HELM_DRIVER"), func(format string, v ...any) {
 			logger.Debug(fmt.Sprintf(format, v...))
 		})
 	if err != nil {
@@ -190,9 +566,26 @@ func getMergedHelmValues(helmValuesFile string, request *migration.Request) (map
 	return mergedValues, nil
 }
 
-func installHelmChart(attempt *migration.Attempt, pvcInfo *pvc.Info, name string,
+func installHelmChart(ctx context.Context, attempt *migration.Attempt, pvcInfo *pvc.Info, name string,
 	values map[string]any, logger *slog.Logger,
-) error {
+) (retErr error) {
+	_, span := tracing.Start(ctx, "install", attribute.String("pv_migrate.release", name),
+		attribute.String("pv_migrate.namespace", pvcInfo.Claim.Namespace))
+	defer func() { tracing.End(span, retErr) }()
+
+	applySecurityProfiles(values, attempt.Migration.Request)
+	applySELinuxOptions(values, attempt.Migration.Request)
+	applyRuntimeClass(values, attempt.Migration.Request)
+	applyDNS(values, attempt.Migration.Request)
+	applyPodRestarts(values, attempt.Migration.Request)
+	applyImages(values, attempt.Migration.Request)
+	applyCapabilities(values, attempt.Migration.Request)
+	applyMeshAnnotations(ctx, values, pvcInfo, attempt.Migration.Request, logger)
+
+	if keep := attempt.Migration.Request.KeepResources; len(keep) > 0 {
+		values["keepResources"] = keep
+	}
+
 	helmValuesFile, err := writeHelmValuesToTempFile(attempt.ID, values)
 	if err != nil {
 		return fmt.Errorf("failed to write helm values to temp file: %w", err)
@@ -202,15 +595,20 @@ func installHelmChart(attempt *migration.Attempt, pvcInfo *pvc.Info, name string
 		os.Remove(helmValuesFile)
 	}()
 
-	helmActionConfig, err := initHelmActionConfig(pvcInfo, logger)
+	mig := attempt.Migration
+	namespace := releaseNamespace(mig.Request, pvcInfo)
+
+	helmActionConfig, err := initHelmActionConfig(pvcInfo, namespace, logger)
 	if err != nil {
 		return fmt.Errorf("failed to init helm action config: %w", err)
 	}
 
-	mig := attempt.Migration
+	if kubeClient, ok := helmActionConfig.KubeClient.(*kube.Client); ok && mig.Request.ServerSideApply {
+		helmActionConfig.KubeClient = k8s.NewServerSideApplyClient(kubeClient)
+	}
 
 	install := action.NewInstall(helmActionConfig)
-	install.Namespace = pvcInfo.Claim.Namespace
+	install.Namespace = namespace
 	install.ReleaseName = name
 	install.Wait = true
 
@@ -225,13 +623,238 @@ func installHelmChart(attempt *migration.Attempt, pvcInfo *pvc.Info, name string
 		return fmt.Errorf("failed to get merged helm values: %w", err)
 	}
 
-	if _, err = install.Run(mig.Chart, vals); err != nil {
+	if mig.Request.PrintCommand {
+		printRsyncCommand(vals, logger)
+	}
+
+	if _, err = install.RunWithContext(ctx, mig.Chart, vals); err != nil {
 		return fmt.Errorf("failed to install helm chart: %w", err)
 	}
 
 	return nil
 }
 
+// printRsyncCommand logs the rsync command the rsync job of the release will run, if it is enabled,
+// including the extra args appended to it by the chart. It contains no secrets, as the SSH keys are mounted as files.
+func printRsyncCommand(vals map[string]any, logger *slog.Logger) {
+	rsyncVals, _ := vals["rsync"].(map[string]any)
+	if enabled, _ := rsyncVals["enabled"].(bool); !enabled {
+		return
+	}
+
+	command, _ := rsyncVals["command"].(string)
+	if extraArgs, _ := rsyncVals["extraArgs"].(string); extraArgs != "" {
+		command += " " + extraArgs
+	}
+
+	logger.Info("📋 Rsync command", "command", command)
+}
+
+// applySecurityProfiles sets the requested seccomp and AppArmor profiles on the pods of the components in the values.
+func applySecurityProfiles(values map[string]any, req *migration.Request) {
+	if req.SeccompProfile == nil && req.AppArmorProfile == nil {
+		return
+	}
+
+	for _, component := range []string{"rsync", "sshd"} {
+		componentVals, ok := values[component].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		podSecurityContext := map[string]any{}
+		if existing, isMap := componentVals["podSecurityContext"].(map[string]any); isMap {
+			maps.Copy(podSecurityContext, existing)
+		}
+
+		if req.SeccompProfile != nil {
+			podSecurityContext["seccompProfile"] = req.SeccompProfile.HelmValues()
+		}
+
+		if req.AppArmorProfile != nil {
+			podSecurityContext["appArmorProfile"] = req.AppArmorProfile.HelmValues()
+		}
+
+		componentVals["podSecurityContext"] = podSecurityContext
+	}
+}
+
+// applySELinuxOptions sets the SELinux type of the pods of the components in the values to selinuxRelabelType
+// when the SELinux contexts are to be preserved, as the default type of the containers is not allowed
+// to relabel the files. SELinux options already set in the values are kept.
+func applySELinuxOptions(values map[string]any, req *migration.Request) {
+	if !req.PreserveSELinux {
+		return
+	}
+
+	for _, component := range []string{"rsync", "sshd"} {
+		componentVals, ok := values[component].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		podSecurityContext := map[string]any{}
+		if existing, isMap := componentVals["podSecurityContext"].(map[string]any); isMap {
+			maps.Copy(podSecurityContext, existing)
+		}
+
+		if _, exists := podSecurityContext["seLinuxOptions"]; !exists {
+			podSecurityContext["seLinuxOptions"] = map[string]any{"type": selinuxRelabelType}
+		}
+
+		componentVals["podSecurityContext"] = podSecurityContext
+	}
+}
+
+// applyRuntimeClass sets the requested RuntimeClass on the pods of the components in the values.
+func applyRuntimeClass(values map[string]any, req *migration.Request) {
+	if req.RuntimeClass == "" {
+		return
+	}
+
+	for _, component := range []string{"rsync", "sshd"} {
+		if componentVals, ok := values[component].(map[string]any); ok {
+			componentVals["runtimeClassName"] = req.RuntimeClass
+		}
+	}
+}
+
+// applyDNS sets the DNS policy and the nameservers of the rsync and the sshd pods in the values, if requested.
+func applyDNS(values map[string]any, req *migration.Request) {
+	for _, component := range []string{"rsync", "sshd"} {
+		componentVals, ok := values[component].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		if req.PodDNSPolicy != "" {
+			componentVals["dnsPolicy"] = req.PodDNSPolicy
+		}
+
+		if len(req.PodDNSNameservers) > 0 {
+			componentVals["dnsConfig"] = map[string]any{"nameservers": req.PodDNSNameservers}
+		}
+	}
+}
+
+// applyPodRestarts lets the rsync job replace its pod up to the requested number of times if it is disrupted,
+// e.g., evicted, to resume the transfer from the partial files kept on the destination.
+// The other failures of the pod still fail the job right away.
+func applyPodRestarts(values map[string]any, req *migration.Request) {
+	rsyncVals, ok := values["rsync"].(map[string]any)
+	if !ok || req.EvictionRetries == 0 {
+		return
+	}
+
+	rsyncVals["backoffLimit"] = req.EvictionRetries
+	rsyncVals["podFailurePolicy"] = map[string]any{
+		"rules": []any{
+			map[string]any{
+				"action":          "Count",
+				"onPodConditions": []any{map[string]any{"type": string(corev1.DisruptionTarget)}},
+			},
+			map[string]any{
+				"action": "FailJob",
+				"onExitCodes": map[string]any{
+					"containerName": "rsync",
+					"operator":      "NotIn",
+					"values":        []any{0},
+				},
+			},
+		},
+	}
+}
+
+// applyCapabilities restricts the Linux capabilities of the rsync pods to the ones they need. All the capabilities
+// are dropped, and the following ones are added back:
+//   - DAC_OVERRIDE, FOWNER and MKNOD, always, to write the files of the other users and to create the device files.
+//   - CHOWN, unless both the owners and the groups are not preserved, i.e., with --no-chown,
+//     or --no-owner together with --no-group.
+//   - FSETID, unless the groups are not preserved, i.e., with --no-chown or --no-group.
+//   - SETFCAP, if the SELinux contexts are preserved.
+//
+// The capabilities of --add-capability are then added and the ones of --drop-capability are removed,
+// where dropping ALL leaves only the ones of --add-capability, e.g., to run the pods without any capabilities
+// in the namespaces enforcing the restricted Pod Security Standard.
+func applyCapabilities(values map[string]any, req *migration.Request) {
+	rsyncVals, ok := values["rsync"].(map[string]any)
+	if !ok {
+		return
+	}
+
+	noOwner := req.NoChown || req.NoOwner
+	noGroup := req.NoChown || req.NoGroup
+
+	var add []string
+	if !noOwner || !noGroup {
+		add = append(add, ownerCapability)
+	}
+
+	if !noGroup {
+		add = append(add, groupCapability)
+	}
+
+	add = append(add, fileCapabilities...)
+
+	if req.PreserveSELinux {
+		add = append(add, "SETFCAP")
+	}
+
+	// only the requested capabilities are added then
+	if slices.Contains(req.DropCapabilities, "ALL") {
+		add = nil
+	}
+
+	for _, capability := range req.AddCapabilities {
+		if !slices.Contains(add, capability) {
+			add = append(add, capability)
+		}
+	}
+
+	add = slices.DeleteFunc(add, func(capability string) bool {
+		return slices.Contains(req.DropCapabilities, capability)
+	})
+
+	capabilities := map[string]any{"drop": []string{"ALL"}}
+	if len(add) > 0 {
+		capabilities["add"] = add
+	}
+
+	securityContext := map[string]any{}
+	if existing, isMap := rsyncVals["securityContext"].(map[string]any); isMap {
+		maps.Copy(securityContext, existing)
+	}
+
+	securityContext["capabilities"] = capabilities
+	rsyncVals["securityContext"] = securityContext
+}
+
+// applyImages overrides the images of the rsync client and the sshd server in the values, if requested.
+// The images are validated beforehand, so the ones which cannot be parsed are skipped.
+func applyImages(values map[string]any, req *migration.Request) {
+	for component, image := range map[string]string{"rsync": req.ClientImage, "sshd": req.ServerImage} {
+		componentVals, ok := values[component].(map[string]any)
+		if !ok || image == "" {
+			continue
+		}
+
+		// the image is set by the strategy itself, e.g., the rclone image of the objstore strategy
+		if _, ok = componentVals["image"]; ok {
+			continue
+		}
+
+		repository, tag, err := util.ParseImage(image)
+		if err != nil {
+			continue
+		}
+
+		componentVals["image"] = map[string]any{
+			"repository": repository,
+			"tag":        tag,
+		}
+	}
+}
+
 func writeHelmValuesToTempFile(id string, vals map[string]any) (string, error) {
 	file, err := os.CreateTemp("", fmt.Sprintf("pv-migrate-vals-%s-*.yaml", id))
 	if err != nil {
@@ -1,9 +1,21 @@
 package strategy
 
 import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
 )
 
 func buildTestPod(namespace string, name string, node string, pvc string) *corev1.Pod {
@@ -43,3 +55,402 @@ func buildTestPVC(namespace string, name string,
 		},
 	}
 }
+
+func TestApplyParallelism(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pvcA := buildTestPVC("namespace1", "pvc1", corev1.ReadWriteOnce)
+	pvcB := buildTestPVC("namespace1", "pvc2", corev1.ReadWriteMany)
+	c := buildTestClient(pvcA, pvcB)
+
+	src, err := pvc.New(ctx, c, "namespace1", "pvc1")
+	require.NoError(t, err)
+
+	dst, err := pvc.New(ctx, c, "namespace1", "pvc2")
+	require.NoError(t, err)
+
+	mig := migration.Migration{
+		Request:    &migration.Request{Parallel: 1},
+		SourceInfo: src,
+		DestInfo:   dst,
+	}
+
+	rsyncVals := map[string]any{}
+	assert.Equal(t, 1, applyParallelism(rsyncVals, &mig, "release", true))
+	assert.Empty(t, rsyncVals)
+
+	mig.Request.Parallel = 3
+
+	rsyncVals = map[string]any{}
+	assert.Equal(t, 3, applyParallelism(rsyncVals, &mig, "release", false))
+	assert.Equal(t, 3, rsyncVals["parallelism"])
+
+	affinity, ok := rsyncVals["affinity"].(map[string]any)
+	require.True(t, ok)
+	assert.Contains(t, affinity, "podAntiAffinity")
+	assert.NotContains(t, affinity, "podAffinity")
+
+	rsyncVals = map[string]any{"affinity": map[string]any{"nodeAffinity": "test"}}
+	assert.Equal(t, 3, applyParallelism(rsyncVals, &mig, "release", true))
+
+	affinity, ok = rsyncVals["affinity"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "test", affinity["nodeAffinity"])
+	assert.Contains(t, affinity, "podAffinity")
+	assert.NotContains(t, affinity, "podAntiAffinity")
+
+	mig.Request.MaxConcurrentPods = 2
+
+	rsyncVals = map[string]any{}
+	assert.Equal(t, 3, applyParallelism(rsyncVals, &mig, "release", false))
+	assert.Equal(t, 1, rsyncVals["maxConcurrentPods"])
+
+	rsyncVals = map[string]any{}
+	assert.Equal(t, 3, applyParallelism(rsyncVals, &mig, "release", true))
+	assert.Equal(t, 2, rsyncVals["maxConcurrentPods"])
+	assert.NotContains(t, rsyncVals, "topologySpreadConstraints")
+
+	mig.Request.TopologySpread = corev1.LabelTopologyZone
+
+	rsyncVals = map[string]any{}
+	applyParallelism(rsyncVals, &mig, "release", false)
+
+	constraints, ok := rsyncVals["topologySpreadConstraints"].([]map[string]any)
+	require.True(t, ok)
+	require.Len(t, constraints, 1)
+	assert.Equal(t, corev1.LabelTopologyZone, constraints[0]["topologyKey"])
+	assert.Equal(t, "ScheduleAnyway", constraints[0]["whenUnsatisfiable"])
+
+	rsyncVals = map[string]any{}
+	applyParallelism(rsyncVals, &mig, "release", true)
+	assert.NotContains(t, rsyncVals, "topologySpreadConstraints", "the pods must be co-located with the source")
+}
+
+func TestPrintRsyncCommand(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	printRsyncCommand(map[string]any{
+		"rsync": map[string]any{"enabled": true, "command": "rsync -av /source/ /dest/", "extraArgs": "--stats"},
+	}, logger)
+	assert.Contains(t, buf.String(), `command="rsync -av /source/ /dest/ --stats"`)
+
+	buf.Reset()
+	printRsyncCommand(map[string]any{"sshd": map[string]any{"enabled": true}}, logger)
+	assert.Empty(t, buf.String())
+}
+
+func TestApplySecurityProfiles(t *testing.T) {
+	t.Parallel()
+
+	vals := map[string]any{
+		"rsync": map[string]any{
+			"podSecurityContext": map[string]any{"runAsUser": 1000},
+		},
+		"sshd": map[string]any{},
+	}
+
+	applySecurityProfiles(vals, &migration.Request{
+		SeccompProfile:  &k8s.SecurityProfile{Type: k8s.SecurityProfileRuntimeDefault},
+		AppArmorProfile: &k8s.SecurityProfile{Type: k8s.SecurityProfileLocalhost, LocalhostProfile: "custom"},
+	})
+
+	podSecurityContext := func(component string) map[string]any {
+		componentVals, _ := vals[component].(map[string]any)
+		result, _ := componentVals["podSecurityContext"].(map[string]any)
+
+		return result
+	}
+
+	for _, component := range []string{"rsync", "sshd"} {
+		assert.Equal(t, map[string]any{"type": "RuntimeDefault"}, podSecurityContext(component)["seccompProfile"])
+		assert.Equal(t, map[string]any{"type": "Localhost", "localhostProfile": "custom"},
+			podSecurityContext(component)["appArmorProfile"])
+	}
+
+	assert.Equal(t, 1000, podSecurityContext("rsync")["runAsUser"])
+}
+
+func TestApplySELinuxOptions(t *testing.T) {
+	t.Parallel()
+
+	vals := map[string]any{
+		"rsync": map[string]any{
+			"podSecurityContext": map[string]any{"runAsUser": 0},
+		},
+		"sshd": map[string]any{
+			"podSecurityContext": map[string]any{"seLinuxOptions": map[string]any{"type": "custom_t"}},
+		},
+	}
+
+	podSecurityContext := func(component string) map[string]any {
+		componentVals, _ := vals[component].(map[string]any)
+		result, _ := componentVals["podSecurityContext"].(map[string]any)
+
+		return result
+	}
+
+	applySELinuxOptions(vals, &migration.Request{})
+	assert.NotContains(t, podSecurityContext("rsync"), "seLinuxOptions")
+
+	applySELinuxOptions(vals, &migration.Request{PreserveSELinux: true})
+
+	assert.Equal(t, map[string]any{
+		"runAsUser":      0,
+		"seLinuxOptions": map[string]any{"type": "spc_t"},
+	}, podSecurityContext("rsync"))
+	assert.Equal(t, map[string]any{
+		"seLinuxOptions": map[string]any{"type": "custom_t"},
+	}, podSecurityContext("sshd"), "the options in the values must be kept")
+}
+
+func TestApplyDNS(t *testing.T) {
+	t.Parallel()
+
+	vals := map[string]any{
+		"rsync": map[string]any{},
+		"sshd":  map[string]any{},
+	}
+
+	applyDNS(vals, &migration.Request{PodDNSPolicy: "None", PodDNSNameservers: []string{"10.0.0.10"}})
+
+	for _, component := range []string{"rsync", "sshd"} {
+		assert.Equal(t, map[string]any{
+			"dnsPolicy": "None",
+			"dnsConfig": map[string]any{"nameservers": []string{"10.0.0.10"}},
+		}, vals[component])
+	}
+}
+
+func TestApplyPodRestarts(t *testing.T) {
+	t.Parallel()
+
+	vals := map[string]any{"rsync": map[string]any{}, "sshd": map[string]any{}}
+
+	applyPodRestarts(vals, &migration.Request{})
+	assert.Equal(t, map[string]any{}, vals["rsync"])
+
+	applyPodRestarts(vals, &migration.Request{EvictionRetries: 3})
+
+	rsyncVals, ok := vals["rsync"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, 3, rsyncVals["backoffLimit"])
+	assert.Contains(t, rsyncVals, "podFailurePolicy")
+	assert.Equal(t, map[string]any{}, vals["sshd"])
+}
+
+func TestNewRsyncCmdPaths(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		sourcePath string
+		destPath   string
+		wantSrc    string
+		wantDest   string
+	}{
+		{"root", "/", "/", "/source/", "/dest/"},
+		{"rename", "/old/prefix", "/new/prefix", "/source/old/prefix/", "/dest/new/prefix/"},
+		{"rename with trailing slashes", "/old/prefix/", "/new/prefix/", "/source/old/prefix/", "/dest/new/prefix/"},
+		{"rename with mixed slashes", "old/prefix", "/new/prefix/", "/source/old/prefix/", "/dest/new/prefix/"},
+		{"into root", "/old/prefix", "/", "/source/old/prefix/", "/dest/"},
+		{"from root", "/", "/new/prefix", "/source/", "/dest/new/prefix/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := newRsyncCmd(&migration.Request{
+				Source: &migration.PVCInfo{Path: tt.sourcePath},
+				Dest:   &migration.PVCInfo{Path: tt.destPath},
+			})
+
+			assert.Equal(t, tt.wantSrc, cmd.SrcPath)
+			assert.Equal(t, tt.wantDest, cmd.DestPath)
+		})
+	}
+}
+
+func TestNewRsyncCmdExcludeLostFound(t *testing.T) {
+	t.Parallel()
+
+	request := migration.Request{
+		Source: &migration.PVCInfo{Path: "/"},
+		Dest:   &migration.PVCInfo{Path: "/"},
+	}
+	assert.True(t, newRsyncCmd(&request).ExcludeLostFound)
+
+	request.Source = &migration.PVCInfo{Path: "/data"}
+	assert.False(t, newRsyncCmd(&request).ExcludeLostFound, "lost+found is only at the root of the volume")
+
+	request.Source = &migration.PVCInfo{Path: "/"}
+	request.IncludeLostFound = true
+	assert.False(t, newRsyncCmd(&request).ExcludeLostFound)
+}
+
+func TestReleaseNamespace(t *testing.T) {
+	t.Parallel()
+
+	info := &pvc.Info{Claim: buildTestPVC("data", "pvc", corev1.ReadWriteOnce)}
+
+	assert.Equal(t, "data", releaseNamespace(&migration.Request{}, info))
+	assert.Equal(t, "pv-migrate", releaseNamespace(&migration.Request{CoordinationNamespace: "pv-migrate"}, info))
+}
+
+func TestApplyDestEmptyCheck(t *testing.T) {
+	t.Parallel()
+
+	rsyncVals := map[string]any{}
+	applyDestEmptyCheck(rsyncVals, &migration.Request{Dest: &migration.PVCInfo{Path: "/"}})
+	assert.Empty(t, rsyncVals)
+
+	applyDestEmptyCheck(rsyncVals, &migration.Request{
+		Dest:               &migration.PVCInfo{Path: "/new/prefix/"},
+		FailIfDestNotEmpty: true,
+	})
+	assert.Equal(t, "/dest/new/prefix", rsyncVals["failIfNotEmpty"])
+}
+
+func TestApplyCapabilities(t *testing.T) {
+	t.Parallel()
+
+	vals := map[string]any{
+		"rsync": map[string]any{"securityContext": map[string]any{"runAsUser": 0}},
+		"sshd":  map[string]any{},
+	}
+
+	applyCapabilities(vals, &migration.Request{})
+
+	rsyncVals, _ := vals["rsync"].(map[string]any)
+	capabilities := func() any {
+		securityContext, _ := rsyncVals["securityContext"].(map[string]any)
+
+		return securityContext["capabilities"]
+	}
+
+	assert.Equal(t, map[string]any{
+		"runAsUser": 0,
+		"capabilities": map[string]any{
+			"drop": []string{"ALL"},
+			"add":  []string{"CHOWN", "FSETID", "DAC_OVERRIDE", "FOWNER", "MKNOD"},
+		},
+	}, rsyncVals["securityContext"])
+	assert.Equal(t, map[string]any{}, vals["sshd"])
+
+	applyCapabilities(vals, &migration.Request{NoChown: true})
+	assert.Equal(t, map[string]any{
+		"drop": []string{"ALL"},
+		"add":  []string{"DAC_OVERRIDE", "FOWNER", "MKNOD"},
+	}, capabilities())
+
+	applyCapabilities(vals, &migration.Request{NoOwner: true})
+	assert.Equal(t, map[string]any{
+		"drop": []string{"ALL"},
+		"add":  []string{"CHOWN", "FSETID", "DAC_OVERRIDE", "FOWNER", "MKNOD"},
+	}, capabilities(), "the groups are still preserved with chown")
+
+	applyCapabilities(vals, &migration.Request{NoGroup: true})
+	assert.Equal(t, map[string]any{
+		"drop": []string{"ALL"},
+		"add":  []string{"CHOWN", "DAC_OVERRIDE", "FOWNER", "MKNOD"},
+	}, capabilities())
+
+	applyCapabilities(vals, &migration.Request{NoOwner: true, NoGroup: true})
+	assert.Equal(t, map[string]any{
+		"drop": []string{"ALL"},
+		"add":  []string{"DAC_OVERRIDE", "FOWNER", "MKNOD"},
+	}, capabilities())
+
+	applyCapabilities(vals, &migration.Request{PreserveSELinux: true})
+	assert.Equal(t, map[string]any{
+		"drop": []string{"ALL"},
+		"add":  []string{"CHOWN", "FSETID", "DAC_OVERRIDE", "FOWNER", "MKNOD", "SETFCAP"},
+	}, capabilities())
+
+	applyCapabilities(vals, &migration.Request{
+		AddCapabilities:  []string{"DAC_READ_SEARCH", "CHOWN"},
+		DropCapabilities: []string{"FSETID"},
+	})
+	assert.Equal(t, map[string]any{
+		"drop": []string{"ALL"},
+		"add":  []string{"CHOWN", "DAC_OVERRIDE", "FOWNER", "MKNOD", "DAC_READ_SEARCH"},
+	}, capabilities())
+
+	applyCapabilities(vals, &migration.Request{NoChown: true, DropCapabilities: []string{"ALL"}})
+	assert.Equal(t, map[string]any{"drop": []string{"ALL"}}, capabilities())
+
+	applyCapabilities(vals, &migration.Request{
+		AddCapabilities:  []string{"DAC_READ_SEARCH"},
+		DropCapabilities: []string{"ALL"},
+	})
+	assert.Equal(t, map[string]any{"drop": []string{"ALL"}, "add": []string{"DAC_READ_SEARCH"}}, capabilities())
+
+	sshdOnly := map[string]any{"sshd": map[string]any{}}
+	applyCapabilities(sshdOnly, &migration.Request{})
+	assert.Equal(t, map[string]any{"sshd": map[string]any{}}, sshdOnly)
+}
+
+func TestApplyImages(t *testing.T) {
+	t.Parallel()
+
+	vals := map[string]any{
+		"rsync": map[string]any{},
+		"sshd":  map[string]any{},
+	}
+
+	applyImages(vals, &migration.Request{ClientImage: "registry.example.com:5000/rsync:1.2.3"})
+
+	assert.Equal(t, map[string]any{
+		"image": map[string]any{"repository": "registry.example.com:5000/rsync", "tag": "1.2.3"},
+	}, vals["rsync"])
+	assert.Equal(t, map[string]any{}, vals["sshd"])
+}
+
+func TestApplyMeshAnnotations(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	logger := slogt.New(t)
+
+	claim := buildTestPVC("namespace1", "pvc1", corev1.ReadWriteOnce)
+	meshedNS := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "namespace1", Labels: map[string]string{"istio-injection": "enabled"},
+	}}
+
+	info, err := pvc.New(ctx, buildTestClient(claim, meshedNS), "namespace1", "pvc1")
+	require.NoError(t, err)
+
+	vals := map[string]any{
+		"rsync": map[string]any{"podAnnotations": map[string]any{"custom": "value"}},
+		"sshd":  map[string]any{},
+	}
+
+	applyMeshAnnotations(ctx, vals, info, &migration.Request{}, logger)
+
+	assert.Equal(t, map[string]any{"custom": "value", "sidecar.istio.io/inject": "false"},
+		vals["rsync"].(map[string]any)["podAnnotations"])
+	assert.Equal(t, map[string]any{"sidecar.istio.io/inject": "false"}, vals["sshd"].(map[string]any)["podAnnotations"])
+
+	vals = map[string]any{"rsync": map[string]any{}}
+
+	applyMeshAnnotations(ctx, vals, info, &migration.Request{AllowSidecars: true, RsyncdPort: 873}, logger)
+
+	assert.Equal(t, map[string]any{"traffic.sidecar.istio.io/excludeOutboundPorts": "22,873"},
+		vals["rsync"].(map[string]any)["podAnnotations"])
+
+	info, err = pvc.New(ctx, buildTestClient(claim), "namespace1", "pvc1")
+	require.NoError(t, err)
+
+	vals = map[string]any{"rsync": map[string]any{}}
+
+	applyMeshAnnotations(ctx, vals, info, &migration.Request{}, logger)
+
+	assert.Equal(t, map[string]any{}, vals["rsync"])
+}
@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
-	"github.com/utkuozdemir/pv-migrate/rsync"
 	"github.com/utkuozdemir/pv-migrate/ssh"
 )
 
@@ -18,7 +16,7 @@ func (r *Svc) canDo(t *migration.Migration) bool {
 	d := t.DestInfo
 	sameCluster := s.ClusterClient.RestConfig.Host == d.ClusterClient.RestConfig.Host
 
-	return sameCluster
+	return sameCluster && !s.BlockMode
 }
 
 func (r *Svc) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
@@ -27,6 +25,8 @@ func (r *Svc) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.
 		return ErrUnaccepted
 	}
 
+	attempt.Accept()
+
 	releaseName := attempt.HelmReleaseNamePrefix
 	releaseNames := []string{releaseName}
 
@@ -35,20 +35,23 @@ func (r *Svc) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.
 		return fmt.Errorf("failed to build helm values: %w", err)
 	}
 
-	doneCh := registerCleanupHook(attempt, releaseNames, logger)
-	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+	rsyncVals := helmVals["rsync"].(map[string]any) //nolint:forcetypeassert
+	applyRsyncMounts(rsyncVals, mig.Request)
+	applyDestEmptyCheck(rsyncVals, mig.Request)
+	parallelism := applyParallelism(rsyncVals, mig, releaseName, false)
+
+	defer cleanupReleases(ctx, attempt, releaseNames, logger)
 
-	err = installHelmChart(attempt, mig.DestInfo, releaseName, helmVals, logger)
+	err = installHelmChart(ctx, attempt, mig.DestInfo, releaseName, helmVals, logger)
 	if err != nil {
 		return fmt.Errorf("failed to install helm chart: %w", err)
 	}
 
-	showProgressBar := !mig.Request.NoProgressBar
 	kubeClient := mig.SourceInfo.ClusterClient.KubeClient
 	jobName := releaseName + "-rsync"
 
-	if err = k8s.WaitForJobCompletion(ctx, kubeClient,
-		mig.DestInfo.Claim.Namespace, jobName, showProgressBar, logger); err != nil {
+	if err = waitForRsyncJob(ctx, mig, kubeClient,
+		mig.DestInfo.Claim.Namespace, jobName, parallelism, logger); err != nil {
 		return fmt.Errorf("failed to wait for job completion: %w", err)
 	}
 
@@ -72,21 +75,22 @@ func buildHelmVals(mig *migration.Migration, helmReleaseName string, logger *slo
 
 	privateKeyMountPath := "/tmp/id_" + keyAlgorithm
 
-	sshTargetHost := helmReleaseName + "-sshd." + sourceNs
+	hostKey, knownHosts, err := createHostKey(mig.Request, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	sshTargetHost := sshServiceName(mig.Request, helmReleaseName) + "." + sourceNs
 	if mig.Request.DestHostOverride != "" {
 		sshTargetHost = mig.Request.DestHostOverride
 	}
 
-	srcPath := srcMountPath + "/" + mig.Request.Source.Path
-	destPath := destMountPath + "/" + mig.Request.Dest.Path
-	rsyncCmd := rsync.Cmd{
-		NoChown:    mig.Request.NoChown,
-		Delete:     mig.Request.DeleteExtraneousFiles,
-		SrcPath:    srcPath,
-		DestPath:   destPath,
-		SrcUseSSH:  true,
-		SrcSSHHost: sshTargetHost,
-		Compress:   mig.Request.Compress,
+	rsyncCmd := newRsyncCmd(mig.Request)
+	rsyncCmd.SrcUseSSH = true
+	rsyncCmd.SrcSSHHost = sshTargetHost
+
+	if knownHosts != "" {
+		rsyncCmd.KnownHostsFile = knownHostsMountPath
 	}
 
 	rsyncCmdStr, err := rsyncCmd.Build()
@@ -101,6 +105,8 @@ func buildHelmVals(mig *migration.Migration, helmReleaseName string, logger *slo
 			"privateKeyMount":     true,
 			"privateKey":          privateKey,
 			"privateKeyMountPath": privateKeyMountPath,
+			"knownHosts":          knownHosts,
+			"knownHostsMountPath": knownHostsMountPath,
 			"pvcMounts": []map[string]any{
 				{
 					"name":      destInfo.Claim.Name,
@@ -111,9 +117,15 @@ func buildHelmVals(mig *migration.Migration, helmReleaseName string, logger *slo
 			"affinity": destInfo.AffinityHelmValues,
 		},
 		"sshd": map[string]any{
-			"enabled":   true,
-			"namespace": sourceNs,
-			"publicKey": publicKey,
+			"enabled":          true,
+			"namespace":        sourceNs,
+			"publicKey":        publicKey,
+			"hostKey":          hostKey,
+			"hostKeyMountPath": hostKeyMountPath,
+			"service": map[string]any{
+				"name":      mig.Request.SSHServiceName,
+				"clusterIP": mig.Request.SSHClusterIP,
+			},
 			"pvcMounts": []map[string]any{
 				{
 					"name":      sourceInfo.Claim.Name,
@@ -125,3 +137,13 @@ func buildHelmVals(mig *migration.Migration, helmReleaseName string, logger *slo
 		},
 	}, nil
 }
+
+// sshServiceName returns the name of the service of the SSH server of the release,
+// i.e., the one given with SSHServiceName, or the one generated by the chart.
+func sshServiceName(request *migration.Request, releaseName string) string {
+	if request.SSHServiceName != "" {
+		return request.SSHServiceName
+	}
+
+	return releaseName + "-sshd"
+}
@@ -5,7 +5,9 @@ import (
 	"context"
 	"testing"
 
+	"github.com/neilotoole/slogt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 
 	"github.com/utkuozdemir/pv-migrate/migration"
@@ -47,3 +49,91 @@ func TestSvcCanDoSameCluster(t *testing.T) {
 	canDo := s.canDo(&mig)
 	assert.True(t, canDo)
 }
+
+func TestBuildHelmValsSSHService(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pvcA := buildTestPVC("namespace1", "pvc1", v1.ReadWriteOnce)
+	pvcB := buildTestPVC("namespace2", "pvc2", v1.ReadWriteOnce)
+	c := buildTestClient(pvcA, pvcB)
+	src, _ := pvc.New(ctx, c, "namespace1", "pvc1")
+	dst, _ := pvc.New(ctx, c, "namespace2", "pvc2")
+
+	mig := migration.Migration{
+		Request: &migration.Request{
+			Source:         &migration.PVCInfo{Namespace: "namespace1", Name: "pvc1"},
+			Dest:           &migration.PVCInfo{Namespace: "namespace2", Name: "pvc2"},
+			KeyAlgorithm:   "ed25519",
+			SSHServiceName: "migration-sshd",
+			SSHClusterIP:   "10.96.0.50",
+		},
+		SourceInfo: src,
+		DestInfo:   dst,
+	}
+
+	vals, err := buildHelmVals(&mig, "pv-migrate-abcde", slogt.New(t))
+	require.NoError(t, err)
+
+	sshdVals, _ := vals["sshd"].(map[string]any)
+	assert.Equal(t, map[string]any{"name": "migration-sshd", "clusterIP": "10.96.0.50"}, sshdVals["service"])
+
+	rsyncVals, _ := vals["rsync"].(map[string]any)
+	assert.Contains(t, rsyncVals["command"], "root@migration-sshd.namespace1:")
+}
+
+func TestBuildHelmValsStrictHostKeyChecking(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pvcA := buildTestPVC("namespace1", "pvc1", v1.ReadWriteOnce)
+	pvcB := buildTestPVC("namespace2", "pvc2", v1.ReadWriteOnce)
+	c := buildTestClient(pvcA, pvcB)
+	src, _ := pvc.New(ctx, c, "namespace1", "pvc1")
+	dst, _ := pvc.New(ctx, c, "namespace2", "pvc2")
+
+	mig := migration.Migration{
+		Request: &migration.Request{
+			Source:       &migration.PVCInfo{Namespace: "namespace1", Name: "pvc1"},
+			Dest:         &migration.PVCInfo{Namespace: "namespace2", Name: "pvc2"},
+			KeyAlgorithm: "ed25519",
+		},
+		SourceInfo: src,
+		DestInfo:   dst,
+	}
+
+	vals, err := buildHelmVals(&mig, "pv-migrate-abcde", slogt.New(t))
+	require.NoError(t, err)
+
+	sshdVals, _ := vals["sshd"].(map[string]any)
+	rsyncVals, _ := vals["rsync"].(map[string]any)
+
+	assert.Empty(t, sshdVals["hostKey"])
+	assert.Empty(t, rsyncVals["knownHosts"])
+	assert.Contains(t, rsyncVals["command"], "StrictHostKeyChecking=no")
+
+	mig.Request.StrictHostKeyChecking = true
+
+	vals, err = buildHelmVals(&mig, "pv-migrate-abcde", slogt.New(t))
+	require.NoError(t, err)
+
+	sshdVals, _ = vals["sshd"].(map[string]any)
+	rsyncVals, _ = vals["rsync"].(map[string]any)
+
+	assert.Contains(t, sshdVals["hostKey"], "OPENSSH PRIVATE KEY")
+	assert.Regexp(t, `^pv-migrate-sshd ssh-ed25519 \S+\n$`, rsyncVals["knownHosts"])
+	assert.Contains(t, rsyncVals["command"], "StrictHostKeyChecking=yes -o UserKnownHostsFile="+knownHostsMountPath+
+		" -o HostKeyAlias=pv-migrate-sshd")
+}
+
+func TestSSHServiceName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "pv-migrate-abcde-src-sshd", sshServiceName(&migration.Request{}, "pv-migrate-abcde-src"))
+	assert.Equal(t, "migration-sshd",
+		sshServiceName(&migration.Request{SSHServiceName: "migration-sshd"}, "pv-migrate-abcde-src"))
+}
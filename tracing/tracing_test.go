@@ -0,0 +1,57 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/utkuozdemir/pv-migrate/tracing"
+)
+
+func TestSetupNoEndpoint(t *testing.T) {
+	t.Parallel()
+
+	shutdown, err := tracing.Setup(context.Background(), "")
+	require.NoError(t, err)
+
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+//nolint:paralleltest // sets the global tracer provider
+func TestStartEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx, root := tracing.Start(context.Background(), "migration")
+	_, child := tracing.Start(ctx, "strategy", attribute.String("pv_migrate.strategy", "mnt2"))
+
+	tracing.End(child, errors.New("test error"))
+	tracing.End(root, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	childSpan, rootSpan := spans[0], spans[1]
+
+	assert.Equal(t, "strategy", childSpan.Name())
+	assert.Equal(t, rootSpan.SpanContext().SpanID(), childSpan.Parent().SpanID())
+	assert.Contains(t, childSpan.Attributes(), attribute.String("pv_migrate.strategy", "mnt2"))
+	assert.Equal(t, codes.Error, childSpan.Status().Code)
+	assert.Equal(t, "test error", childSpan.Status().Description)
+
+	assert.Equal(t, "migration", rootSpan.Name())
+	assert.Equal(t, codes.Unset, rootSpan.Status().Code)
+}
@@ -0,0 +1,65 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExpandEnv replaces the ${VAR} and ${VAR:-default} references in the given string
+// with the values returned by the lookup function.
+//
+// It is an error to reference a variable which is not defined, unless a default is provided.
+// A literal "$" can be written as "$$". A "$" which is not followed by "{" or "$" is kept as is.
+func ExpandEnv(value string, lookup func(string) (string, bool)) (string, error) {
+	var result strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			result.WriteByte(value[i])
+
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			result.WriteByte('$')
+
+			i++
+		case '{':
+			end := strings.IndexByte(value[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference in %q", value)
+			}
+
+			expanded, err := expandReference(value[i+2:i+2+end], lookup)
+			if err != nil {
+				return "", err
+			}
+
+			result.WriteString(expanded)
+
+			i += end + 2
+		default:
+			result.WriteByte('$')
+		}
+	}
+
+	return result.String(), nil
+}
+
+func expandReference(reference string, lookup func(string) (string, bool)) (string, error) {
+	name, defaultValue, hasDefault := strings.Cut(reference, ":-")
+	if name == "" {
+		return "", fmt.Errorf("empty variable name in ${%s}", reference)
+	}
+
+	if val, ok := lookup(name); ok && (val != "" || !hasDefault) {
+		return val, nil
+	}
+
+	if hasDefault {
+		return defaultValue, nil
+	}
+
+	return "", fmt.Errorf("environment variable %s is not defined", name)
+}
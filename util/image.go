@@ -0,0 +1,22 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseImage splits the reference of a container image in the form of <repository>:<tag>,
+// e.g. registry.example.com:5000/pv-migrate-rsync:1.0.0, into its repository and tag.
+func ParseImage(image string) (string, string, error) {
+	if strings.Contains(image, "@") {
+		return "", "", fmt.Errorf("image %q must be referenced by a tag, not a digest", image)
+	}
+
+	index := strings.LastIndex(image, ":")
+	if index <= strings.LastIndex(image, "/") || index == 0 || index == len(image)-1 {
+		return "", "", errors.New("image must be in the form of <repository>:<tag>")
+	}
+
+	return image[:index], image[index+1:], nil
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsIPv6(t *testing.T) {
@@ -13,3 +14,65 @@ func TestIsIPv6(t *testing.T) {
 	assert.True(t, IsIPv6("2001:0db8:85a3:0000:0000:8a2e:0370:7334"))
 	assert.True(t, IsIPv6("::1"))
 }
+
+func TestExpandEnv(t *testing.T) {
+	t.Parallel()
+
+	lookup := func(name string) (string, bool) {
+		vals := map[string]string{"NS": "target", "EMPTY": ""}
+		val, ok := vals[name]
+
+		return val, ok
+	}
+
+	tests := []struct {
+		value    string
+		expected string
+		err      bool
+	}{
+		{value: "plain", expected: "plain"},
+		{value: "${NS}", expected: "target"},
+		{value: "ns-${NS}-1", expected: "ns-target-1"},
+		{value: "${MISSING:-default}", expected: "default"},
+		{value: "${EMPTY:-default}", expected: "default"},
+		{value: "${EMPTY}", expected: ""},
+		{value: "$${NS}", expected: "${NS}"},
+		{value: "cost: $5", expected: "cost: $5"},
+		{value: "trailing $", expected: "trailing $"},
+		{value: "${MISSING}", err: true},
+		{value: "${NS", err: true},
+		{value: "${}", err: true},
+	}
+
+	for _, tt := range tests {
+		result, err := ExpandEnv(tt.value, lookup)
+		if tt.err {
+			require.Error(t, err, tt.value)
+
+			continue
+		}
+
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.expected, result, tt.value)
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	t.Parallel()
+
+	repository, tag, err := ParseImage("docker.io/utkuozdemir/pv-migrate-rsync:1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "docker.io/utkuozdemir/pv-migrate-rsync", repository)
+	assert.Equal(t, "1.0.0", tag)
+
+	repository, tag, err = ParseImage("registry.example.com:5000/rsync:latest")
+	require.NoError(t, err)
+	assert.Equal(t, "registry.example.com:5000/rsync", repository)
+	assert.Equal(t, "latest", tag)
+
+	for _, image := range []string{"", "rsync", "rsync:", ":1.0.0", "registry.example.com:5000/rsync",
+		"rsync@sha256:0123456789abcdef"} {
+		_, _, err = ParseImage(image)
+		assert.Error(t, err, image)
+	}
+}
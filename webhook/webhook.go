@@ -0,0 +1,278 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+type EventType string
+
+const (
+	EventStarted          EventType = "started"
+	EventStrategySelected EventType = "strategy-selected"
+	EventProgress         EventType = "progress"
+	EventCompleted        EventType = "completed"
+	EventFailed           EventType = "failed"
+
+	requestTimeout = 10 * time.Second
+
+	// progressInterval is the minimum interval between two progress events, not to flood the endpoint.
+	progressInterval = 10 * time.Second
+
+	// queueSize is the number of the events waiting to be delivered, above which the new events are dropped.
+	queueSize = 100
+
+	// closeTimeout is the maximum time to wait for the pending events to be delivered when the notifier is closed.
+	closeTimeout = 30 * time.Second
+)
+
+// Event is the JSON payload POSTed to the webhook endpoint.
+type Event struct {
+	Type      EventType `json:"type"`
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	Dest      string    `json:"dest"`
+	Strategy  string    `json:"strategy,omitempty"`
+	AttemptID string    `json:"attemptId,omitempty"`
+	Metrics   *Metrics  `json:"metrics,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type Metrics struct {
+	TransferredBytes int64   `json:"transferredBytes"`
+	TotalBytes       int64   `json:"totalBytes"`
+	Percentage       int     `json:"percentage"`
+	DurationSeconds  float64 `json:"durationSeconds,omitempty"`
+	// ETASeconds and ThroughputBytesPerSecond are the smoothed estimates of the progress events, if known.
+	ETASeconds               float64 `json:"etaSeconds,omitempty"`
+	ThroughputBytesPerSecond float64 `json:"throughputBytesPerSecond,omitempty"`
+}
+
+// Notifier POSTs the events of a migration to a webhook endpoint.
+//
+// The events are delivered in order in the background, for a slow endpoint not to hold up the migration,
+// and Close waits for the pending ones. Failures to deliver the events are only logged, they never fail
+// the migration. A nil Notifier is valid and does nothing.
+type Notifier struct {
+	url    string
+	client *http.Client
+	logger *slog.Logger
+
+	source string
+	dest   string
+
+	queue chan func()
+	done  chan struct{}
+
+	lock         sync.Mutex
+	closed       bool
+	startTime    time.Time
+	strategy     string
+	attemptID    string
+	lastProgress progress.Progress
+	lastReport   time.Time
+}
+
+// New creates a Notifier for the migration from the source to the destination PVC, both in the form of "ns/name".
+func New(url, source, dest string, logger *slog.Logger) *Notifier {
+	notifier := &Notifier{
+		url:    url,
+		client: &http.Client{Timeout: requestTimeout},
+		logger: logger,
+		source: source,
+		dest:   dest,
+		queue:  make(chan func(), queueSize),
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(notifier.done)
+
+		for deliver := range notifier.queue {
+			deliver()
+		}
+	}()
+
+	return notifier
+}
+
+// Close waits for the pending events to be delivered, up to closeTimeout, and stops the notifier.
+// The events sent after it is closed are dropped.
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+
+	n.lock.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.lock.Unlock()
+
+	select {
+	case <-n.done:
+	case <-time.After(closeTimeout):
+		n.logger.Warn("🔶 Timed out waiting for the webhook events to be delivered", "timeout", closeTimeout)
+	}
+}
+
+func (n *Notifier) Started(ctx context.Context) {
+	if n == nil {
+		return
+	}
+
+	n.lock.Lock()
+	n.startTime = time.Now()
+	n.strategy = ""
+	n.attemptID = ""
+	n.lastProgress = progress.Progress{}
+	n.lastReport = time.Time{}
+	n.lock.Unlock()
+
+	n.send(ctx, n.buildEvent(EventStarted))
+}
+
+func (n *Notifier) StrategySelected(ctx context.Context, strategy, attemptID string) {
+	if n == nil {
+		return
+	}
+
+	n.lock.Lock()
+	n.strategy = strategy
+	n.attemptID = attemptID
+	n.lock.Unlock()
+
+	n.send(ctx, n.buildEvent(EventStrategySelected))
+}
+
+// ReportProgress implements progress.Reporter.
+func (n *Notifier) ReportProgress(ctx context.Context, prg progress.Progress) {
+	if n == nil {
+		return
+	}
+
+	n.lock.Lock()
+	n.lastProgress = prg
+
+	if time.Since(n.lastReport) < progressInterval && prg.Percentage < 100 {
+		n.lock.Unlock()
+
+		return
+	}
+
+	n.lastReport = time.Now()
+	n.lock.Unlock()
+
+	n.send(ctx, n.buildEvent(EventProgress))
+}
+
+func (n *Notifier) Completed(ctx context.Context) {
+	if n == nil {
+		return
+	}
+
+	n.send(ctx, n.buildEvent(EventCompleted))
+}
+
+func (n *Notifier) Failed(ctx context.Context, err error) {
+	if n == nil {
+		return
+	}
+
+	event := n.buildEvent(EventFailed)
+	event.Error = err.Error()
+
+	n.send(ctx, event)
+}
+
+func (n *Notifier) buildEvent(eventType EventType) Event {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	now := time.Now()
+
+	event := Event{
+		Type:      eventType,
+		Time:      now,
+		Source:    n.source,
+		Dest:      n.dest,
+		Strategy:  n.strategy,
+		AttemptID: n.attemptID,
+	}
+
+	if eventType == EventProgress || eventType == EventCompleted || eventType == EventFailed {
+		event.Metrics = &Metrics{
+			TransferredBytes: n.lastProgress.Transferred,
+			TotalBytes:       n.lastProgress.Total,
+			Percentage:       n.lastProgress.Percentage,
+			DurationSeconds:  now.Sub(n.startTime).Seconds(),
+		}
+	}
+
+	if eventType == EventProgress {
+		event.Metrics.ETASeconds = n.lastProgress.ETA.Seconds()
+		event.Metrics.ThroughputBytesPerSecond = n.lastProgress.Throughput
+	}
+
+	return event
+}
+
+// send queues the event to be delivered in the background, dropping it if the queue is full.
+func (n *Notifier) send(ctx context.Context, event Event) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.closed {
+		return
+	}
+
+	deliver := func() {
+		if err := n.post(ctx, event); err != nil {
+			n.logger.Warn("🔶 Failed to deliver the webhook event", "type", event.Type, "error", err)
+		}
+	}
+
+	select {
+	case n.queue <- deliver:
+	default:
+		n.logger.Warn("🔶 Dropped the webhook event, as too many events are waiting to be delivered",
+			"type", event.Type)
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	// the event is delivered even if the migration is being canceled, e.g., to report the failure
+	req, err := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+	"github.com/utkuozdemir/pv-migrate/webhook"
+)
+
+func TestNotifier(t *testing.T) {
+	t.Parallel()
+
+	var (
+		lock   sync.Mutex
+		events []webhook.Event
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event webhook.Event
+
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		lock.Lock()
+		events = append(events, event)
+		lock.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	notifier := webhook.New(server.URL, "ns1/pvc1", "ns2/pvc2", slogt.New(t))
+
+	notifier.Started(ctx)
+	notifier.StrategySelected(ctx, "svc", "abcde")
+	notifier.ReportProgress(ctx, progress.Progress{
+		Transferred: 10, Total: 100, Percentage: 10, ETA: time.Minute, Throughput: 1.5,
+	})
+	notifier.ReportProgress(ctx, progress.Progress{Transferred: 20, Total: 100, Percentage: 20}) // throttled
+	notifier.ReportProgress(ctx, progress.Progress{Transferred: 100, Total: 100, Percentage: 100})
+	notifier.Failed(ctx, errors.New("test error"))
+	notifier.Close()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	types := make([]webhook.EventType, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+
+		assert.Equal(t, "ns1/pvc1", event.Source)
+		assert.Equal(t, "ns2/pvc2", event.Dest)
+	}
+
+	require.Equal(t, []webhook.EventType{
+		webhook.EventStarted, webhook.EventStrategySelected,
+		webhook.EventProgress, webhook.EventProgress, webhook.EventFailed,
+	}, types)
+
+	assert.Empty(t, events[0].Strategy)
+	assert.Equal(t, "svc", events[1].Strategy)
+	assert.Equal(t, "abcde", events[1].AttemptID)
+	assert.Equal(t, int64(10), events[2].Metrics.TransferredBytes)
+	assert.InDelta(t, 60.0, events[2].Metrics.ETASeconds, 0)
+	assert.InDelta(t, 1.5, events[2].Metrics.ThroughputBytesPerSecond, 0)
+	assert.Zero(t, events[4].Metrics.ETASeconds)
+	assert.Equal(t, 100, events[4].Metrics.Percentage)
+	assert.Equal(t, "test error", events[4].Error)
+}
+
+func TestNotifierDeliveryFailure(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+
+	notifier := webhook.New(server.URL, "ns1/pvc1", "ns2/pvc2", slogt.New(t))
+
+	assert.NotPanics(t, func() { notifier.Completed(context.Background()) })
+
+	var nilNotifier *webhook.Notifier
+
+	assert.NotPanics(t, func() { nilNotifier.Started(context.Background()) })
+}
+
+func TestNotifierAsync(t *testing.T) {
+	t.Parallel()
+
+	var (
+		lock  sync.Mutex
+		types []webhook.EventType
+	)
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-release
+
+		var event webhook.Event
+
+		if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+			lock.Lock()
+			types = append(types, event.Type)
+			lock.Unlock()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	notifier := webhook.New(server.URL, "ns1/pvc1", "ns2/pvc2", slogt.New(t))
+
+	start := time.Now()
+
+	notifier.Started(ctx)
+	notifier.StrategySelected(ctx, "svc", "abcde")
+	notifier.Completed(ctx)
+
+	assert.Less(t, time.Since(start), time.Second, "the events must not wait for the endpoint")
+
+	close(release)
+	notifier.Close()
+	notifier.Completed(ctx) // dropped after the notifier is closed
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	assert.Equal(t, []webhook.EventType{
+		webhook.EventStarted, webhook.EventStrategySelected, webhook.EventCompleted,
+	}, types, "the events must be delivered in order before Close returns")
+}